@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// adminOnly guards operator endpoints. They always require the API token, so
+// a deployment without API_TOKEN has them disabled rather than open.
+func (s *Server) adminOnly(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.config.APIToken == "" {
+			writeError(w, http.StatusForbidden, ErrForbidden, "Admin endpoints require API_TOKEN to be configured")
+			return
+		}
+		next(w, r)
+	}
+}
+
+// resyncMetricsHandler re-reads the stored expression count and sets the
+// cron_expressions_stored gauge, correcting drift after rows are changed
+// outside the app. Prometheus counters can't decrease, so
+// cron_expressions_total is left alone; dashboards should use the gauge for
+// the current count.
+func (s *Server) resyncMetricsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.dbContext(r)
+	defer cancel()
+
+	count, err := s.db.countExpressions(ctx)
+	if err != nil {
+		writeDBError(w, ctx, err)
+		return
+	}
+	cronExpressionsStored.Set(float64(count))
+
+	s.writeJSON(w, r, http.StatusOK, map[string]int{"cron_expressions_stored": count})
+}
+
+// InvalidStoredExpression is a stored expression that no longer parses
+type InvalidStoredExpression struct {
+	ID         int    `json:"id"`
+	Name       string `json:"name"`
+	Expression string `json:"expression"`
+	Error      string `json:"error"`
+}
+
+// ValidationReport summarises a validation pass over stored expressions
+type ValidationReport struct {
+	Checked int                       `json:"checked"`
+	Invalid []InvalidStoredExpression `json:"invalid"`
+}
+
+// validateStoredExpressions parses every stored expression and reports those
+// that fail, catching data saved under looser rules before the parser was
+// tightened. It sets the invalid_stored_expressions gauge, logs each failure
+// and stamps last_validated_at on the expressions that still parse.
+func (s *Server) validateStoredExpressions(ctx context.Context) (ValidationReport, error) {
+	report := ValidationReport{Invalid: []InvalidStoredExpression{}}
+	rows, err := s.db.queryRead(ctx, `
+		SELECT `+expressionColumns+`
+		FROM cron_expressions e
+		WHERE e.deleted_at IS NULL
+		ORDER BY e.id
+	`)
+	if err != nil {
+		return report, err
+	}
+	defer rows.Close()
+
+	valid := []int64{}
+	for rows.Next() {
+		var exp CronExpression
+		if err := scanExpression(rows, &exp); err != nil {
+			return report, err
+		}
+		report.Checked++
+		if _, err := parseExpression(exp.Expression); err != nil {
+			log.Printf("Warning: stored expression %d (%q) is invalid: %v", exp.ID, exp.Expression, err)
+			report.Invalid = append(report.Invalid, InvalidStoredExpression{
+				ID:         exp.ID,
+				Name:       exp.Name,
+				Expression: exp.Expression,
+				Error:      err.Error(),
+			})
+		} else {
+			valid = append(valid, int64(exp.ID))
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return report, err
+	}
+	rows.Close()
+
+	if len(valid) > 0 {
+		_, err := s.db.writer().ExecContext(ctx, `
+			UPDATE cron_expressions SET last_validated_at = $1 WHERE id = ANY($2)
+		`, time.Now(), pq.Array(valid))
+		if err != nil {
+			return report, err
+		}
+	}
+
+	invalidStoredExpressions.Set(float64(len(report.Invalid)))
+	return report, nil
+}
+
+func (s *Server) validateAllHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.dbContext(r)
+	defer cancel()
+
+	report, err := s.validateStoredExpressions(ctx)
+	if err != nil {
+		writeDBError(w, ctx, err)
+		return
+	}
+	s.writeJSON(w, r, http.StatusOK, report)
+}
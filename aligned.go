@@ -0,0 +1,146 @@
+package main
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// alignments round a time up to the start of the next boundary of each
+// supported kind. Boundaries are computed in the request's timezone with
+// time.Date, so "day" is local midnight even across a DST change.
+var alignments = map[string]func(t time.Time, weekStart time.Weekday) time.Time{
+	"minute": func(t time.Time, _ time.Weekday) time.Time {
+		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute()+1, 0, 0, t.Location())
+	},
+	"quarter-hour": func(t time.Time, _ time.Weekday) time.Time {
+		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute()/15*15+15, 0, 0, t.Location())
+	},
+	"hour": func(t time.Time, _ time.Weekday) time.Time {
+		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+	},
+	"day": func(t time.Time, _ time.Weekday) time.Time {
+		return time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+	},
+	"week": func(t time.Time, weekStart time.Weekday) time.Time {
+		days := (int(weekStart) - int(t.Weekday()) + 7) % 7
+		if days == 0 {
+			days = 7
+		}
+		return time.Date(t.Year(), t.Month(), t.Day()+days, 0, 0, 0, 0, t.Location())
+	},
+	"month": func(t time.Time, _ time.Weekday) time.Time {
+		return time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+	},
+}
+
+// AlignedRequest is the request body for an aligned next run. Alignment is
+// one of the alignments keys, optionally written as "next-hour" or "start of
+// next hour". From is RFC 3339 and defaults to now; WeekStart is as for
+// describe.
+type AlignedRequest struct {
+	Expression string `json:"expression"`
+	Alignment  string `json:"alignment"`
+	Timezone   string `json:"timezone"`
+	From       string `json:"from"`
+	WeekStart  string `json:"weekStart"`
+}
+
+// AlignedResponse is the first run at or after the aligned boundary. NextRun
+// is null if the expression never fires again.
+type AlignedResponse struct {
+	Expression     string     `json:"expression"`
+	Alignment      string     `json:"alignment"`
+	Timezone       string     `json:"timezone"`
+	From           time.Time  `json:"from"`
+	AlignedFrom    time.Time  `json:"alignedFrom"`
+	NextRun        *time.Time `json:"nextRun"`
+	NextExecutions []string   `json:"nextExecutions"`
+}
+
+// parseAlignment maps an alignment keyword onto its alignments key
+func parseAlignment(value string) (string, bool) {
+	key := strings.Join(strings.Fields(strings.ToLower(value)), " ")
+	key = strings.TrimPrefix(key, "start of ")
+	key = strings.TrimPrefix(key, "next")
+	key = strings.TrimLeft(key, " -_")
+	key = strings.ReplaceAll(key, " ", "-")
+	_, ok := alignments[key]
+	return key, ok
+}
+
+// alignmentNames lists the supported alignments for error messages
+func alignmentNames() string {
+	names := make([]string, 0, len(alignments))
+	for name := range alignments {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// alignedScheduleHandler returns the next runs of an expression counted from
+// the start of the next boundary (minute, hour, day, ...) rather than from
+// now, for scheduling relative to clean time boundaries
+func (s *Server) alignedScheduleHandler(w http.ResponseWriter, r *http.Request) {
+	var req AlignedRequest
+	if !s.decodeJSON(w, r, &req) {
+		return
+	}
+
+	alignment, ok := parseAlignment(req.Alignment)
+	if !ok {
+		writeFieldError(w, http.StatusBadRequest, ErrInvalidRequest, "alignment", "alignment must be one of: "+alignmentNames())
+		return
+	}
+	weekStart, err := parseWeekStart(req.WeekStart)
+	if err != nil {
+		writeFieldError(w, http.StatusBadRequest, ErrInvalidRequest, "weekStart", err.Error())
+		return
+	}
+
+	expression := cleanExpression(req.Expression)
+	schedule, err := parseExpression(expression)
+	if err != nil {
+		invalidCronExpressions.Inc()
+		writeError(w, http.StatusBadRequest, ErrInvalidCron, "Invalid cron expression: "+err.Error())
+		return
+	}
+
+	loc := s.config.DefaultTimezone
+	if req.Timezone != "" {
+		loc, err = time.LoadLocation(req.Timezone)
+		if err != nil {
+			writeFieldError(w, http.StatusBadRequest, ErrInvalidTimezone, "timezone", "Invalid timezone: "+req.Timezone)
+			return
+		}
+	}
+	from := time.Now()
+	if req.From != "" {
+		from, err = time.Parse(time.RFC3339, req.From)
+		if err != nil {
+			writeFieldError(w, http.StatusBadRequest, ErrInvalidRequest, "from", "from must be an RFC 3339 time: "+req.From)
+			return
+		}
+	}
+	from = from.In(loc)
+
+	// Next is strictly after its argument, so start a second early to
+	// include a run exactly on the boundary
+	aligned := alignments[alignment](from, weekStart)
+	times := nextExecutionTimes(schedule, aligned.Add(-time.Second), 5)
+
+	response := AlignedResponse{
+		Expression:     expression,
+		Alignment:      alignment,
+		Timezone:       loc.String(),
+		From:           from,
+		AlignedFrom:    aligned,
+		NextExecutions: formatExecutions(times),
+	}
+	if len(times) > 0 {
+		response.NextRun = &times[0]
+	}
+	s.writeJSON(w, r, http.StatusOK, response)
+}
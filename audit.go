@@ -0,0 +1,225 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gorilla/mux"
+)
+
+// Audited actions on a stored expression
+const (
+	AuditCreate = "create"
+	AuditUpdate = "update"
+	AuditDelete = "delete"
+	AuditPause  = "pause"
+	AuditResume = "resume"
+	// AuditMerge is a duplicate removed by dedup; its snapshot is the
+	// expression it was merged into
+	AuditMerge = "merge"
+)
+
+// maxClaimedActor caps the length of a stored X-Actor header
+const maxClaimedActor = 255
+
+// auditActor names who made a change. All callers share one API token, so
+// the client address is the only identity the server has verified.
+func (s *Server) auditActor(r *http.Request) string {
+	return s.clientIP(r)
+}
+
+// claimedActor is the caller's own X-Actor header. It's unverified, so it's
+// stored alongside the actor for information rather than in its place.
+func claimedActor(r *http.Request) string {
+	actor := strings.TrimSpace(r.Header.Get("X-Actor"))
+	for len(actor) > maxClaimedActor {
+		// Trim whole runes so the stored value stays valid UTF-8
+		_, size := utf8.DecodeLastRuneInString(actor)
+		actor = actor[:len(actor)-size]
+	}
+	return actor
+}
+
+// recordAudit appends a change to the audit log. snapshot is the expression
+// after the change, or nil for a delete. The change itself has already been
+// written, so a failure here is logged rather than failing the request.
+func (s *Server) recordAudit(ctx context.Context, r *http.Request, id interface{}, action string, snapshot *CronExpression) {
+	var state []byte
+	if snapshot != nil {
+		var err error
+		if state, err = json.Marshal(snapshot); err != nil {
+			log.Printf("Warning: audit snapshot for expression %v: %v", id, err)
+			return
+		}
+	}
+	_, err := s.db.writer().ExecContext(ctx, `
+		INSERT INTO expression_audit (expression_id, action, actor, claimed_actor, snapshot, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`, id, action, s.auditActor(r), nullIfEmpty(claimedActor(r)), nullIfEmpty(string(state)), time.Now())
+	if err != nil {
+		log.Printf("Warning: recording %s of expression %v in audit log: %v", action, id, err)
+	}
+}
+
+// TimelineEntry is one change to an expression with a readable summary of
+// what changed since the previous entry. ClaimedActor is the unverified
+// X-Actor header sent with the change, if any.
+type TimelineEntry struct {
+	Action       string    `json:"action"`
+	Actor        string    `json:"actor"`
+	ClaimedActor string    `json:"claimedActor,omitempty"`
+	At           time.Time `json:"at"`
+	Changes      []string  `json:"changes"`
+}
+
+// auditEntry is a row of expression_audit
+type auditEntry struct {
+	action       string
+	actor        string
+	claimedActor string
+	at           time.Time
+	snapshot     *CronExpression
+}
+
+// describeChanges summarizes how an expression changed between two audit
+// snapshots. prev is nil for the first entry and next is nil for a delete.
+func describeChanges(action string, prev, next *CronExpression) []string {
+	switch {
+	case action == AuditMerge && next != nil:
+		return []string{fmt.Sprintf("merged into %q (id %d) as a duplicate", next.Name, next.ID)}
+	case next == nil:
+		return []string{"deleted"}
+	case prev == nil && action == AuditCreate:
+		return []string{fmt.Sprintf("created as %q with expression %q", next.Name, next.Expression)}
+	case prev == nil:
+		// The expression predates the audit log
+		return []string{fmt.Sprintf("%s recorded with expression %q; no earlier history", action, next.Expression)}
+	}
+
+	changes := []string{}
+	changed := func(field, from, to string) {
+		if from != to {
+			changes = append(changes, fmt.Sprintf("%s changed from %q to %q", field, from, to))
+		}
+	}
+	changed("name", prev.Name, next.Name)
+	changed("expression", prev.Expression, next.Expression)
+	changed("description", prev.Description, next.Description)
+	changed("timezone", prev.Timezone, next.Timezone)
+	changed("environment", prev.Environment, next.Environment)
+	if categoryLabel(prev.CategoryID) != categoryLabel(next.CategoryID) {
+		changes = append(changes, fmt.Sprintf("category changed from %s to %s", categoryLabel(prev.CategoryID), categoryLabel(next.CategoryID)))
+	}
+	if !bytes.Equal(compactJSON(prev.Metadata), compactJSON(next.Metadata)) {
+		changes = append(changes, fmt.Sprintf("metadata changed from %s to %s", compactJSON(prev.Metadata), compactJSON(next.Metadata)))
+	}
+	if prev.Enabled != next.Enabled {
+		if next.Enabled {
+			changes = append(changes, "resumed")
+		} else {
+			changes = append(changes, "paused")
+		}
+	}
+	if len(changes) == 0 {
+		changes = append(changes, action+" with no changes")
+	}
+	return changes
+}
+
+// categoryLabel renders a category id for a change summary
+func categoryLabel(id *int) string {
+	if id == nil {
+		return "none"
+	}
+	return fmt.Sprintf("#%d", *id)
+}
+
+// compactJSON normalizes metadata so formatting differences don't show up
+// as changes; absent metadata compares equal to an empty object
+func compactJSON(raw json.RawMessage) []byte {
+	if len(raw) == 0 || string(raw) == "null" {
+		return []byte("{}")
+	}
+	var b bytes.Buffer
+	if err := json.Compact(&b, raw); err != nil {
+		return raw
+	}
+	return b.Bytes()
+}
+
+// timelineHandler returns an expression's audit log oldest first, each entry
+// summarizing what changed since the one before. Deleted expressions keep
+// their timeline; expressions from before the audit log have an empty one.
+func (s *Server) timelineHandler(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	ctx, cancel := s.dbContext(r)
+	defer cancel()
+
+	rows, err := s.db.queryRead(ctx, `
+		SELECT action, actor, COALESCE(claimed_actor, ''), snapshot, created_at
+		FROM expression_audit
+		WHERE expression_id = $1
+		ORDER BY created_at, id
+	`, id)
+	if err != nil {
+		writeDBError(w, ctx, err)
+		return
+	}
+	defer rows.Close()
+
+	entries := []auditEntry{}
+	for rows.Next() {
+		var entry auditEntry
+		var snapshot []byte
+		if err := rows.Scan(&entry.action, &entry.actor, &entry.claimedActor, &snapshot, &entry.at); err != nil {
+			writeDBError(w, ctx, err)
+			return
+		}
+		if snapshot != nil {
+			entry.snapshot = &CronExpression{}
+			if err := json.Unmarshal(snapshot, entry.snapshot); err != nil {
+				writeError(w, http.StatusInternalServerError, ErrInternal, err.Error())
+				return
+			}
+		}
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		writeDBError(w, ctx, err)
+		return
+	}
+
+	if len(entries) == 0 {
+		if _, err := s.db.expressionByID(ctx, id); err == sql.ErrNoRows {
+			writeError(w, http.StatusNotFound, ErrNotFound, "Expression not found")
+			return
+		} else if err != nil {
+			writeDBError(w, ctx, err)
+			return
+		}
+	}
+
+	timeline := make([]TimelineEntry, 0, len(entries))
+	var prev *CronExpression
+	for _, entry := range entries {
+		timeline = append(timeline, TimelineEntry{
+			Action:       entry.action,
+			Actor:        entry.actor,
+			ClaimedActor: entry.claimedActor,
+			At:           entry.at,
+			Changes:      describeChanges(entry.action, prev, entry.snapshot),
+		})
+		prev = entry.snapshot
+	}
+
+	s.writeJSON(w, r, http.StatusOK, timeline)
+}
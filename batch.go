@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// maxBatchSize caps the number of expressions accepted by a single batch convert
+const maxBatchSize = 1000
+
+// BatchConvertRequest is the request body for converting several expressions
+type BatchConvertRequest struct {
+	Expressions []string `json:"expressions"`
+}
+
+// BatchConvertResult is the conversion of one expression in a batch. Invalid
+// expressions carry an error instead of failing the whole batch.
+type BatchConvertResult struct {
+	Expression     string   `json:"expression"`
+	RawExpression  string   `json:"rawExpression"`
+	Description    string   `json:"description,omitempty"`
+	NextExecutions []string `json:"nextExecutions,omitempty"`
+	Error          string   `json:"error,omitempty"`
+}
+
+// BatchConvertResponse is the response for a batch convert, in input order
+type BatchConvertResponse struct {
+	Results []BatchConvertResult `json:"results"`
+	Unique  int                  `json:"unique"`
+}
+
+func (s *Server) batchConvertHandler(w http.ResponseWriter, r *http.Request) {
+	var req BatchConvertRequest
+	if !s.decodeJSON(w, r, &req) {
+		return
+	}
+
+	if len(req.Expressions) > maxBatchSize {
+		writeFieldError(w, http.StatusBadRequest, ErrInvalidRequest, "expressions", fmt.Sprintf("Batch exceeds maximum of %d expressions", maxBatchSize))
+		return
+	}
+
+	// Convert each distinct expression once and reuse the result for duplicates
+	converted := make(map[string]BatchConvertResult)
+	results := make([]BatchConvertResult, 0, len(req.Expressions))
+	for _, raw := range req.Expressions {
+		expression := cleanExpression(raw)
+		result, ok := converted[expression]
+		if !ok {
+			result = BatchConvertResult{Expression: expression}
+			if _, err := parseExpression(expression); err != nil {
+				invalidCronExpressions.Inc()
+				result.Error = "Invalid cron expression: " + err.Error()
+			} else {
+				result.Description = timedDescription(expression, time.Sunday)
+				result.NextExecutions = calculateNextExecutions(expression, 5)
+			}
+			converted[expression] = result
+		}
+		result.RawExpression = raw
+		results = append(results, result)
+	}
+
+	s.writeNegotiated(w, r, http.StatusOK, BatchConvertResponse{Results: results, Unique: len(converted)})
+}
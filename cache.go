@@ -0,0 +1,145 @@
+package main
+
+import (
+	"container/list"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// descriptionCacheCapacity bounds how many expression descriptions are kept
+const descriptionCacheCapacity = 10000
+
+// descriptionCache memoizes generateDescription by expression string, since
+// stored expressions repeat heavily and a description never changes for a
+// given string. It holds at most capacity entries, evicting the least
+// recently used, so arbitrary client input can't grow it without limit.
+type descriptionCache struct {
+	capacity int
+	mu       sync.Mutex
+	order    *list.List // front is most recently used
+	entries  map[string]*list.Element
+}
+
+type descriptionCacheEntry struct {
+	expression  string
+	description string
+}
+
+func newDescriptionCache(capacity int) *descriptionCache {
+	return &descriptionCache{capacity: capacity, order: list.New(), entries: map[string]*list.Element{}}
+}
+
+// describe returns the description for an expression, generating it on
+// first use
+func (c *descriptionCache) describe(expression string) string {
+	c.mu.Lock()
+	if element, ok := c.entries[expression]; ok {
+		c.order.MoveToFront(element)
+		c.mu.Unlock()
+		return element.Value.(descriptionCacheEntry).description
+	}
+	c.mu.Unlock()
+
+	// Generate outside the lock; a concurrent miss on the same expression
+	// just computes the same string twice
+	description := generateDescription(expression)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if element, ok := c.entries[expression]; ok {
+		c.order.MoveToFront(element)
+		return description
+	}
+	c.entries[expression] = c.order.PushFront(descriptionCacheEntry{expression: expression, description: description})
+	if c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(descriptionCacheEntry).expression)
+	}
+	return description
+}
+
+// len returns how many descriptions are cached
+func (c *descriptionCache) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// maxListCacheEntries bounds how many distinct list queries are cached
+// between invalidations
+const maxListCacheEntries = 100
+
+// listCache holds recent GET /api/expressions results for LIST_CACHE_TTL_MS
+// so polling dashboards don't hit the database on every refresh. Any write
+// to expressions or categories clears it. A nil *listCache is a disabled
+// cache: lookups miss without touching the metrics.
+type listCache struct {
+	ttl     time.Duration
+	mu      sync.RWMutex
+	entries map[string]listCacheEntry
+}
+
+type listCacheEntry struct {
+	expressions []CronExpression
+	expires     time.Time
+}
+
+// newListCache returns a cache with the given TTL, or nil if ttl is zero
+func newListCache(ttl time.Duration) *listCache {
+	if ttl <= 0 {
+		return nil
+	}
+	return &listCache{ttl: ttl, entries: map[string]listCacheEntry{}}
+}
+
+// listCacheKey identifies a list request by its query, ignoring options that
+// only change how the response is rendered
+func listCacheKey(query url.Values) string {
+	key := url.Values{}
+	for name, values := range query {
+		if name != "pretty" {
+			key[name] = values
+		}
+	}
+	return key.Encode()
+}
+
+func (c *listCache) get(key string) ([]CronExpression, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mu.RLock()
+	entry, ok := c.entries[key]
+	c.mu.RUnlock()
+
+	if !ok || time.Now().After(entry.expires) {
+		cacheMisses.Inc()
+		return nil, false
+	}
+	cacheHits.Inc()
+	return entry.expressions, true
+}
+
+func (c *listCache) set(key string, expressions []CronExpression) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.entries) >= maxListCacheEntries {
+		c.entries = map[string]listCacheEntry{}
+	}
+	c.entries[key] = listCacheEntry{expressions: expressions, expires: time.Now().Add(c.ttl)}
+}
+
+// invalidate drops every cached list
+func (c *listCache) invalidate() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	c.entries = map[string]listCacheEntry{}
+	c.mu.Unlock()
+}
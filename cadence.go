@@ -0,0 +1,119 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/robfig/cron/v3"
+)
+
+// Cadences detected by classifyCadence
+const (
+	CadenceEveryMinute = "every-minute"
+	CadenceSubHourly   = "sub-hourly"
+	CadenceHourly      = "hourly"
+	CadenceDaily       = "daily"
+	CadenceWeekly      = "weekly"
+	CadenceMonthly     = "monthly"
+	CadenceYearly      = "yearly"
+	CadenceIrregular   = "irregular"
+)
+
+// knownCadences are the cadences a client may assert an expression has
+var knownCadences = map[string]bool{
+	CadenceEveryMinute: true,
+	CadenceSubHourly:   true,
+	CadenceHourly:      true,
+	CadenceDaily:       true,
+	CadenceWeekly:      true,
+	CadenceMonthly:     true,
+	CadenceYearly:      true,
+	CadenceIrregular:   true,
+}
+
+// CadenceRequest is the request body for validating an expression's cadence
+type CadenceRequest struct {
+	Expression string `json:"expression"`
+	Cadence    string `json:"cadence"`
+}
+
+// CadenceResponse reports whether the expression matches the expected cadence
+type CadenceResponse struct {
+	Expression    string `json:"expression"`
+	RawExpression string `json:"rawExpression"`
+	Expected      string `json:"expected"`
+	Detected      string `json:"detected"`
+	Matches       bool   `json:"matches"`
+}
+
+// classifyCadence works out how often a schedule fires from its parsed fields.
+// Only schedules that fire exactly once per period are given a calendar
+// cadence; anything else, such as "0 9,17 * * *", is irregular.
+func classifyCadence(schedule cron.Schedule) string {
+	spec, ok := schedule.(*cron.SpecSchedule)
+	if !ok {
+		return CadenceIrregular
+	}
+
+	minutes := len(bitValues(spec.Minute, 0, 59))
+	hours := len(bitValues(spec.Hour, 0, 23))
+	days := len(bitValues(spec.Dow, 0, 6))
+	monthDays := len(bitValues(spec.Dom, 1, 31))
+	months := len(bitValues(spec.Month, 1, 12))
+	domStar := spec.Dom&starBit != 0
+	dowStar := spec.Dow&starBit != 0
+	monthStar := spec.Month&starBit != 0
+	everyDay := domStar && dowStar && monthStar
+
+	switch {
+	case minutes == 60 && hours == 24 && everyDay:
+		return CadenceEveryMinute
+	case minutes > 1 && hours == 24 && everyDay:
+		return CadenceSubHourly
+	case minutes == 1 && hours == 24 && everyDay:
+		return CadenceHourly
+	case minutes != 1 || hours != 1:
+		return CadenceIrregular
+	case everyDay:
+		return CadenceDaily
+	case monthStar && domStar && days == 1:
+		return CadenceWeekly
+	case monthStar && dowStar && monthDays == 1:
+		return CadenceMonthly
+	case months == 1 && dowStar && monthDays == 1:
+		return CadenceYearly
+	}
+	return CadenceIrregular
+}
+
+func (s *Server) validateCadenceHandler(w http.ResponseWriter, r *http.Request) {
+	var req CadenceRequest
+	if !s.decodeJSON(w, r, &req) {
+		return
+	}
+
+	expected := strings.ToLower(req.Cadence)
+	if !knownCadences[expected] {
+		writeFieldError(w, http.StatusBadRequest, ErrInvalidRequest, "cadence", "Unknown cadence: "+req.Cadence)
+		return
+	}
+
+	expression := cleanExpression(req.Expression)
+	schedule, err := parseExpression(expression)
+	if err != nil {
+		invalidCronExpressions.Inc()
+		writeError(w, http.StatusBadRequest, ErrInvalidCron, "Invalid cron expression: "+err.Error())
+		return
+	}
+
+	detected := classifyCadence(schedule)
+	response := CadenceResponse{
+		Expression:    expression,
+		RawExpression: req.Expression,
+		Expected:      expected,
+		Detected:      detected,
+		Matches:       detected == expected,
+	}
+
+	s.writeJSON(w, r, http.StatusOK, response)
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Category groups related cron expressions
+type Category struct {
+	ID          int       `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+func (s *Server) getCategoriesHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.dbContext(r)
+	defer cancel()
+
+	rows, err := s.db.queryRead(ctx, `
+		SELECT id, name, COALESCE(description, ''), created_at, updated_at
+		FROM categories
+		ORDER BY name
+	`)
+	if err != nil {
+		writeDBError(w, ctx, err)
+		return
+	}
+	defer rows.Close()
+
+	categories := []Category{}
+	for rows.Next() {
+		var c Category
+		err := rows.Scan(&c.ID, &c.Name, &c.Description, &c.CreatedAt, &c.UpdatedAt)
+		if err != nil {
+			writeDBError(w, ctx, err)
+			return
+		}
+		categories = append(categories, c)
+	}
+
+	s.writeJSON(w, r, http.StatusOK, categories)
+}
+
+func (s *Server) createCategoryHandler(w http.ResponseWriter, r *http.Request) {
+	var c Category
+	if !s.decodeJSON(w, r, &c) {
+		return
+	}
+
+	if c.Name == "" {
+		writeFieldError(w, http.StatusBadRequest, ErrInvalidRequest, "name", "Category name is required")
+		return
+	}
+
+	ctx, cancel := s.dbContext(r)
+	defer cancel()
+
+	now := time.Now()
+	err := s.db.writer().QueryRowContext(ctx, `
+		INSERT INTO categories (name, description, created_at, updated_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at, updated_at
+	`, c.Name, c.Description, now, now).Scan(&c.ID, &c.CreatedAt, &c.UpdatedAt)
+	if err != nil {
+		if isUniqueViolation(err) {
+			writeError(w, http.StatusConflict, ErrDuplicateName, "Category already exists")
+		} else {
+			writeDBError(w, ctx, err)
+		}
+		return
+	}
+
+	s.writeJSON(w, r, http.StatusCreated, c)
+}
+
+func (s *Server) getCategoryHandler(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	ctx, cancel := s.dbContext(r)
+	defer cancel()
+
+	var c Category
+	err := s.db.retryRead(ctx, func(db *sql.DB) error {
+		return db.QueryRowContext(ctx, `
+			SELECT id, name, COALESCE(description, ''), created_at, updated_at
+			FROM categories
+			WHERE id = $1
+		`, id).Scan(&c.ID, &c.Name, &c.Description, &c.CreatedAt, &c.UpdatedAt)
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			writeError(w, http.StatusNotFound, ErrNotFound, "Category not found")
+		} else {
+			writeDBError(w, ctx, err)
+		}
+		return
+	}
+
+	s.writeJSON(w, r, http.StatusOK, c)
+}
+
+func (s *Server) updateCategoryHandler(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	var c Category
+	if !s.decodeJSON(w, r, &c) {
+		return
+	}
+
+	if c.Name == "" {
+		writeFieldError(w, http.StatusBadRequest, ErrInvalidRequest, "name", "Category name is required")
+		return
+	}
+
+	ctx, cancel := s.dbContext(r)
+	defer cancel()
+
+	err := s.db.writer().QueryRowContext(ctx, `
+		UPDATE categories
+		SET name = $1, description = $2, updated_at = $3
+		WHERE id = $4
+		RETURNING id, created_at, updated_at
+	`, c.Name, c.Description, time.Now(), id).Scan(&c.ID, &c.CreatedAt, &c.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			writeError(w, http.StatusNotFound, ErrNotFound, "Category not found")
+		} else if isUniqueViolation(err) {
+			writeError(w, http.StatusConflict, ErrDuplicateName, "Category already exists")
+		} else {
+			writeDBError(w, ctx, err)
+		}
+		return
+	}
+	s.lists.invalidate()
+
+	s.writeJSON(w, r, http.StatusOK, c)
+}
+
+// deleteCategoryHandler removes a category. Expressions in it are kept and
+// have their category_id set to NULL by the foreign key.
+func (s *Server) deleteCategoryHandler(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	ctx, cancel := s.dbContext(r)
+	defer cancel()
+
+	result, err := s.db.writer().ExecContext(ctx, "DELETE FROM categories WHERE id = $1", id)
+	if err != nil {
+		writeDBError(w, ctx, err)
+		return
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, ErrDBError, err.Error())
+		return
+	}
+
+	if rowsAffected == 0 {
+		writeError(w, http.StatusNotFound, ErrNotFound, "Category not found")
+		return
+	}
+	s.lists.invalidate()
+
+	s.writeJSON(w, r, http.StatusOK, map[string]string{"message": "Category deleted successfully"})
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"log"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// parseTrustedProxies reads a comma-separated list of CIDRs. A bare IP is
+// treated as a single-address range; invalid entries are logged and skipped.
+func parseTrustedProxies(value string) []*net.IPNet {
+	proxies := []*net.IPNet{}
+	for _, item := range splitList(value) {
+		if !strings.Contains(item, "/") {
+			if ip := net.ParseIP(item); ip != nil && ip.To4() != nil {
+				item += "/32"
+			} else {
+				item += "/128"
+			}
+		}
+		_, network, err := net.ParseCIDR(item)
+		if err != nil {
+			log.Printf("Warning: ignoring invalid TRUSTED_PROXIES entry %q", item)
+			continue
+		}
+		proxies = append(proxies, network)
+	}
+	return proxies
+}
+
+// trustedProxy reports whether ip falls in one of the TRUSTED_PROXIES ranges
+func (s *Server) trustedProxy(ip net.IP) bool {
+	for _, network := range s.config.TrustedProxies {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// clientIP returns the address of the client that made the request.
+// X-Forwarded-For is only honoured when the direct peer is a trusted proxy;
+// its entries are then read right to left, skipping further trusted proxies,
+// so a client can't spoof its address by sending the header itself.
+func (s *Server) clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	peer := net.ParseIP(host)
+	if peer == nil || !s.trustedProxy(peer) {
+		return host
+	}
+
+	hops := strings.Split(r.Header.Get("X-Forwarded-For"), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		ip := net.ParseIP(strings.TrimSpace(hops[i]))
+		if ip == nil {
+			// A malformed hop can't be trusted past, stop at the last good one
+			break
+		}
+		host = ip.String()
+		if !s.trustedProxy(ip) {
+			break
+		}
+	}
+	return host
+}
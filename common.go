@@ -0,0 +1,140 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+const (
+	defaultCommonDays  = 7
+	maxCommonDays      = 31
+	defaultCommonLimit = 10
+	maxCommonLimit     = 100
+	maxCommonSchedules = 50
+	// maxCommonSteps caps the walk below; see commonRuns
+	maxCommonSteps = 100000
+)
+
+// CommonScheduleRequest is the request body for finding when several
+// expressions fire at the same time
+type CommonScheduleRequest struct {
+	Expressions []string `json:"expressions"`
+	Days        int      `json:"days"`
+	Limit       int      `json:"limit"`
+}
+
+// CommonScheduleResponse lists the shared run times found in the window
+type CommonScheduleResponse struct {
+	Expressions []string    `json:"expressions"`
+	From        time.Time   `json:"from"`
+	To          time.Time   `json:"to"`
+	Coincide    bool        `json:"coincide"`
+	Runs        []time.Time `json:"runs"`
+	Truncated   bool        `json:"truncated"`
+}
+
+// commonRuns intersects the run sets of schedules between from and to,
+// returning up to limit times when all of them fire. It walks the schedules
+// in step like a k-way merge: each round advances every schedule that is
+// behind the latest candidate, so the work is O(k * r) for k schedules where
+// r is the number of runs of the schedules visited, bounded by maxCommonSteps.
+// The second result reports whether the step cap cut the walk short.
+func commonRuns(schedules []cron.Schedule, from, to time.Time, limit int) ([]time.Time, bool) {
+	runs := []time.Time{}
+	next := make([]time.Time, len(schedules))
+	for i, schedule := range schedules {
+		next[i] = schedule.Next(from)
+	}
+
+	for steps := 0; len(runs) < limit; steps++ {
+		if steps == maxCommonSteps {
+			return runs, true
+		}
+
+		latest := next[0]
+		for _, t := range next {
+			if t.IsZero() || !t.Before(to) {
+				return runs, false
+			}
+			if t.After(latest) {
+				latest = t
+			}
+		}
+
+		aligned := true
+		for i, schedule := range schedules {
+			if next[i].Before(latest) {
+				// Jump straight to the first run at or after the candidate
+				next[i] = schedule.Next(latest.Add(-time.Second))
+				aligned = false
+			}
+		}
+		if !aligned {
+			continue
+		}
+
+		runs = append(runs, latest)
+		for i, schedule := range schedules {
+			next[i] = schedule.Next(latest)
+		}
+	}
+	return runs, false
+}
+
+func (s *Server) commonScheduleHandler(w http.ResponseWriter, r *http.Request) {
+	var req CommonScheduleRequest
+	if !s.decodeJSON(w, r, &req) {
+		return
+	}
+
+	if len(req.Expressions) < 2 || len(req.Expressions) > maxCommonSchedules {
+		writeFieldError(w, http.StatusBadRequest, ErrInvalidRequest, "expressions", fmt.Sprintf("Provide between 2 and %d expressions", maxCommonSchedules))
+		return
+	}
+
+	days, limit := req.Days, req.Limit
+	if days == 0 {
+		days = defaultCommonDays
+	}
+	if limit == 0 {
+		limit = defaultCommonLimit
+	}
+	if days < 0 || days > maxCommonDays {
+		writeFieldError(w, http.StatusBadRequest, ErrInvalidRequest, "days", fmt.Sprintf("days must be between 1 and %d", maxCommonDays))
+		return
+	}
+	if limit < 0 || limit > maxCommonLimit {
+		writeFieldError(w, http.StatusBadRequest, ErrInvalidRequest, "limit", fmt.Sprintf("limit must be between 1 and %d", maxCommonLimit))
+		return
+	}
+
+	expressions := make([]string, 0, len(req.Expressions))
+	schedules := make([]cron.Schedule, 0, len(req.Expressions))
+	for i, raw := range req.Expressions {
+		expression := cleanExpression(raw)
+		schedule, err := parseExpression(expression)
+		if err != nil {
+			invalidCronExpressions.Inc()
+			writeError(w, http.StatusBadRequest, ErrInvalidCron, fmt.Sprintf("Invalid cron expression at index %d: %s", i, err.Error()))
+			return
+		}
+		expressions = append(expressions, expression)
+		schedules = append(schedules, schedule)
+	}
+
+	from := time.Now().In(s.config.DefaultTimezone)
+	to := from.AddDate(0, 0, days)
+	runs, truncated := commonRuns(schedules, from, to, limit)
+
+	s.writeJSON(w, r, http.StatusOK, CommonScheduleResponse{
+		Expressions: expressions,
+		From:        from,
+		To:          to,
+		Coincide:    len(runs) > 0,
+		Runs:        runs,
+		Truncated:   truncated,
+	})
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// maxConfigBytes caps the size of a schedule config body
+const maxConfigBytes = 1 << 20
+
+// configKeyPattern matches environment variable style keys
+var configKeyPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// ConfigEntry is one KEY=expression line of a schedule config
+type ConfigEntry struct {
+	Line        int    `json:"line"`
+	Key         string `json:"key"`
+	Expression  string `json:"expression"`
+	Valid       bool   `json:"valid"`
+	Normalized  string `json:"normalized,omitempty"`
+	Description string `json:"description,omitempty"`
+	Error       string `json:"error,omitempty"`
+}
+
+// ConfigValidateResponse reports every entry of a schedule config in file
+// order. Valid is only set when every entry is.
+type ConfigValidateResponse struct {
+	Valid   bool          `json:"valid"`
+	Total   int           `json:"total"`
+	Invalid int           `json:"invalid"`
+	Entries []ConfigEntry `json:"entries"`
+}
+
+// parseScheduleConfig checks each KEY=expression line of an .env-style
+// config, skipping blank lines and comments. Values may be quoted and lines
+// may start with "export". A key set twice is reported on its later line.
+func parseScheduleConfig(content string) ([]ConfigEntry, error) {
+	entries := []ConfigEntry{}
+	seen := map[string]int{}
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		entry := ConfigEntry{Line: lineNumber}
+		key, value, found := strings.Cut(strings.TrimPrefix(line, "export "), "=")
+		entry.Key = strings.TrimSpace(key)
+		entry.Expression = cleanExpression(value)
+		switch first, duplicate := seen[entry.Key]; {
+		case !found:
+			entry.Error = "Expected KEY=expression"
+		case !configKeyPattern.MatchString(entry.Key):
+			entry.Error = fmt.Sprintf("Invalid key %q", entry.Key)
+		case duplicate:
+			entry.Error = fmt.Sprintf("Duplicate key, first set on line %d", first)
+		}
+		if entry.Error == "" {
+			seen[entry.Key] = lineNumber
+			if _, err := parseExpression(entry.Expression); err != nil {
+				entry.Error = "Invalid cron expression: " + err.Error()
+			} else {
+				entry.Valid = true
+				entry.Normalized = canonicalExpression(entry.Expression)
+				entry.Description = generateDescription(entry.Expression)
+			}
+		}
+		entries = append(entries, entry)
+	}
+	return entries, scanner.Err()
+}
+
+// validateConfigHandler validates a whole text/plain schedule config of
+// KEY=expression lines in one call, so CI can check it before deploying
+func (s *Server) validateConfigHandler(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxConfigBytes))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeError(w, http.StatusRequestEntityTooLarge, ErrPayloadTooLarge, fmt.Sprintf("Config exceeds %d bytes", maxConfigBytes))
+		} else {
+			writeError(w, http.StatusBadRequest, ErrInvalidRequest, "Reading config: "+err.Error())
+		}
+		return
+	}
+
+	entries, err := parseScheduleConfig(string(body))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, ErrInvalidRequest, "Reading config: "+err.Error())
+		return
+	}
+
+	response := ConfigValidateResponse{Total: len(entries), Entries: entries}
+	for _, entry := range entries {
+		if !entry.Valid {
+			response.Invalid++
+			invalidCronExpressions.Inc()
+		}
+	}
+	response.Valid = response.Invalid == 0
+	s.writeJSON(w, r, http.StatusOK, response)
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// Converters that operators can enable or disable with ENABLED_CONVERTERS
+const (
+	ConverterJenkins    = "jenkins"
+	ConverterAWS        = "aws"
+	ConverterQuartz     = "quartz"
+	ConverterKubernetes = "k8s"
+	ConverterRRule      = "rrule"
+	ConverterSystemd    = "systemd"
+)
+
+var knownConverters = []string{ConverterJenkins, ConverterAWS, ConverterQuartz, ConverterKubernetes, ConverterRRule, ConverterSystemd}
+
+// parseEnabledConverters reads a comma-separated list of converter names.
+// An empty value enables every known converter.
+func parseEnabledConverters(value string) map[string]bool {
+	enabled := map[string]bool{}
+	names := splitList(value)
+	if len(names) == 0 {
+		names = knownConverters
+	}
+	for _, name := range names {
+		name = strings.ToLower(name)
+		known := false
+		for _, k := range knownConverters {
+			if k == name {
+				known = true
+			}
+		}
+		if !known {
+			log.Printf("Warning: ignoring unknown converter %q in ENABLED_CONVERTERS", name)
+			continue
+		}
+		enabled[name] = true
+	}
+	return enabled
+}
+
+// converterEnabled reports whether the named converter is enabled
+func (s *Server) converterEnabled(name string) bool {
+	return s.config.EnabledConverters[name]
+}
+
+// handleConverter registers a converter endpoint only when the converter is
+// enabled, so disabled converters are never routed and fall through to a 404
+func (s *Server) handleConverter(r *mux.Router, name, path string, handler http.HandlerFunc) {
+	if !s.converterEnabled(name) {
+		return
+	}
+	r.HandleFunc(path, s.metricMiddleware(path, handler)).Methods("POST")
+}
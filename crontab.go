@@ -0,0 +1,214 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/robfig/cron/v3"
+)
+
+// defaultCommandTemplate makes each exported line a no-op comment naming the
+// expression, so the export is safe to install as-is
+const defaultCommandTemplate = "# {name}"
+
+// CrontabParseRequest is the request body for parsing a crontab file
+type CrontabParseRequest struct {
+	Crontab string `json:"crontab"`
+}
+
+// CrontabEntry is a single schedule line parsed from a crontab
+type CrontabEntry struct {
+	Line        int    `json:"line"`
+	Expression  string `json:"expression"`
+	Command     string `json:"command"`
+	Description string `json:"description,omitempty"`
+	Error       string `json:"error,omitempty"`
+}
+
+// CrontabCommand is a command within a schedule group, keeping its line number
+type CrontabCommand struct {
+	Line    int    `json:"line"`
+	Command string `json:"command"`
+}
+
+// CrontabGroup collects all crontab lines sharing the same schedule
+type CrontabGroup struct {
+	Expression  string           `json:"expression"`
+	Description string           `json:"description,omitempty"`
+	Error       string           `json:"error,omitempty"`
+	Commands    []CrontabCommand `json:"commands"`
+}
+
+// parseCrontab splits crontab content into schedule entries, skipping blank
+// lines, comments and environment assignments
+func parseCrontab(content string) []CrontabEntry {
+	entries := []CrontabEntry{}
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if strings.Contains(fields[0], "=") {
+			// Environment assignment such as MAILTO=ops@example.com
+			continue
+		}
+
+		entry := CrontabEntry{Line: lineNumber}
+		fieldCount := 5
+		if strings.HasPrefix(fields[0], "@") {
+			fieldCount = 1
+		}
+		if len(fields) <= fieldCount {
+			entry.Expression = strings.Join(fields, " ")
+			entry.Error = "Missing command"
+			entries = append(entries, entry)
+			continue
+		}
+
+		// Canonical form collapses repeated whitespace between fields
+		entry.Expression = strings.Join(fields[:fieldCount], " ")
+		entry.Command = strings.Join(fields[fieldCount:], " ")
+
+		if _, err := parseExpression(entry.Expression); err != nil {
+			entry.Error = "Invalid cron expression: " + err.Error()
+		} else {
+			entry.Description = generateDescription(entry.Expression)
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// scheduleKey identifies the schedule an expression runs on, so that
+// equivalent spellings such as "*/15 * * * *" and "0,15,30,45 * * * *", or
+// "@daily" and "@midnight", share a key. Invalid expressions are keyed on
+// their text.
+func scheduleKey(expression string) string {
+	schedule, err := parseExpression(expression)
+	if err != nil {
+		return "invalid " + expression
+	}
+	if spec, ok := schedule.(*cron.SpecSchedule); ok {
+		return fmt.Sprintf("%x %x %x %x %x %x", spec.Second, spec.Minute, spec.Hour, spec.Dom, spec.Month, spec.Dow)
+	}
+	return expression
+}
+
+// groupCrontabEntries groups entries by schedule in order of first appearance
+func groupCrontabEntries(entries []CrontabEntry) []CrontabGroup {
+	groups := []CrontabGroup{}
+	index := map[string]int{}
+	for _, entry := range entries {
+		key := scheduleKey(entry.Expression)
+		i, ok := index[key]
+		if !ok {
+			i = len(groups)
+			index[key] = i
+			groups = append(groups, CrontabGroup{
+				Expression:  entry.Expression,
+				Description: entry.Description,
+				Error:       entry.Error,
+				Commands:    []CrontabCommand{},
+			})
+		}
+		groups[i].Commands = append(groups[i].Commands, CrontabCommand{Line: entry.Line, Command: entry.Command})
+	}
+	return groups
+}
+
+func (s *Server) parseCrontabHandler(w http.ResponseWriter, r *http.Request) {
+	var req CrontabParseRequest
+	if !s.decodeJSON(w, r, &req) {
+		return
+	}
+
+	entries := parseCrontab(req.Crontab)
+
+	if r.URL.Query().Get("groupBySchedule") == "true" {
+		s.writeJSON(w, r, http.StatusOK, map[string][]CrontabGroup{"groups": groupCrontabEntries(entries)})
+		return
+	}
+	s.writeJSON(w, r, http.StatusOK, map[string][]CrontabEntry{"entries": entries})
+}
+
+// renderCommand fills the {id}, {name}, {expression} and {description}
+// placeholders of a command template. Each value is shell-quoted, so a name
+// such as "x; rm -rf ~" stays a single argument rather than live shell.
+func renderCommand(template string, exp CronExpression) string {
+	return strings.NewReplacer(
+		"{id}", crontabQuote(strconv.Itoa(exp.ID)),
+		"{name}", crontabQuote(exp.Name),
+		"{expression}", crontabQuote(exp.Expression),
+		"{description}", crontabQuote(exp.Description),
+	).Replace(template)
+}
+
+// crontabQuote single-quotes value for sh and escapes "%", which cron would
+// otherwise turn into a newline even inside quotes. Control characters such
+// as newlines become spaces so the entry stays on one line.
+func crontabQuote(value string) string {
+	value = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return ' '
+		}
+		return r
+	}, value)
+	value = strings.ReplaceAll(value, "'", `'\''`)
+	value = strings.ReplaceAll(value, "%", `\%`)
+	return "'" + value + "'"
+}
+
+// exportCrontabHandler renders all stored expressions as a crontab file, one
+// line per expression with its command built from ?command_template=
+func (s *Server) exportCrontabHandler(w http.ResponseWriter, r *http.Request) {
+	template := r.URL.Query().Get("command_template")
+	if template == "" {
+		template = defaultCommandTemplate
+	}
+
+	ctx, cancel := s.dbContext(r)
+	defer cancel()
+
+	rows, err := s.db.queryRead(ctx, `
+		SELECT `+expressionColumns+`
+		FROM cron_expressions e
+		WHERE e.deleted_at IS NULL
+		ORDER BY e.id
+	`)
+	if err != nil {
+		writeDBError(w, ctx, err)
+		return
+	}
+	defer rows.Close()
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Exported by cron-converter at %s\n", time.Now().Format(time.RFC3339))
+	for rows.Next() {
+		var exp CronExpression
+		if err := scanExpression(rows, &exp); err != nil {
+			writeDBError(w, ctx, err)
+			return
+		}
+		// Keep each entry on one line even if the template contains a newline
+		command := strings.ReplaceAll(renderCommand(template, exp), "\n", " ")
+		if !exp.Enabled {
+			// Keep paused entries visible but commented out
+			b.WriteString("# paused: ")
+		}
+		fmt.Fprintf(&b, "%s %s\n", exp.Expression, command)
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="crontab"`)
+	w.Write([]byte(b.String()))
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// Which day field made a run fire when both are restricted
+const (
+	TriggerDayOfMonth = "day-of-month"
+	TriggerDayOfWeek  = "day-of-week"
+	TriggerBoth       = "both"
+)
+
+// RunTrigger annotates a run with the day field that caused it
+type RunTrigger struct {
+	Run     string `json:"run"`
+	Trigger string `json:"trigger"`
+}
+
+// dayTriggers explains cron's OR rule for expressions that restrict both
+// day-of-month and day-of-week, such as "0 9 13 * 5": each run is labelled
+// with the field that matched its date. It returns nil when either day field
+// is a wildcard, since the rule doesn't apply.
+func dayTriggers(schedule cron.Schedule, times []time.Time) []RunTrigger {
+	if ys, ok := schedule.(yearSchedule); ok {
+		schedule = ys.base
+	}
+	spec, ok := schedule.(*cron.SpecSchedule)
+	if !ok || spec.Dom&starBit != 0 || spec.Dow&starBit != 0 {
+		return nil
+	}
+
+	triggers := make([]RunTrigger, 0, len(times))
+	for _, t := range times {
+		domMatch := spec.Dom&(1<<uint(t.Day())) != 0
+		dowMatch := spec.Dow&(1<<uint(t.Weekday())) != 0
+		trigger := TriggerBoth
+		if !dowMatch {
+			trigger = TriggerDayOfMonth
+		} else if !domMatch {
+			trigger = TriggerDayOfWeek
+		}
+		triggers = append(triggers, RunTrigger{Run: t.Format(executionFormat), Trigger: trigger})
+	}
+	return triggers
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+// dbContext derives the context for a request's database calls, bounded by
+// DB_QUERY_TIMEOUT so a slow query can't hold the request open indefinitely.
+// Callers must defer the returned cancel function until they've finished
+// reading any rows.
+func (s *Server) dbContext(r *http.Request) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(r.Context(), s.config.DBQueryTimeout)
+}
+
+// writeDBError reports a failed database call, answering 504 when the call
+// ran out of time and 500 otherwise. lib/pq surfaces a cancelled query as a
+// server error rather than the context's, so ctx is checked as well.
+func writeDBError(w http.ResponseWriter, ctx context.Context, err error) {
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		writeError(w, http.StatusGatewayTimeout, ErrDBTimeout, "Database query timed out")
+		return
+	}
+	writeError(w, http.StatusInternalServerError, ErrDBError, err.Error())
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/robfig/cron/v3"
+)
+
+// DedupMember is a stored expression within a duplicate group
+type DedupMember struct {
+	ID         int       `json:"id"`
+	Name       string    `json:"name"`
+	Expression string    `json:"expression"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// DuplicateGroup is a set of expressions that fire at the same times in the
+// same environment and timezone. Kept is the oldest.
+type DuplicateGroup struct {
+	Environment string        `json:"environment"`
+	Timezone    string        `json:"timezone"`
+	Kept        DedupMember   `json:"kept"`
+	Duplicates  []DedupMember `json:"duplicates"`
+}
+
+// DedupReport is the result of a dedup pass. Removed counts the duplicates
+// soft-deleted when Applied; Skipped counts stored expressions that no
+// longer parse and so weren't compared. Inconclusive counts comparisons
+// that couldn't be decided within maxEquivalenceRuns; those are left alone.
+type DedupReport struct {
+	Applied      bool             `json:"applied"`
+	Checked      int              `json:"checked"`
+	Skipped      int              `json:"skipped"`
+	Inconclusive int              `json:"inconclusive"`
+	Groups       []DuplicateGroup `json:"groups"`
+	Removed      int              `json:"removed"`
+}
+
+// dedupCandidate is the first expression seen with a given schedule, which
+// later expressions are compared against
+type dedupCandidate struct {
+	key       string
+	canonical string
+	exp       CronExpression
+	schedule  cron.Schedule
+	group     *DuplicateGroup
+}
+
+// sameSchedule reports whether a and b fire at the same times. Parsed
+// schedules with identical fields are the same outright; otherwise the runs
+// are compared over equivalenceWindow from from. known is false when that
+// walk is cut short by maxEquivalenceRuns, since dedup deletes rows on the
+// strength of the answer.
+func sameSchedule(a, b cron.Schedule, from time.Time) (same, known bool) {
+	if specA, ok := a.(*cron.SpecSchedule); ok {
+		if specB, ok := b.(*cron.SpecSchedule); ok && *specA == *specB {
+			return true, true
+		}
+	}
+	at, _, _, truncated := firstDivergence(a, b, from, from.Add(equivalenceWindow))
+	if !at.IsZero() {
+		return false, true
+	}
+	return !truncated, !truncated
+}
+
+// findDuplicates groups live expressions, oldest first, with the earliest
+// expression they are equivalent to. Expressions only match within the same
+// environment and timezone, where equal schedules mean equal run times.
+func (s *Server) findDuplicates(ctx context.Context, tx *sql.Tx, lock bool) (DedupReport, []CronExpression, error) {
+	report := DedupReport{Groups: []DuplicateGroup{}}
+	query := `
+		SELECT ` + expressionColumns + `
+		FROM cron_expressions e
+		WHERE e.deleted_at IS NULL
+		ORDER BY e.created_at, e.id
+	`
+	if lock {
+		query += " FOR UPDATE"
+	}
+	rows, err := tx.QueryContext(ctx, query)
+	if err != nil {
+		return report, nil, err
+	}
+	defer rows.Close()
+
+	candidates := []*dedupCandidate{}
+	kept := map[int]CronExpression{}
+	for rows.Next() {
+		if err := ctx.Err(); err != nil {
+			return report, nil, err
+		}
+		var exp CronExpression
+		if err := scanExpression(rows, &exp); err != nil {
+			return report, nil, err
+		}
+		report.Checked++
+		schedule, err := parseExpression(exp.Expression)
+		if err != nil {
+			report.Skipped++
+			continue
+		}
+
+		loc := s.expressionLocation(exp)
+		key := exp.Environment + " " + loc.String()
+		from := time.Now().In(loc)
+		canonical := canonicalExpression(exp.Expression)
+		var match *dedupCandidate
+		for _, c := range candidates {
+			if c.key != key {
+				continue
+			}
+			if c.canonical == canonical {
+				match = c
+				break
+			}
+			same, known := sameSchedule(c.schedule, schedule, from)
+			if !known {
+				report.Inconclusive++
+			}
+			if same {
+				match = c
+				break
+			}
+		}
+		member := DedupMember{ID: exp.ID, Name: exp.Name, Expression: exp.Expression, CreatedAt: exp.CreatedAt}
+		if match == nil {
+			candidates = append(candidates, &dedupCandidate{
+				key:       key,
+				canonical: canonical,
+				exp:       exp,
+				schedule:  schedule,
+				group:     &DuplicateGroup{Environment: exp.Environment, Timezone: loc.String(), Kept: member, Duplicates: []DedupMember{}},
+			})
+			continue
+		}
+		match.group.Duplicates = append(match.group.Duplicates, member)
+		kept[exp.ID] = match.exp
+	}
+	if err := rows.Err(); err != nil {
+		return report, nil, err
+	}
+
+	for _, c := range candidates {
+		if len(c.group.Duplicates) > 0 {
+			report.Groups = append(report.Groups, *c.group)
+		}
+	}
+	keptFor := []CronExpression{}
+	for _, group := range report.Groups {
+		for _, dup := range group.Duplicates {
+			keptFor = append(keptFor, kept[dup.ID])
+		}
+	}
+	return report, keptFor, nil
+}
+
+// dedupHandler reports groups of stored expressions with equivalent
+// schedules. With ?apply=true it keeps the oldest of each group and
+// soft-deletes the rest in the same transaction, recording each as merged
+// into the one kept.
+func (s *Server) dedupHandler(w http.ResponseWriter, r *http.Request) {
+	apply := r.URL.Query().Get("apply") == "true"
+
+	ctx, cancel := s.dbContext(r)
+	defer cancel()
+
+	tx, err := s.db.writer().BeginTx(ctx, nil)
+	if err != nil {
+		writeDBError(w, ctx, err)
+		return
+	}
+	defer tx.Rollback()
+
+	report, keptFor, err := s.findDuplicates(ctx, tx, apply)
+	if err != nil {
+		writeDBError(w, ctx, err)
+		return
+	}
+	if !apply || len(report.Groups) == 0 {
+		s.writeJSON(w, r, http.StatusOK, report)
+		return
+	}
+
+	ids := []int64{}
+	for _, group := range report.Groups {
+		for _, dup := range group.Duplicates {
+			ids = append(ids, int64(dup.ID))
+		}
+	}
+	result, err := tx.ExecContext(ctx, `
+		UPDATE cron_expressions
+		SET deleted_at = $1
+		WHERE id = ANY($2) AND deleted_at IS NULL
+	`, time.Now(), pq.Array(ids))
+	if err != nil {
+		writeDBError(w, ctx, err)
+		return
+	}
+	removed, err := result.RowsAffected()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, ErrDBError, err.Error())
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		writeDBError(w, ctx, err)
+		return
+	}
+
+	report.Applied = true
+	report.Removed = int(removed)
+	cronExpressionsStored.Sub(float64(removed))
+	s.lists.invalidate()
+	for i, id := range ids {
+		s.recordAudit(ctx, r, id, AuditMerge, &keptFor[i])
+	}
+
+	s.writeJSON(w, r, http.StatusOK, report)
+}
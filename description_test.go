@@ -0,0 +1,337 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestGenerateDescriptionSteppedRanges(t *testing.T) {
+	tests := []struct {
+		name       string
+		expression string
+		expected   string
+	}{
+		{"Hour range with step", "0 2-22/4 * * *",
+			"This cron expression will run at the start of every 4 hours from 02:00 to 22:00."},
+		{"Hour start with step", "0 3/6 * * *",
+			"This cron expression will run at the start of every 6 hours starting at 03:00."},
+		{"Hour wildcard step", "0 */4 * * *",
+			"This cron expression will run at the start of every 4 hours."},
+		{"Minute range with step", "10-50/5 * * * *",
+			"This cron expression will run every 5 minutes from minute 10 to 50."},
+		{"Minute start with step", "5/15 * * * *",
+			"This cron expression will run every 15 minutes starting at minute 5."},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := generateDescription(tt.expression); got != tt.expected {
+				t.Errorf("generateDescription(%q) = %q, expected %q", tt.expression, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestGenerateDescriptionMinutePastSteppedHours(t *testing.T) {
+	tests := []struct {
+		expression string
+		expected   string
+	}{
+		{"15 */2 * * *", "at 15 minutes past every 2 hours"},
+		{"30 */3 * * *", "at 30 minutes past every 3 hours"},
+		{"45 */12 * * *", "at 45 minutes past every 12 hours"},
+		{"1 */6 * * *", "at 1 minute past every 6 hours"},
+		{"0 */2 * * *", "at the start of every 2 hours"},
+		{"15 1/2 * * *", "at 15 minutes past every 2 hours starting at 01:00"},
+		{"30 8-18/2 * * *", "at 30 minutes past every 2 hours from 08:00 to 18:00"},
+		{"15 */2 * * 1-5", "at 15 minutes past every 2 hours on weekdays"},
+		{"5 */4 1 * *", "at 5 minutes past every 4 hours on the 1st of the month"},
+		{"15 */1 * * *", "at 15 minutes past every hour"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			expected := "This cron expression will run " + tt.expected + "."
+			if got := generateDescription(tt.expression); got != expected {
+				t.Errorf("generateDescription(%q) = %q, expected %q", tt.expression, got, expected)
+			}
+		})
+	}
+}
+
+func TestGenerateDescriptionNames(t *testing.T) {
+	tests := []struct {
+		name       string
+		expression string
+		expected   string
+	}{
+		{"Month list", "0 9 1 3,6,9 *",
+			"This cron expression will run at 09:00 on the 1st of the month in March, June, and September."},
+		{"Month range", "0 9 1 1-5 *",
+			"This cron expression will run at 09:00 on the 1st of the month from January to May."},
+		{"Single month", "0 9 1 11 *",
+			"This cron expression will run at 09:00 on the 1st of the month in November."},
+		{"Weekday range", "0 9 * * 1-5",
+			"This cron expression will run at 09:00 on weekdays."},
+		{"Day range", "0 9 * * 2-4",
+			"This cron expression will run at 09:00 from Tuesday to Thursday."},
+		{"Day list", "0 9 * * 1,3,5",
+			"This cron expression will run at 09:00 on Monday, Wednesday, and Friday."},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := generateDescription(tt.expression); got != tt.expected {
+				t.Errorf("generateDescription(%q) = %q, expected %q", tt.expression, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestDescribeBareStep(t *testing.T) {
+	expression := cleanExpression("/5 * * * *")
+	if _, err := parseExpression(expression); err != nil {
+		t.Fatalf("Expected %q to parse, got %v", expression, err)
+	}
+	expected := "This cron expression will run every 5 minutes."
+	if got := generateDescription(expression); got != expected {
+		t.Errorf("generateDescription(%q) = %q, expected %q", expression, got, expected)
+	}
+}
+
+func TestJoinNatural(t *testing.T) {
+	tests := []struct {
+		name     string
+		items    []string
+		expected string
+	}{
+		{"Empty", []string{}, ""},
+		{"One item", []string{"Monday"}, "Monday"},
+		{"Two items", []string{"Monday", "Friday"}, "Monday and Friday"},
+		{"Three items", []string{"Monday", "Tuesday", "Wednesday"}, "Monday, Tuesday, and Wednesday"},
+		{"Four items", []string{"0", "15", "30", "45"}, "0, 15, 30, and 45"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := joinNatural(tt.items); got != tt.expected {
+				t.Errorf("joinNatural(%q) = %q, expected %q", tt.items, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestGenerateDescriptionNaturalLists(t *testing.T) {
+	tests := []struct {
+		name       string
+		expression string
+		expected   string
+	}{
+		{"Two days", "0 9 * * 1,5",
+			"This cron expression will run at 09:00 on Monday and Friday."},
+		{"Two months", "0 0 1 6,12 *",
+			"This cron expression will run at midnight on the 1st of the month in June and December."},
+		{"Minute list", "0,15,30 * * * *",
+			"This cron expression will run at minutes 0, 15, and 30 of every hour."},
+		{"Hour list", "0 9,17 * * *",
+			"This cron expression will run at 09:00 and 17:00."},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := generateDescription(tt.expression); got != tt.expected {
+				t.Errorf("generateDescription(%q) = %q, expected %q", tt.expression, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestDescribeWithSeconds(t *testing.T) {
+	tests := []struct {
+		name       string
+		expression string
+		expected   string
+	}{
+		{"Second within minute step", "15 */5 * * * *",
+			"This cron expression will run at second 15 of every 5 minutes."},
+		{"Every 30 seconds", "*/30 * * * * *",
+			"This cron expression will run every 30 seconds."},
+		{"At second 0", "0 * * * * *",
+			"This cron expression will run at second 0 of every minute."},
+		{"Fixed second every minute", "15 * * * * *",
+			"This cron expression will run at second 15 of every minute."},
+		{"Fixed second during an hour", "15 * 9 * * *",
+			"This cron expression will run at second 15 of every minute between 09:00 and 09:59."},
+		{"Every second of a fixed minute", "* 0 9 * * *",
+			"This cron expression will run every second during the minute at 09:00."},
+		{"Every second", "* * * * * *",
+			"This cron expression will run every second."},
+		{"Seconds during an hour", "*/10 * 9 * * *",
+			"This cron expression will run every 10 seconds between 09:00 and 09:59."},
+		{"Second list at fixed minute", "0,30 5 * * * *",
+			"This cron expression will run at seconds 0 and 30, at 5 minutes past every hour."},
+		{"Second range", "10-20 */15 * * * *",
+			"This cron expression will run every second from second 10 to 20 of every 15 minutes."},
+		{"Second ranged step", "0-30/10 0 9 * * 1-5",
+			"This cron expression will run every 10 seconds from second 0 to 30 during the minute at 09:00 on weekdays."},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := describeWithSeconds(tt.expression, time.Sunday); got != tt.expected {
+				t.Errorf("describeWithSeconds(%q) = %q, expected %q", tt.expression, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestGenerateDescriptionSixFields(t *testing.T) {
+	// Six fields lead with seconds rather than trailing with a year
+	expected := "This cron expression will run every 30 seconds."
+	if got := generateDescription("*/30 * * * * *"); got != expected {
+		t.Errorf("generateDescription(%q) = %q, expected %q", "*/30 * * * * *", got, expected)
+	}
+
+	expected = "This cron expression will run at midnight on the 1st of the month in January in years 2025 to 2027."
+	if got := describeWithYear("0 0 1 1 * 2025-2027", time.Sunday); got != expected {
+		t.Errorf("describeWithYear(%q) = %q, expected %q", "0 0 1 1 * 2025-2027", got, expected)
+	}
+}
+
+func TestGenerateDescriptionCombinations(t *testing.T) {
+	tests := []struct {
+		expression string
+		expected   string
+	}{
+		{"* * * * *", "every minute."},
+		{"30 * * * 1", "at 30 minutes past every hour on Mondays."},
+		{"1 * * * *", "at 1 minute past every hour."},
+		{"0 * * * 1", "at the start of every hour on Mondays."},
+		{"*/15 * * * 1", "every 15 minutes on Mondays."},
+		{"0,30 * * * *", "at minutes 0 and 30 of every hour."},
+		{"0 9 * * 1", "at 09:00 on Mondays."},
+		{"15 10 * * *", "at 10:15."},
+		{"0 12 * * 0", "at noon on Sundays."},
+		{"0 9,17 * * 6,0", "at 09:00 and 17:00 on weekends."},
+		{"* 9 * * 1-5", "every minute between 09:00 and 09:59 on weekdays."},
+		{"*/10 14 * * *", "every 10 minutes between 14:00 and 14:59."},
+		{"0 0 1 1 *", "at midnight on the 1st of the month in January."},
+		{"0 9 13 * 5", "at 09:00 on the 13th of the month or on Fridays."},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			expected := "This cron expression will run " + tt.expected
+			if got := generateDescription(tt.expression); got != expected {
+				t.Errorf("generateDescription(%q) = %q, expected %q", tt.expression, got, expected)
+			}
+		})
+	}
+}
+
+func TestDescribeExpressionWeekStart(t *testing.T) {
+	tests := []struct {
+		name       string
+		expression string
+		weekStart  time.Weekday
+		expected   string
+	}{
+		{"Sunday first list", "0 9 * * 1,0,3", time.Sunday, "on Sunday, Monday, and Wednesday."},
+		{"Monday first list", "0 9 * * 0,1,3", time.Monday, "on Monday, Wednesday, and Sunday."},
+		{"Monday first names", "0 9 * * SUN,FRI", time.Monday, "on Friday and Sunday."},
+		{"Monday first range from Sunday", "0 9 * * 0-2", time.Monday, "from Monday to Tuesday and on Sundays."},
+		{"Sunday first range", "0 9 * * 0-2", time.Sunday, "from Sunday to Tuesday."},
+		{"Weekdays either way", "0 9 * * 1-5", time.Monday, "on weekdays."},
+		{"Weekends either way", "0 9 * * 6,0", time.Monday, "on weekends."},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := describeExpression(tt.expression, tt.weekStart); !strings.HasSuffix(got, tt.expected) {
+				t.Errorf("describeExpression(%q, %s) = %q, expected it to end with %q", tt.expression, tt.weekStart, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestDescriptionFallbackMetric(t *testing.T) {
+	before := testutil.ToFloat64(descriptionFallbacks.WithLabelValues("minute"))
+	generateDescription("10-20 9 * * *")
+	if got := testutil.ToFloat64(descriptionFallbacks.WithLabelValues("minute")); got != before+1 {
+		t.Errorf("Expected minute fallbacks to increase by 1, got %v -> %v", before, got)
+	}
+
+	before = testutil.ToFloat64(descriptionFallbacks.WithLabelValues("hour"))
+	generateDescription("0 9 * * *")
+	if got := testutil.ToFloat64(descriptionFallbacks.WithLabelValues("hour")); got != before {
+		t.Errorf("Expected no hour fallback for a specific hour, got %v -> %v", before, got)
+	}
+}
+
+func TestDescriptionDurationMetric(t *testing.T) {
+	samples := func() uint64 {
+		var m dto.Metric
+		if err := descriptionDuration.Write(&m); err != nil {
+			t.Fatal(err)
+		}
+		return m.GetHistogram().GetSampleCount()
+	}
+
+	s, _ := newTestServer(t)
+	before := samples()
+	if rec := serve(s, "POST", "/api/convert", `{"expression":"0 9 * * 1-5"}`); rec.Code != http.StatusOK {
+		t.Fatalf("Expected status %d but got %d", http.StatusOK, rec.Code)
+	}
+	if got := samples(); got != before+1 {
+		t.Errorf("Expected one description timing per convert, got %d -> %d", before, got)
+	}
+}
+
+func TestDescribeWrapAroundRanges(t *testing.T) {
+	tests := []struct {
+		name       string
+		expression string
+		expected   string
+	}{
+		{"Overnight hours", "0 22-2 * * *",
+			"This cron expression will run at the start of every hour from 22:00 through 02:00 (overnight)."},
+		{"Fri-Mon by number", "0 9 * * 5-1",
+			"This cron expression will run at 09:00 from Friday through Monday."},
+		{"Fri-Mon by name", "0 9 * * FRI-MON",
+			"This cron expression will run at 09:00 from Friday through Monday."},
+		{"Winter months", "0 0 1 11-2 *",
+			"This cron expression will run at midnight on the 1st of the month from November through February (across the new year)."},
+		{"Minutes across the hour", "50-10 * * * *",
+			"This cron expression will run every minute from minute 50 through minute 10 of the next hour."},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := generateDescription(tt.expression); got != tt.expected {
+				t.Errorf("generateDescription(%q) = %q, expected %q", tt.expression, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseWrapAroundRanges(t *testing.T) {
+	schedule, err := parseExpression("0 22-2 * * FRI-MON")
+	if err != nil {
+		t.Fatalf("parseExpression: %v", err)
+	}
+
+	// Thursday 2024-01-18 at 20:00; runs start Friday night
+	next := time.Date(2024, time.January, 18, 20, 0, 0, 0, time.UTC)
+	expected := []string{"Fri 00:00", "Fri 01:00", "Fri 02:00", "Fri 22:00", "Fri 23:00", "Sat 00:00"}
+	for i, want := range expected {
+		next = schedule.Next(next)
+		if got := next.Format("Mon 15:04"); got != want {
+			t.Errorf("run %d = %s, expected %s", i, got, want)
+		}
+	}
+}
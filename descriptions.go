@@ -0,0 +1,92 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// maxBulkDescriptions caps how many rows one bulk description update touches
+const maxBulkDescriptions = 1000
+
+// BulkDescriptionsResponse reports the outcome of a bulk description update.
+// Skipped lists ids that don't exist or are soft-deleted.
+type BulkDescriptionsResponse struct {
+	Updated int   `json:"updated"`
+	Skipped []int `json:"skipped"`
+}
+
+// bulkDescriptionsHandler sets the descriptions of many expressions from an
+// {"id": "description"} object in one transaction, so curated descriptions
+// can replace generated ones after an import. Only description and
+// updated_at are touched.
+func (s *Server) bulkDescriptionsHandler(w http.ResponseWriter, r *http.Request) {
+	var req map[string]string
+	if !s.decodeJSON(w, r, &req) {
+		return
+	}
+	if len(req) == 0 || len(req) > maxBulkDescriptions {
+		writeError(w, http.StatusBadRequest, ErrInvalidRequest, fmt.Sprintf("Provide between 1 and %d descriptions", maxBulkDescriptions))
+		return
+	}
+
+	ids := make([]int, 0, len(req))
+	descriptions := make(map[int]string, len(req))
+	for key, description := range req {
+		id, err := strconv.Atoi(key)
+		if err != nil {
+			writeFieldError(w, http.StatusBadRequest, ErrInvalidRequest, key, fmt.Sprintf("Invalid expression id %q", key))
+			return
+		}
+		ids = append(ids, id)
+		descriptions[id] = description
+	}
+	// Update in id order so concurrent bulk updates lock rows consistently
+	sort.Ints(ids)
+
+	ctx, cancel := s.dbContext(r)
+	defer cancel()
+
+	tx, err := s.db.writer().BeginTx(ctx, nil)
+	if err != nil {
+		writeDBError(w, ctx, err)
+		return
+	}
+	defer tx.Rollback()
+
+	response := BulkDescriptionsResponse{Skipped: []int{}}
+	updated := []CronExpression{}
+	now := time.Now()
+	for _, id := range ids {
+		var exp CronExpression
+		err := scanExpression(tx.QueryRowContext(ctx, `
+			UPDATE cron_expressions e
+			SET description = $1, updated_at = $2
+			WHERE e.id = $3 AND e.deleted_at IS NULL
+			RETURNING `+expressionColumns,
+			descriptions[id], now, id), &exp)
+		if err == sql.ErrNoRows {
+			response.Skipped = append(response.Skipped, id)
+			continue
+		}
+		if err != nil {
+			writeDBError(w, ctx, err)
+			return
+		}
+		updated = append(updated, exp)
+		response.Updated++
+	}
+
+	if err := tx.Commit(); err != nil {
+		writeDBError(w, ctx, err)
+		return
+	}
+	s.lists.invalidate()
+	for i := range updated {
+		s.recordAudit(ctx, r, updated[i].ID, AuditUpdate, &updated[i])
+	}
+	s.writeJSON(w, r, http.StatusOK, response)
+}
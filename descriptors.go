@@ -0,0 +1,31 @@
+package main
+
+import "strings"
+
+// descriptorAliases maps descriptors used by other cron implementations to
+// the canonical descriptor with the same meaning
+var descriptorAliases = map[string]string{
+	"@midnight": "@daily",
+	"@annually": "@yearly",
+}
+
+// descriptorExpressions gives the 5-field equivalent of each canonical descriptor
+var descriptorExpressions = map[string]string{
+	"@yearly":  "0 0 1 1 *",
+	"@monthly": "0 0 1 * *",
+	"@weekly":  "0 0 * * 0",
+	"@daily":   "0 0 * * *",
+	"@hourly":  "0 * * * *",
+}
+
+// resolveDescriptor maps a descriptor such as @midnight to its canonical name
+// (@daily) and 5-field equivalent (0 0 * * *). ok is false for anything that
+// isn't a known descriptor.
+func resolveDescriptor(expression string) (canonical, equivalent string, ok bool) {
+	canonical = strings.ToLower(strings.TrimSpace(expression))
+	if alias, isAlias := descriptorAliases[canonical]; isAlias {
+		canonical = alias
+	}
+	equivalent, ok = descriptorExpressions[canonical]
+	return canonical, equivalent, ok
+}
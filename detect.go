@@ -0,0 +1,105 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+// StandardSeconds and StandardDescriptor are only reported by detection
+const (
+	StandardSeconds    = "seconds"
+	StandardDescriptor = "descriptor"
+)
+
+// DetectRequest is the request body for detecting an expression's standard
+type DetectRequest struct {
+	Expression string `json:"expression"`
+}
+
+// DetectResult is the outcome of parsing an expression under one standard
+type DetectResult struct {
+	Standard    string `json:"standard"`
+	Valid       bool   `json:"valid"`
+	Description string `json:"description,omitempty"`
+	Error       string `json:"error,omitempty"`
+}
+
+// DetectResponse lists the standards an expression is valid under
+type DetectResponse struct {
+	Expression string         `json:"expression"`
+	Standards  []string       `json:"standards"`
+	Results    []DetectResult `json:"results"`
+}
+
+// detectStandards tries the expression under every supported standard
+func detectStandards(expression string) []DetectResult {
+	results := []DetectResult{}
+
+	// Descriptors are only tried for @-prefixed input; the field-based
+	// standards never accept it
+	if strings.HasPrefix(expression, "@") {
+		result := DetectResult{Standard: StandardDescriptor}
+		if _, _, ok := resolveDescriptor(expression); ok {
+			result.Valid = true
+			result.Description = generateDescription(expression)
+		} else {
+			result.Error = "unrecognized descriptor"
+		}
+		return append(results, result)
+	}
+
+	unix := DetectResult{Standard: StandardUnix}
+	if _, err := parseExpression(expression); err != nil {
+		unix.Error = err.Error()
+	} else {
+		unix.Valid = true
+		unix.Description = generateDescription(expression)
+	}
+	results = append(results, unix)
+
+	seconds := DetectResult{Standard: StandardSeconds}
+	if _, err := secondsParser.Parse(expression); err != nil {
+		seconds.Error = err.Error()
+	} else {
+		seconds.Valid = true
+		seconds.Description = describeWithSeconds(expression, time.Sunday)
+	}
+	results = append(results, seconds)
+
+	quartz := DetectResult{Standard: StandardQuartz}
+	standard, err := quartzToStandard(expression)
+	if err == nil {
+		_, err = parseExpression(standard)
+	}
+	if err != nil {
+		quartz.Error = err.Error()
+	} else {
+		quartz.Valid = true
+		quartz.Description = generateDescription(standard)
+	}
+	results = append(results, quartz)
+
+	return results
+}
+
+func (s *Server) detectHandler(w http.ResponseWriter, r *http.Request) {
+	var req DetectRequest
+	if !s.decodeJSON(w, r, &req) {
+		return
+	}
+
+	expression := cleanExpression(req.Expression)
+	response := DetectResponse{
+		Expression: expression,
+		Standards:  []string{},
+		Results:    detectStandards(expression),
+	}
+	for _, result := range response.Results {
+		if result.Valid {
+			response.Standards = append(response.Standards, result.Standard)
+		}
+	}
+
+	s.writeJSON(w, r, http.StatusOK, response)
+}
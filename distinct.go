@@ -0,0 +1,44 @@
+package main
+
+import (
+	"net/http"
+)
+
+// DistinctExpression is a unique stored expression with its usage count
+type DistinctExpression struct {
+	Expression  string `json:"expression"`
+	Count       int    `json:"count"`
+	Description string `json:"description"`
+}
+
+func (s *Server) getDistinctExpressionsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.dbContext(r)
+	defer cancel()
+
+	rows, err := s.db.queryRead(ctx, `
+		SELECT expression, COUNT(*)
+		FROM cron_expressions
+		WHERE deleted_at IS NULL
+		GROUP BY expression
+		ORDER BY COUNT(*) DESC, expression
+	`)
+	if err != nil {
+		writeDBError(w, ctx, err)
+		return
+	}
+	defer rows.Close()
+
+	distinct := []DistinctExpression{}
+	for rows.Next() {
+		var d DistinctExpression
+		err := rows.Scan(&d.Expression, &d.Count)
+		if err != nil {
+			writeDBError(w, ctx, err)
+			return
+		}
+		d.Description = s.descriptions.describe(d.Expression)
+		distinct = append(distinct, d)
+	}
+
+	s.writeJSON(w, r, http.StatusOK, distinct)
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// DowItem shows how one day-of-week list item reads in cron's 0-indexed
+// convention (0 or 7 = Sunday) and in 1-indexed systems such as Quartz
+// (1 = Sunday). An empty reading means the item is invalid there.
+type DowItem struct {
+	Item       string   `json:"item"`
+	Cron       []string `json:"cron"`
+	OneIndexed []string `json:"oneIndexed"`
+	Ambiguous  bool     `json:"ambiguous"`
+}
+
+// DowResponse reports how an expression's day-of-week field is interpreted
+type DowResponse struct {
+	Expression string    `json:"expression"`
+	Field      string    `json:"field"`
+	Resolved   []string  `json:"resolved"`
+	Items      []DowItem `json:"items"`
+	Ambiguous  bool      `json:"ambiguous"`
+	Warnings   []string  `json:"warnings"`
+}
+
+// dowDays expands a day-of-week item into day indexes (0 = Sunday) under a
+// convention whose numbers start at first: 0 for cron, 1 for Quartz. Names
+// mean the same in both. ok is false if a value is out of range.
+func dowDays(item string, first int) ([]int, bool) {
+	last := first + 6
+	if first == 0 {
+		last = 7 // cron also accepts 7 for Sunday
+	}
+
+	base, step := item, 1
+	if i := strings.Index(item, "/"); i >= 0 {
+		n, err := strconv.Atoi(item[i+1:])
+		if err != nil || n < 1 {
+			return nil, false
+		}
+		base, step = item[:i], n
+	}
+
+	value := func(token string) (int, bool) {
+		if day, ok := dowNumbers[strings.ToUpper(token)]; ok {
+			return day + first, true
+		}
+		n, err := strconv.Atoi(token)
+		return n, err == nil && n >= first && n <= last
+	}
+
+	start, end := first, first+6
+	if base != "*" && base != "?" {
+		bounds := strings.Split(base, "-")
+		var ok bool
+		if start, ok = value(bounds[0]); !ok {
+			return nil, false
+		}
+		end = start
+		if len(bounds) == 2 {
+			if end, ok = value(bounds[1]); !ok || end < start {
+				return nil, false
+			}
+		} else if len(bounds) > 2 {
+			return nil, false
+		} else if step > 1 {
+			end = last
+		}
+	}
+
+	days := []int{}
+	for v := start; v <= end; v += step {
+		days = append(days, (v-first)%7)
+	}
+	return days, true
+}
+
+// dayNames maps day indexes to names
+func dayNames(days []int) []string {
+	names := []string{"Sunday", "Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday"}
+	result := make([]string, 0, len(days))
+	for _, day := range days {
+		result = append(result, names[day])
+	}
+	return result
+}
+
+// containsDigit reports whether an item uses numbers rather than names
+func containsDigit(item string) bool {
+	base := strings.SplitN(item, "/", 2)[0]
+	return strings.ContainsAny(base, "0123456789")
+}
+
+// validateDowHandler explains how the day-of-week field reads under both
+// numbering conventions and warns about values that differ between them,
+// the classic off-by-one when moving schedules between systems
+func (s *Server) validateDowHandler(w http.ResponseWriter, r *http.Request) {
+	var req ConvertRequest
+	if !s.decodeJSON(w, r, &req) {
+		return
+	}
+
+	expression := cleanExpression(req.Expression)
+	if _, equivalent, ok := resolveDescriptor(expression); ok {
+		expression = equivalent
+	}
+	fields := strings.Fields(expression)
+	if len(fields) != len(cronFields) {
+		invalidCronExpressions.Inc()
+		writeError(w, http.StatusBadRequest, ErrInvalidCron, fmt.Sprintf("Invalid cron expression: expected %d fields, found %d", len(cronFields), len(fields)))
+		return
+	}
+
+	response := DowResponse{Expression: expression, Field: fields[4], Resolved: []string{}, Items: []DowItem{}, Warnings: []string{}}
+	seen := map[int]bool{}
+	for _, item := range strings.Split(fields[4], ",") {
+		cronDays, cronOK := dowDays(item, 0)
+		oneDays, oneOK := dowDays(item, 1)
+		if !cronOK {
+			invalidCronExpressions.Inc()
+			writeError(w, http.StatusBadRequest, ErrInvalidCron, fmt.Sprintf("Invalid cron expression: day-of-week item %q", item))
+			return
+		}
+
+		entry := DowItem{Item: item, Cron: dayNames(cronDays), OneIndexed: []string{}}
+		if oneOK {
+			entry.OneIndexed = dayNames(oneDays)
+		}
+		for _, day := range cronDays {
+			if !seen[day] {
+				seen[day] = true
+				response.Resolved = append(response.Resolved, dayNames([]int{day})[0])
+			}
+		}
+
+		if containsDigit(item) && item != "*" {
+			entry.Ambiguous = true
+			response.Ambiguous = true
+			switch {
+			case !oneOK:
+				response.Warnings = append(response.Warnings, fmt.Sprintf(
+					"%q means %s in cron but is out of range in 1-indexed systems", item, strings.Join(entry.Cron, ", ")))
+			default:
+				response.Warnings = append(response.Warnings, fmt.Sprintf(
+					"%q means %s in cron but %s in 1-indexed systems such as Quartz",
+					item, strings.Join(entry.Cron, ", "), strings.Join(entry.OneIndexed, ", ")))
+			}
+		}
+		if strings.Contains(strings.SplitN(item, "/", 2)[0], "7") {
+			response.Warnings = append(response.Warnings, fmt.Sprintf(
+				"%q uses 7 for Sunday, which systems expecting 0-6 reject; use 0 or SUN", item))
+		}
+		response.Items = append(response.Items, entry)
+	}
+	if response.Ambiguous {
+		response.Warnings = append(response.Warnings, "Use day names such as MON-FRI to mean the same day everywhere")
+	}
+
+	s.writeJSON(w, r, http.StatusOK, response)
+}
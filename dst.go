@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// dstLookahead is how far ahead dstWarning looks for clock changes
+const dstLookahead = 366 * 24 * time.Hour
+
+// dstTransition is an instant where a location's UTC offset changes
+type dstTransition struct {
+	at        time.Time
+	oldOffset int // seconds east of UTC
+	newOffset int
+}
+
+// dstTransitions returns the offset changes in loc between from and to
+func dstTransitions(loc *time.Location, from, to time.Time) []dstTransition {
+	transitions := []dstTransition{}
+	_, offset := from.In(loc).Zone()
+	for day := from; day.Before(to); day = day.Add(24 * time.Hour) {
+		next := day.Add(24 * time.Hour)
+		_, nextOffset := next.In(loc).Zone()
+		if nextOffset == offset {
+			continue
+		}
+
+		// Narrow the change down to the second it happens
+		lo, hi := day, next
+		for hi.Sub(lo) > time.Second {
+			mid := lo.Add(hi.Sub(lo) / 2)
+			if _, o := mid.In(loc).Zone(); o == offset {
+				lo = mid
+			} else {
+				hi = mid
+			}
+		}
+		transitions = append(transitions, dstTransition{at: hi, oldOffset: offset, newOffset: nextOffset})
+		offset = nextOffset
+	}
+	return transitions
+}
+
+// dstWarning reports whether schedule has runs whose local time is skipped or
+// repeated by a clock change in loc within the next year, with a message
+// describing the first one
+func dstWarning(schedule cron.Schedule, loc *time.Location, now time.Time) (bool, string) {
+	spec, ok := schedule.(*cron.SpecSchedule)
+	if ys, isYear := schedule.(yearSchedule); isYear {
+		spec, ok = ys.base.(*cron.SpecSchedule)
+	}
+	if !ok {
+		return false, ""
+	}
+
+	for _, tr := range dstTransitions(loc, now, now.Add(dstLookahead)) {
+		// Wall-clock times are held in UTC so they aren't normalised by loc.
+		// Springing forward skips wall times starting from the old offset;
+		// falling back repeats wall times starting from the new offset.
+		gap := tr.newOffset > tr.oldOffset
+		start := tr.at.UTC().Add(time.Duration(tr.newOffset) * time.Second)
+		length := time.Duration(tr.oldOffset-tr.newOffset) * time.Second
+		if gap {
+			start = tr.at.UTC().Add(time.Duration(tr.oldOffset) * time.Second)
+			length = -length
+		}
+
+		for wall := start; wall.Before(start.Add(length)); wall = wall.Add(time.Minute) {
+			if !specMatchesWall(spec, wall) {
+				continue
+			}
+			date := wall.Format("Mon Jan 2 2006")
+			if gap {
+				return true, fmt.Sprintf("Runs at %s are skipped on %s when %s springs forward",
+					wall.Format("15:04"), date, loc)
+			}
+			return true, fmt.Sprintf("Runs at %s happen twice on %s when %s falls back",
+				wall.Format("15:04"), date, loc)
+		}
+	}
+	return false, ""
+}
+
+// specMatchesWall reports whether spec fires at the wall-clock minute wall,
+// following cron's rule that day-of-month and day-of-week are ORed unless
+// either is a wildcard
+func specMatchesWall(spec *cron.SpecSchedule, wall time.Time) bool {
+	if spec.Minute&(1<<uint(wall.Minute())) == 0 ||
+		spec.Hour&(1<<uint(wall.Hour())) == 0 ||
+		spec.Month&(1<<uint(wall.Month())) == 0 {
+		return false
+	}
+	domMatch := spec.Dom&(1<<uint(wall.Day())) != 0
+	dowMatch := spec.Dow&(1<<uint(wall.Weekday())) != 0
+	if spec.Dom&starBit != 0 || spec.Dow&starBit != 0 {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
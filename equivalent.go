@@ -0,0 +1,108 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+const (
+	// equivalenceWindow covers a full leap-year cycle so day-of-month,
+	// day-of-week and February differences all get a chance to show up
+	equivalenceWindow = 4 * 366 * 24 * time.Hour
+	// maxEquivalenceRuns caps how many runs of each expression are compared
+	maxEquivalenceRuns = 100000
+)
+
+// EquivalentRequest is the request body for comparing two expressions
+type EquivalentRequest struct {
+	A string `json:"a"`
+	B string `json:"b"`
+}
+
+// EquivalentResponse reports whether two expressions fire at the same times.
+// FirstDivergence is the earliest time only one of them fires, and
+// DivergentExpression names which one ("a" or "b").
+type EquivalentResponse struct {
+	A                   string     `json:"a"`
+	B                   string     `json:"b"`
+	Equivalent          bool       `json:"equivalent"`
+	FirstDivergence     *time.Time `json:"firstDivergence,omitempty"`
+	DivergentExpression string     `json:"divergentExpression,omitempty"`
+	ComparedRuns        int        `json:"comparedRuns"`
+	From                time.Time  `json:"from"`
+	To                  time.Time  `json:"to"`
+	Truncated           bool       `json:"truncated"`
+}
+
+// firstDivergence walks the run sequences of a and b from from until to and
+// returns the first time only one fires along with "a" or "b". It returns the
+// zero time if the sequences agree, with the number of runs compared and
+// whether maxEquivalenceRuns cut the walk short.
+func firstDivergence(a, b cron.Schedule, from, to time.Time) (time.Time, string, int, bool) {
+	nextA, nextB := a.Next(from), b.Next(from)
+	for runs := 0; ; runs++ {
+		doneA := nextA.IsZero() || !nextA.Before(to)
+		doneB := nextB.IsZero() || !nextB.Before(to)
+		switch {
+		case doneA && doneB:
+			return time.Time{}, "", runs, false
+		case runs == maxEquivalenceRuns:
+			return time.Time{}, "", runs, true
+		case doneB || (!doneA && nextA.Before(nextB)):
+			return nextA, "a", runs, false
+		case doneA || nextB.Before(nextA):
+			return nextB, "b", runs, false
+		}
+		nextA, nextB = a.Next(nextA), b.Next(nextB)
+	}
+}
+
+// compareSchedules compares a and b over the equivalence window starting at
+// from. A walk cut short by maxEquivalenceRuns hasn't shown that the
+// schedules agree, so it is never reported as equivalent.
+func compareSchedules(a, b cron.Schedule, from time.Time) EquivalentResponse {
+	to := from.Add(equivalenceWindow)
+	divergence, which, runs, truncated := firstDivergence(a, b, from, to)
+
+	response := EquivalentResponse{
+		Equivalent:   divergence.IsZero() && !truncated,
+		ComparedRuns: runs,
+		From:         from,
+		To:           to,
+		Truncated:    truncated,
+	}
+	if !divergence.IsZero() {
+		response.FirstDivergence = &divergence
+		response.DivergentExpression = which
+	}
+	return response
+}
+
+// equivalentHandler reports whether two expressions are semantically equal
+// by comparing their run times rather than their text, for dedup tooling
+func (s *Server) equivalentHandler(w http.ResponseWriter, r *http.Request) {
+	var req EquivalentRequest
+	if !s.decodeJSON(w, r, &req) {
+		return
+	}
+
+	a, b := cleanExpression(req.A), cleanExpression(req.B)
+	scheduleA, err := parseExpression(a)
+	if err != nil {
+		invalidCronExpressions.Inc()
+		writeFieldError(w, http.StatusBadRequest, ErrInvalidCron, "a", "Invalid cron expression a: "+err.Error())
+		return
+	}
+	scheduleB, err := parseExpression(b)
+	if err != nil {
+		invalidCronExpressions.Inc()
+		writeFieldError(w, http.StatusBadRequest, ErrInvalidCron, "b", "Invalid cron expression b: "+err.Error())
+		return
+	}
+
+	response := compareSchedules(scheduleA, scheduleB, time.Now().In(s.config.DefaultTimezone))
+	response.A, response.B = a, b
+	s.writeJSON(w, r, http.StatusOK, response)
+}
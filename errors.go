@@ -0,0 +1,68 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// Error codes returned in ErrorBody.Code. Clients branch on these rather than
+// on messages, so existing codes must not be renamed or reused.
+const (
+	ErrInvalidRequest       = "INVALID_REQUEST"
+	ErrInvalidJSON          = "INVALID_JSON"
+	ErrInvalidCron          = "INVALID_CRON"
+	ErrInvalidTimezone      = "INVALID_TIMEZONE"
+	ErrNotFound             = "NOT_FOUND"
+	ErrDuplicateName        = "DUPLICATE_NAME"
+	ErrConflict             = "CONFLICT"
+	ErrTooFrequent          = "TOO_FREQUENT"
+	ErrUnprocessable        = "UNPROCESSABLE"
+	ErrExpired              = "EXPIRED"
+	ErrUnauthorized         = "UNAUTHORIZED"
+	ErrForbidden            = "FORBIDDEN"
+	ErrUnsupportedMediaType = "UNSUPPORTED_MEDIA_TYPE"
+	ErrPayloadTooLarge      = "PAYLOAD_TOO_LARGE"
+	ErrNotImplemented       = "NOT_IMPLEMENTED"
+	ErrUnavailable          = "UNAVAILABLE"
+	ErrDBError              = "DB_ERROR"
+	ErrDBTimeout            = "DB_TIMEOUT"
+	ErrInternal             = "INTERNAL"
+)
+
+// ErrorBody describes a failed request. Field names the request field at
+// fault where there is one; the remaining members carry detail for specific
+// codes and are omitted otherwise.
+type ErrorBody struct {
+	Code      string   `json:"code"`
+	Message   string   `json:"message"`
+	Field     string   `json:"field,omitempty"`
+	Token     string   `json:"token,omitempty"`
+	Expected  string   `json:"expected,omitempty"`
+	Offset    int64    `json:"offset,omitempty"`
+	Problems  []string `json:"problems,omitempty"`
+	RequestID string   `json:"request_id,omitempty"`
+}
+
+// ErrorResponse is the body of every error response
+type ErrorResponse struct {
+	Error ErrorBody `json:"error"`
+}
+
+// writeError responds with status and a JSON error body
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	writeErrorBody(w, status, ErrorBody{Code: code, Message: message})
+}
+
+// writeFieldError is writeError for a problem with one request field
+func writeFieldError(w http.ResponseWriter, status int, code, field, message string) {
+	writeErrorBody(w, status, ErrorBody{Code: code, Message: message, Field: field})
+}
+
+// writeErrorBody responds with status and body. Errors are never enveloped or
+// negotiated, so clients can always read them as JSON.
+func writeErrorBody(w http.ResponseWriter, status int, body ErrorBody) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(ErrorResponse{Error: body})
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// StandardJenkins is only used when explaining an expression across standards
+const StandardJenkins = "jenkins"
+
+// jenkinsUnsupported matches the Quartz and extension constructs Jenkins
+// rejects: L, W and #. It avoids matching day names such as WED.
+var jenkinsUnsupported = regexp.MustCompile(`(^|[,\d])L|\dW|#`)
+
+// ExplainStandardsRequest is the request body for comparing how standards
+// read an expression
+type ExplainStandardsRequest struct {
+	Expression string `json:"expression"`
+}
+
+// StandardReading is how one standard interprets the expression. Equivalent
+// is the 5-field Unix expression with the same meaning.
+type StandardReading struct {
+	Standard    string   `json:"standard"`
+	Valid       bool     `json:"valid"`
+	Error       string   `json:"error,omitempty"`
+	Equivalent  string   `json:"equivalent,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Notes       []string `json:"notes"`
+}
+
+// StandardsComparison says whether two valid readings fire at the same
+// times, and when they first disagree if not
+type StandardsComparison struct {
+	A               string     `json:"a"`
+	B               string     `json:"b"`
+	Equivalent      bool       `json:"equivalent"`
+	FirstDivergence *time.Time `json:"firstDivergence,omitempty"`
+}
+
+// ExplainStandardsResponse lists each standard's reading and compares every
+// pair of valid readings. Agree is true when all valid readings match.
+type ExplainStandardsResponse struct {
+	Expression  string                `json:"expression"`
+	Readings    []StandardReading     `json:"readings"`
+	Comparisons []StandardsComparison `json:"comparisons"`
+	Agree       bool                  `json:"agree"`
+}
+
+// dowHasNumbers reports whether a day-of-week field uses numbers, which is
+// where the standards' numbering differs
+func dowHasNumbers(dow string) bool {
+	return strings.ContainsAny(dow, "0123456789")
+}
+
+// restrictedDays reports whether both day fields are restricted, in which
+// case Unix-style crons fire on days matching either
+func restrictedDays(dom, dow string) bool {
+	return dom != "*" && dom != "?" && dow != "*" && dow != "?"
+}
+
+// unixReading interprets the expression as a standard 5-field crontab entry
+func unixReading(expression string) StandardReading {
+	reading := StandardReading{Standard: StandardUnix, Notes: []string{}}
+	if _, err := parseExpression(expression); err != nil {
+		reading.Error = err.Error()
+		return reading
+	}
+	reading.Valid = true
+	reading.Equivalent = expression
+
+	if fields := strings.Fields(expression); len(fields) == len(cronFields) {
+		if dowHasNumbers(fields[4]) {
+			reading.Notes = append(reading.Notes, "day-of-week numbers run 0-6 with Sunday as 0")
+		}
+		if restrictedDays(fields[2], fields[4]) {
+			reading.Notes = append(reading.Notes, "runs on days matching either day-of-month or day-of-week")
+		}
+		if strings.Contains(fields[2], "W") || strings.HasPrefix(fields[2], "L") {
+			reading.Notes = append(reading.Notes, "L and W are accepted here as an extension; most Unix crons reject them")
+		}
+	}
+	return reading
+}
+
+// jenkinsReading interprets the expression as a Jenkins trigger: 5 fields,
+// day-of-week 0-7 with both ends Sunday, H for a per-job hashed value and no
+// L, W or #
+func jenkinsReading(expression string) StandardReading {
+	reading := StandardReading{Standard: StandardJenkins, Notes: []string{}}
+	if _, equivalent, ok := resolveDescriptor(expression); ok {
+		expression = equivalent
+	}
+	fields := strings.Fields(expression)
+	if len(fields) != len(cronFields) {
+		reading.Error = fmt.Sprintf("expected %d fields, found %d", len(cronFields), len(fields))
+		return reading
+	}
+	if jenkinsUnsupported.MatchString(fields[2]) || jenkinsUnsupported.MatchString(fields[4]) {
+		reading.Error = "Jenkins doesn't support L, W or #"
+		return reading
+	}
+
+	for i, field := range fields {
+		if strings.Contains(field, "H") && !strings.Contains(strings.ToUpper(field), "TH") {
+			fields[i] = strings.ReplaceAll(field, "H", fmt.Sprint(cronFields[i].min))
+			reading.Notes = append(reading.Notes, fmt.Sprintf("H in the %s field is a stable per-job hash; shown here as %d", cronFields[i].name, cronFields[i].min))
+		}
+	}
+	if dowHasNumbers(fields[4]) {
+		reading.Notes = append(reading.Notes, "day-of-week numbers run 0-7 with both 0 and 7 as Sunday")
+		// 7 only stands alone or ends a range, so it can be mapped back to
+		// 0 or to 6 (Saturday) respectively without changing the meaning
+		items := strings.Split(fields[4], ",")
+		for i, item := range items {
+			switch {
+			case item == "7":
+				items[i] = "0"
+			case strings.HasSuffix(item, "-7"):
+				items[i] = strings.TrimSuffix(item, "-7") + "-6,0"
+			}
+		}
+		fields[4] = strings.Join(items, ",")
+	}
+	if restrictedDays(fields[2], fields[4]) {
+		reading.Notes = append(reading.Notes, "runs on days matching either day-of-month or day-of-week")
+	}
+
+	equivalent := strings.Join(fields, " ")
+	if _, err := parseExpression(equivalent); err != nil {
+		reading.Error = err.Error()
+		reading.Notes = []string{}
+		return reading
+	}
+	reading.Valid = true
+	reading.Equivalent = equivalent
+	return reading
+}
+
+// quartzReading interprets the expression as Quartz. A 5-field expression is
+// read with a seconds field of 0 prepended, since that is how it would
+// usually be pasted into a Quartz trigger.
+func quartzReading(expression string) StandardReading {
+	reading := StandardReading{Standard: StandardQuartz, Notes: []string{}}
+	fields := strings.Fields(expression)
+	if len(fields) == len(cronFields) {
+		fields = append([]string{"0"}, fields...)
+		reading.Notes = append(reading.Notes, "Quartz has a leading seconds field; read here as 0")
+	}
+	if len(fields) >= 6 {
+		if fields[3] != "?" && fields[5] != "?" {
+			reading.Notes = append(reading.Notes, "Quartz requires ? in day-of-month or day-of-week and rejects this as written")
+		}
+		if dowHasNumbers(fields[5]) {
+			reading.Notes = append(reading.Notes, "day-of-week numbers run 1-7 with Sunday as 1")
+		}
+	}
+
+	equivalent, err := quartzToStandard(strings.Join(fields, " "))
+	if err == nil {
+		_, err = parseExpression(equivalent)
+	}
+	if err != nil {
+		reading.Error = err.Error()
+		return reading
+	}
+	reading.Valid = true
+	reading.Equivalent = equivalent
+	return reading
+}
+
+// explainStandardsHandler shows how Unix cron, Jenkins and Quartz each read
+// the same expression and where their schedules diverge, for users porting
+// schedules between systems
+func (s *Server) explainStandardsHandler(w http.ResponseWriter, r *http.Request) {
+	var req ExplainStandardsRequest
+	if !s.decodeJSON(w, r, &req) {
+		return
+	}
+
+	expression := cleanExpression(req.Expression)
+	if expression == "" {
+		writeFieldError(w, http.StatusBadRequest, ErrInvalidRequest, "expression", "expression is required")
+		return
+	}
+
+	response := ExplainStandardsResponse{
+		Expression:  expression,
+		Readings:    []StandardReading{unixReading(expression), jenkinsReading(expression), quartzReading(expression)},
+		Comparisons: []StandardsComparison{},
+		Agree:       true,
+	}
+	for i := range response.Readings {
+		if response.Readings[i].Valid {
+			response.Readings[i].Description = generateDescription(response.Readings[i].Equivalent)
+		}
+	}
+
+	from := time.Now().UTC()
+	to := from.Add(equivalenceWindow)
+	for i, a := range response.Readings {
+		for _, b := range response.Readings[i+1:] {
+			if !a.Valid || !b.Valid {
+				continue
+			}
+			comparison := StandardsComparison{A: a.Standard, B: b.Standard, Equivalent: true}
+			if a.Equivalent != b.Equivalent {
+				scheduleA, _ := parseExpression(a.Equivalent)
+				scheduleB, _ := parseExpression(b.Equivalent)
+				if divergence, _, _, _ := firstDivergence(scheduleA, scheduleB, from, to); !divergence.IsZero() {
+					comparison.Equivalent = false
+					comparison.FirstDivergence = &divergence
+					response.Agree = false
+				}
+			}
+			response.Comparisons = append(response.Comparisons, comparison)
+		}
+	}
+
+	s.writeJSON(w, r, http.StatusOK, response)
+}
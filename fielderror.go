@@ -0,0 +1,95 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// FieldError pinpoints the field and token that make an expression invalid,
+// so editors can highlight it inline
+type FieldError struct {
+	Error  string
+	Field  int
+	Token  string
+	Reason string
+}
+
+// locateFieldError checks each field of a 5-field expression on its own and
+// returns the first bad item, or nil if every field looks valid. Descriptors
+// and expressions with the wrong field count are left to the parser.
+func locateFieldError(expression string) *FieldError {
+	fields := strings.Fields(expression)
+	if len(fields) != len(cronFields) {
+		return nil
+	}
+
+	for i, field := range fields {
+		f := cronFields[i]
+		for _, item := range strings.Split(field, ",") {
+			if reason := checkFieldItem(item, f); reason != "" {
+				return &FieldError{
+					Error:  "Invalid cron expression: " + reason,
+					Field:  i,
+					Token:  item,
+					Reason: reason,
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// checkFieldItem validates one comma-separated item of a field, returning a
+// reason such as "day-of-month out of range", or "" if it's valid
+func checkFieldItem(item string, f cronField) string {
+	base, step := item, ""
+	if i := strings.Index(item, "/"); i >= 0 {
+		base, step = item[:i], item[i+1:]
+		if n, err := strconv.Atoi(step); err != nil || n < 1 {
+			return f.name + " has an invalid step"
+		}
+	}
+	if base == "*" || (base == "?" && (f.name == "day-of-month" || f.name == "day-of-week")) {
+		return ""
+	}
+	if f.name == "day-of-month" && step == "" {
+		// Nearest-weekday days such as 15W or LW, see weekdaySchedule
+		if strings.EqualFold(base, "LW") {
+			return ""
+		}
+		base = strings.TrimSuffix(strings.ToUpper(base), "W")
+	}
+
+	bounds := strings.Split(base, "-")
+	if len(bounds) > 2 {
+		return f.name + " has an invalid range"
+	}
+	values := make([]int, len(bounds))
+	for j, bound := range bounds {
+		n, ok := f.value(bound)
+		if !ok {
+			return f.name + " has an invalid value"
+		}
+		if n < f.min || n > f.max {
+			return f.name + " out of range"
+		}
+		values[j] = n
+	}
+	if len(values) == 2 && values[0] > values[1] && (step != "" || !wrapFields[f.name]) {
+		return f.name + " range starts after it ends"
+	}
+	return ""
+}
+
+// writeInvalidExpression responds 400 with the field-level detail of an
+// invalid expression, naming the cron field and the offending token
+func writeInvalidExpression(w http.ResponseWriter, fieldErr *FieldError) {
+	invalidCronExpressions.Inc()
+	writeErrorBody(w, http.StatusBadRequest, ErrorBody{
+		Code:    ErrInvalidCron,
+		Message: fieldErr.Error,
+		Field:   cronFields[fieldErr.Field].name,
+		Token:   fieldErr.Token,
+	})
+}
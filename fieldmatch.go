@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+
+	"github.com/robfig/cron/v3"
+)
+
+// maxListLimit bounds ?limit= on the expressions list
+const maxListLimit = 1000
+
+// fieldIndexes maps the names accepted by ?field= to positions in cronFields
+var fieldIndexes = map[string]int{
+	"minute":       0,
+	"hour":         1,
+	"day-of-month": 2,
+	"dom":          2,
+	"month":        3,
+	"day-of-week":  4,
+	"dow":          4,
+}
+
+// fieldFilter selects expressions whose field fires on value, so
+// ?field=hour&value=9 finds every job that can run in the 9 o'clock hour
+type fieldFilter struct {
+	index int
+	value int
+}
+
+// parseFieldFilter reads ?field= and ?value=, returning nil when neither is
+// set. Values may be names where the field has them, such as MON or JAN.
+func parseFieldFilter(query url.Values) (*fieldFilter, error) {
+	field, value := query.Get("field"), query.Get("value")
+	if field == "" && value == "" {
+		return nil, nil
+	}
+	if field == "" || value == "" {
+		return nil, fmt.Errorf("field and value must be given together")
+	}
+	index, ok := fieldIndexes[field]
+	if !ok {
+		return nil, fmt.Errorf("Unknown field %q", field)
+	}
+	f := cronFields[index]
+	n, ok := f.value(value)
+	if !ok || n < f.min || n > f.max {
+		return nil, fmt.Errorf("Invalid %s value %q", f.name, value)
+	}
+	return &fieldFilter{index: index, value: n}, nil
+}
+
+// matches reports whether the expression's field includes the value, after
+// expanding wildcards, ranges, steps and lists. Expressions that don't parse
+// never match. A nearest-weekday day-of-month such as 15W matches its
+// nominal day; LW has no fixed day and matches none.
+func (f *fieldFilter) matches(expression string) bool {
+	schedule, err := parseExpression(expression)
+	if err != nil {
+		return false
+	}
+	if ws, ok := schedule.(weekdaySchedule); ok {
+		if f.index == 2 {
+			return ws.day == f.value
+		}
+		schedule = ws.base
+	}
+	spec, ok := schedule.(*cron.SpecSchedule)
+	if !ok {
+		return false
+	}
+	bits := []uint64{spec.Minute, spec.Hour, spec.Dom, spec.Month, spec.Dow}[f.index]
+	return bits&(1<<uint(f.value)) != 0
+}
+
+// parsePage reads ?limit= and ?offset=. A zero limit means no limit.
+func parsePage(query url.Values) (limit, offset int, err error) {
+	if v := query.Get("limit"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil || limit < 1 || limit > maxListLimit {
+			return 0, 0, fmt.Errorf("limit must be between 1 and %d", maxListLimit)
+		}
+	}
+	if v := query.Get("offset"); v != "" {
+		if offset, err = strconv.Atoi(v); err != nil || offset < 0 {
+			return 0, 0, fmt.Errorf("offset must be a non-negative integer")
+		}
+	}
+	return limit, offset, nil
+}
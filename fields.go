@@ -0,0 +1,41 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+)
+
+// cronField describes one field of a standard 5-field expression
+type cronField struct {
+	name     string
+	min, max int
+	names    map[string]int
+}
+
+var monthNumbers = map[string]int{
+	"JAN": 1, "FEB": 2, "MAR": 3, "APR": 4, "MAY": 5, "JUN": 6,
+	"JUL": 7, "AUG": 8, "SEP": 9, "OCT": 10, "NOV": 11, "DEC": 12,
+}
+
+var dowNumbers = map[string]int{
+	"SUN": 0, "MON": 1, "TUE": 2, "WED": 3, "THU": 4, "FRI": 5, "SAT": 6,
+}
+
+// cronFields lists the fields of a standard expression in order, with the
+// bounds accepted by the parser
+var cronFields = []cronField{
+	{name: "minute", min: 0, max: 59},
+	{name: "hour", min: 0, max: 23},
+	{name: "day-of-month", min: 1, max: 31},
+	{name: "month", min: 1, max: 12, names: monthNumbers},
+	{name: "day-of-week", min: 0, max: 6, names: dowNumbers},
+}
+
+// value parses a single field value, accepting names where the field has them
+func (f cronField) value(token string) (int, bool) {
+	if n, ok := f.names[strings.ToUpper(token)]; ok {
+		return n, true
+	}
+	n, err := strconv.Atoi(token)
+	return n, err == nil
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// maxFitDatetimes bounds how many datetimes one fit request may contain
+	maxFitDatetimes = 1000
+	// maxFitRuns bounds how many runs are walked when checking a fit for
+	// extra runs between the first and last datetime
+	maxFitRuns = 100000
+)
+
+// FitRequest is the request body for fitting an expression to datetimes
+type FitRequest struct {
+	Datetimes []string `json:"datetimes"`
+	Timezone  string   `json:"timezone"`
+}
+
+// FitResponse is the tightest single expression firing at every datetime.
+// Approximate is set when it also fires at other times between the first and
+// last datetime.
+type FitResponse struct {
+	Expression  string `json:"expression"`
+	Description string `json:"description"`
+	Timezone    string `json:"timezone"`
+	Matched     int    `json:"matched"`
+	Approximate bool   `json:"approximate"`
+	ExtraRuns   int    `json:"extraRuns,omitempty"`
+	Warning     string `json:"warning,omitempty"`
+}
+
+// fitExpression builds an expression firing at every given time from the
+// distinct values of each field. Minutes and hours are kept exactly. Days use
+// day-of-week when the times share a weekly pattern and day-of-month
+// otherwise, since restricting both would make cron fire when either matches.
+// The month is only kept for day-of-month fits: weekly patterns recur across
+// months.
+func fitExpression(times []time.Time) (string, error) {
+	var minutes, hours, doms, months, dows []int
+	for _, t := range times {
+		if t.Second() != 0 || t.Nanosecond() != 0 {
+			return "", fmt.Errorf("%s isn't on a whole minute, which no 5-field expression can fire at", t.Format(time.RFC3339Nano))
+		}
+		minutes = append(minutes, t.Minute())
+		hours = append(hours, t.Hour())
+		doms = append(doms, t.Day())
+		months = append(months, int(t.Month()))
+		dows = append(dows, int(t.Weekday()))
+	}
+
+	dom, month, dow := "*", "*", fitField(dows, cronFields[4])
+	if dow == "*" || len(distinctInts(doms)) == 1 {
+		dow = "*"
+		dom = fitField(doms, cronFields[2])
+		month = fitField(months, cronFields[3])
+	}
+	return strings.Join([]string{
+		fitField(minutes, cronFields[0]),
+		fitField(hours, cronFields[1]),
+		dom, month, dow,
+	}, " "), nil
+}
+
+// fitField collapses values into the most compact field covering them
+func fitField(values []int, f cronField) string {
+	distinct := distinctInts(values)
+	items := make([]string, len(distinct))
+	for i, v := range distinct {
+		items[i] = strconv.Itoa(v)
+	}
+	return compactField(strings.Join(items, ","), f)
+}
+
+// distinctInts returns the sorted distinct values
+func distinctInts(values []int) []int {
+	seen := map[int]bool{}
+	distinct := []int{}
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			distinct = append(distinct, v)
+		}
+	}
+	sort.Ints(distinct)
+	return distinct
+}
+
+// fitHandler returns the tightest single expression that fires at every
+// datetime in the request, or 422 when no 5-field expression can
+func (s *Server) fitHandler(w http.ResponseWriter, r *http.Request) {
+	var req FitRequest
+	if !s.decodeJSON(w, r, &req) {
+		return
+	}
+	if len(req.Datetimes) == 0 || len(req.Datetimes) > maxFitDatetimes {
+		writeFieldError(w, http.StatusBadRequest, ErrInvalidRequest, "datetimes", fmt.Sprintf("datetimes must contain between 1 and %d values", maxFitDatetimes))
+		return
+	}
+
+	loc := s.config.DefaultTimezone
+	if req.Timezone != "" {
+		var err error
+		loc, err = time.LoadLocation(req.Timezone)
+		if err != nil {
+			writeFieldError(w, http.StatusBadRequest, ErrInvalidTimezone, "timezone", "Invalid timezone: "+req.Timezone)
+			return
+		}
+	}
+
+	times := make([]time.Time, 0, len(req.Datetimes))
+	distinct := map[time.Time]bool{}
+	for _, v := range req.Datetimes {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			writeFieldError(w, http.StatusBadRequest, ErrInvalidRequest, "datetimes", "Invalid datetime (expected RFC 3339): "+v)
+			return
+		}
+		t = t.In(loc)
+		times = append(times, t)
+		distinct[t.UTC()] = true
+	}
+	sort.Slice(times, func(i, j int) bool { return times[i].Before(times[j]) })
+
+	expression, err := fitExpression(times)
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, ErrUnprocessable, "No single expression fits: "+err.Error())
+		return
+	}
+	schedule, err := parseExpression(expression)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, ErrInternal, "Fitted expression is invalid: "+err.Error())
+		return
+	}
+
+	response := FitResponse{
+		Expression:  expression,
+		Description: generateDescription(expression),
+		Timezone:    loc.String(),
+		Matched:     len(distinct),
+	}
+	last := times[len(times)-1]
+	runs := 0
+	for next := schedule.Next(times[0].Add(-time.Minute)); !next.IsZero() && !next.After(last) && runs < maxFitRuns; next = schedule.Next(next) {
+		runs++
+	}
+	if extra := runs - len(distinct); extra > 0 {
+		response.Approximate = true
+		response.ExtraRuns = extra
+		response.Warning = fmt.Sprintf("The expression also fires %d other time(s) between the first and last datetime", extra)
+	}
+
+	s.writeJSON(w, r, http.StatusOK, response)
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/robfig/cron/v3"
+)
+
+// maxFrequencyRuns caps the schedule walk; an every-minute schedule over the
+// 30 day window (43200 runs) stays under it
+const maxFrequencyRuns = 50000
+
+// FrequencyResponse counts an expression's runs over the next day, week and
+// 30 days
+type FrequencyResponse struct {
+	ID         int    `json:"id,omitempty"`
+	Expression string `json:"expression"`
+	Enabled    bool   `json:"enabled"`
+	Next24h    int    `json:"next24h"`
+	Next7d     int    `json:"next7d"`
+	Next30d    int    `json:"next30d"`
+	Truncated  bool   `json:"truncated"`
+}
+
+// countFrequency walks schedule from from across 30 days. Schedules that
+// rarely fire simply produce zeros, since Next gives up with a zero time.
+func countFrequency(schedule cron.Schedule, from time.Time, response *FrequencyResponse) {
+	day, week, month := from.Add(24*time.Hour), from.AddDate(0, 0, 7), from.AddDate(0, 0, 30)
+	for next := schedule.Next(from); !next.IsZero() && next.Before(month); next = schedule.Next(next) {
+		if response.Next30d == maxFrequencyRuns {
+			response.Truncated = true
+			break
+		}
+		if next.Before(day) {
+			response.Next24h++
+		}
+		if next.Before(week) {
+			response.Next7d++
+		}
+		response.Next30d++
+	}
+}
+
+// frequencyHandler counts the runs of an expression in the request body
+func (s *Server) frequencyHandler(w http.ResponseWriter, r *http.Request) {
+	var req ConvertRequest
+	if !s.decodeJSON(w, r, &req) {
+		return
+	}
+
+	expression := cleanExpression(req.Expression)
+	schedule, err := parseExpression(expression)
+	if err != nil {
+		invalidCronExpressions.Inc()
+		writeError(w, http.StatusBadRequest, ErrInvalidCron, "Invalid cron expression: "+err.Error())
+		return
+	}
+
+	loc := s.config.DefaultTimezone
+	if req.Timezone != "" {
+		loc, err = time.LoadLocation(req.Timezone)
+		if err != nil {
+			writeFieldError(w, http.StatusBadRequest, ErrInvalidTimezone, "timezone", "Invalid timezone: "+req.Timezone)
+			return
+		}
+	}
+
+	response := FrequencyResponse{Expression: expression, Enabled: true}
+	countFrequency(schedule, time.Now().In(loc), &response)
+
+	s.writeJSON(w, r, http.StatusOK, response)
+}
+
+// expressionFrequencyHandler counts the runs of a stored expression. Paused
+// expressions don't run, so their counts are zero.
+func (s *Server) expressionFrequencyHandler(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	ctx, cancel := s.dbContext(r)
+	defer cancel()
+
+	exp, err := s.db.expressionByID(ctx, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			writeError(w, http.StatusNotFound, ErrNotFound, "Expression not found")
+		} else {
+			writeDBError(w, ctx, err)
+		}
+		return
+	}
+
+	schedule, err := parseExpression(exp.Expression)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, ErrInternal, "Stored expression is invalid: "+err.Error())
+		return
+	}
+
+	response := FrequencyResponse{ID: exp.ID, Expression: exp.Expression, Enabled: exp.Enabled}
+	if exp.Enabled {
+		countFrequency(schedule, time.Now().In(s.expressionLocation(exp)), &response)
+	}
+
+	s.writeJSON(w, r, http.StatusOK, response)
+}
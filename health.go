@@ -0,0 +1,34 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+)
+
+// version is the build version, set with -ldflags "-X main.version=..."
+var version = "dev"
+
+// healthzHandler reports that the process is up
+func (s *Server) healthzHandler(w http.ResponseWriter, r *http.Request) {
+	s.writeJSON(w, r, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// readyzHandler reports whether the server can reach its primary database.
+// The ping is bounded by READYZ_TIMEOUT so the probe answers promptly. The
+// endpoint is public, so the error is only logged.
+func (s *Server) readyzHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), s.config.ReadyzTimeout)
+	defer cancel()
+
+	if err := s.db.writer().PingContext(ctx); err != nil {
+		log.Printf("readyz: database ping failed: %v", err)
+		s.writeJSON(w, r, http.StatusServiceUnavailable, map[string]string{"status": "unavailable"})
+		return
+	}
+	s.writeJSON(w, r, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+func (s *Server) versionHandler(w http.ResponseWriter, r *http.Request) {
+	s.writeJSON(w, r, http.StatusOK, map[string]string{"version": version})
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/robfig/cron/v3"
+)
+
+const (
+	defaultDistributionWindow = 7 * 24 * time.Hour
+	maxDistributionWindow     = 90 * 24 * time.Hour
+	// maxDistributionRuns caps the walk; an every-minute schedule over the
+	// maximum window is 129600 runs
+	maxDistributionRuns = 150000
+)
+
+// HourlyDistributionResponse counts runs by hour of day, in the expression's
+// timezone, over the window starting now. Hours[0] is 00:00-00:59.
+type HourlyDistributionResponse struct {
+	ID         int       `json:"id"`
+	Expression string    `json:"expression"`
+	Timezone   string    `json:"timezone"`
+	Window     string    `json:"window"`
+	From       time.Time `json:"from"`
+	To         time.Time `json:"to"`
+	Hours      [24]int   `json:"hours"`
+	Total      int       `json:"total"`
+	Truncated  bool      `json:"truncated"`
+	Note       string    `json:"note,omitempty"`
+}
+
+// hourlyDistribution walks schedule from from until to, counting runs by
+// hour of day in from's location, and reports whether maxDistributionRuns
+// cut the walk short
+func hourlyDistribution(schedule cron.Schedule, from, to time.Time) (hours [24]int, total int, truncated bool) {
+	for next := schedule.Next(from); !next.IsZero() && !next.After(to); next = schedule.Next(next) {
+		if total == maxDistributionRuns {
+			return hours, total, true
+		}
+		hours[next.In(from.Location()).Hour()]++
+		total++
+	}
+	return hours, total, false
+}
+
+// hourlyDistributionHandler counts a stored expression's runs in each hour of
+// the day over ?window= (default 7d), showing whether a job clusters at
+// certain hours
+func (s *Server) hourlyDistributionHandler(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	window := defaultDistributionWindow
+	if v := r.URL.Query().Get("window"); v != "" {
+		var err error
+		window, err = parseWindow(v)
+		if err != nil || window <= 0 || window > maxDistributionWindow {
+			writeFieldError(w, http.StatusBadRequest, ErrInvalidRequest, "window", fmt.Sprintf("window must be a duration such as 7d or 12h, at most %dd", maxDistributionWindow/(24*time.Hour)))
+			return
+		}
+	}
+
+	ctx, cancel := s.dbContext(r)
+	defer cancel()
+
+	exp, err := s.db.expressionByID(ctx, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			writeError(w, http.StatusNotFound, ErrNotFound, "Expression not found")
+		} else {
+			writeDBError(w, ctx, err)
+		}
+		return
+	}
+
+	schedule, err := parseExpression(exp.Expression)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, ErrInternal, "Stored expression is invalid: "+err.Error())
+		return
+	}
+
+	loc := s.expressionLocation(exp)
+	from := time.Now().In(loc)
+	to := from.Add(window)
+	response := HourlyDistributionResponse{
+		ID:         exp.ID,
+		Expression: exp.Expression,
+		Timezone:   loc.String(),
+		Window:     window.String(),
+		From:       from,
+		To:         to,
+	}
+	response.Hours, response.Total, response.Truncated = hourlyDistribution(schedule, from, to)
+	if response.Total == 0 {
+		response.Note = "expression doesn't run in the window; try a longer one"
+	}
+
+	s.writeJSON(w, r, http.StatusOK, response)
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// humanizeDuration renders a duration as a short relative phrase such as
+// "in 3 hours" or "in 2 days", rounding down to the largest whole unit
+func humanizeDuration(d time.Duration) string {
+	if d < time.Minute {
+		return "in less than a minute"
+	}
+
+	units := []struct {
+		name string
+		size time.Duration
+	}{
+		{"day", 24 * time.Hour},
+		{"hour", time.Hour},
+		{"minute", time.Minute},
+	}
+	for _, unit := range units {
+		if d >= unit.size {
+			n := int(d / unit.size)
+			if n == 1 {
+				return fmt.Sprintf("in 1 %s", unit.name)
+			}
+			return fmt.Sprintf("in %d %ss", n, unit.name)
+		}
+	}
+	return "in less than a minute"
+}
+
+// relativeExecutions describes each execution time relative to now
+func relativeExecutions(times []time.Time, now time.Time) []string {
+	relative := []string{}
+	for _, t := range times {
+		relative = append(relative, humanizeDuration(t.Sub(now)))
+	}
+	return relative
+}
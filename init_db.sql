@@ -4,19 +4,72 @@ CREATE DATABASE IF NOT EXISTS cronconverter;
 -- Connect to the database
 \c cronconverter;
 
+-- Create table for expression categories
+CREATE TABLE IF NOT EXISTS categories (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL UNIQUE,
+    description TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
 -- Create table for cron expressions
 CREATE TABLE IF NOT EXISTS cron_expressions (
     id SERIAL PRIMARY KEY,
     name VARCHAR(255) NOT NULL,
     expression VARCHAR(255) NOT NULL,
     description TEXT,
+    category_id INTEGER REFERENCES categories(id) ON DELETE SET NULL,
+    enabled BOOLEAN NOT NULL DEFAULT true,
+    timezone VARCHAR(64),
+    metadata JSONB,
+    environment VARCHAR(16) NOT NULL DEFAULT 'dev',
+    last_validated_at TIMESTAMP,
+    deleted_at TIMESTAMP,
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 );
 
+-- Create table for webhooks notified when an expression fires
+CREATE TABLE IF NOT EXISTS webhooks (
+    id SERIAL PRIMARY KEY,
+    expression_id INTEGER NOT NULL REFERENCES cron_expressions(id) ON DELETE CASCADE,
+    url TEXT NOT NULL,
+    enabled BOOLEAN NOT NULL DEFAULT true,
+    consecutive_failures INTEGER NOT NULL DEFAULT 0,
+    last_delivery_at TIMESTAMP,
+    last_error TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Create table for shared conversion permalinks
+CREATE TABLE IF NOT EXISTS shared_conversions (
+    token VARCHAR(16) PRIMARY KEY,
+    request JSONB NOT NULL,
+    options JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP
+);
+
+-- Create table for the expression change history
+CREATE TABLE IF NOT EXISTS expression_audit (
+    id SERIAL PRIMARY KEY,
+    expression_id INTEGER NOT NULL,
+    action VARCHAR(16) NOT NULL,
+    actor VARCHAR(255) NOT NULL,
+    claimed_actor VARCHAR(255),
+    snapshot JSONB,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
 -- Create indexes
 CREATE INDEX IF NOT EXISTS idx_cron_expressions_name ON cron_expressions (name);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_cron_expressions_live_name ON cron_expressions (name) WHERE deleted_at IS NULL;
 CREATE INDEX IF NOT EXISTS idx_cron_expressions_created_at ON cron_expressions (created_at);
+CREATE INDEX IF NOT EXISTS idx_cron_expressions_category_id ON cron_expressions (category_id);
+CREATE INDEX IF NOT EXISTS idx_cron_expressions_environment ON cron_expressions (environment);
+CREATE INDEX IF NOT EXISTS idx_webhooks_expression_id ON webhooks (expression_id);
+CREATE INDEX IF NOT EXISTS idx_expression_audit_expression_id ON expression_audit (expression_id, created_at);
 
 -- Insert some sample presets
 INSERT INTO cron_expressions (name, expression, description) 
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// lintRule suggests a canonical rewrite of a single field. Rules are applied
+// in order, each to the output of the previous one, so new rules only need
+// adding to lintRules.
+type lintRule struct {
+	name      string
+	rationale string
+	rewrite   func(field string, f cronField) (string, bool)
+}
+
+var lintRules = []lintRule{
+	{
+		name:      "redundant-step",
+		rationale: "A step of 1 is the default and can be dropped",
+		rewrite: func(field string, f cronField) (string, bool) {
+			if base := strings.TrimSuffix(field, "/1"); base != field && !strings.Contains(base, ",") {
+				return base, true
+			}
+			return field, false
+		},
+	},
+	{
+		name:      "step-list",
+		rationale: "Evenly spaced values are clearer as a step",
+		rewrite: func(field string, f cronField) (string, bool) {
+			values, ok := plainValues(field, f)
+			if !ok || len(values) < 2 {
+				return field, false
+			}
+			step := values[1] - values[0]
+			if step < 2 || !evenlySpaced(values, step) {
+				return field, false
+			}
+			first, last := values[0], values[len(values)-1]
+			if first == f.min && last+step > f.max {
+				return fmt.Sprintf("*/%d", step), true
+			}
+			if len(values) >= 3 {
+				return fmt.Sprintf("%d-%d/%d", first, last, step), true
+			}
+			return field, false
+		},
+	},
+	{
+		name:      "consecutive-range",
+		rationale: "Consecutive values are clearer as a range",
+		rewrite: func(field string, f cronField) (string, bool) {
+			values, ok := plainValues(field, f)
+			if !ok || len(values) < 3 || !evenlySpaced(values, 1) {
+				return field, false
+			}
+			first, last := values[0], values[len(values)-1]
+			if first == f.min && last == f.max {
+				return "*", true
+			}
+			return fmt.Sprintf("%d-%d", first, last), true
+		},
+	},
+}
+
+// plainValues parses a comma list of single values into sorted, distinct
+// numbers. Lists containing ranges, steps or wildcards aren't plain.
+func plainValues(field string, f cronField) ([]int, bool) {
+	seen := map[int]bool{}
+	values := []int{}
+	for _, item := range strings.Split(field, ",") {
+		n, ok := f.value(item)
+		if !ok {
+			return nil, false
+		}
+		if !seen[n] {
+			seen[n] = true
+			values = append(values, n)
+		}
+	}
+	sort.Ints(values)
+	return values, true
+}
+
+func evenlySpaced(values []int, step int) bool {
+	for i := 1; i < len(values); i++ {
+		if values[i]-values[i-1] != step {
+			return false
+		}
+	}
+	return true
+}
+
+// LintSuggestion is one rewrite suggested for a field
+type LintSuggestion struct {
+	Rule      string `json:"rule"`
+	Field     string `json:"field"`
+	Original  string `json:"original"`
+	Suggested string `json:"suggested"`
+	Rationale string `json:"rationale"`
+}
+
+// LintResponse carries the original expression, its suggested canonical
+// form and the suggestions that produced it
+type LintResponse struct {
+	Original    string           `json:"original"`
+	Suggested   string           `json:"suggested"`
+	Changed     bool             `json:"changed"`
+	Suggestions []LintSuggestion `json:"suggestions"`
+}
+
+// lintExpression applies lintRules to each field of a 5-field expression
+func lintExpression(expression string) (string, []LintSuggestion) {
+	suggestions := []LintSuggestion{}
+	parts := strings.Fields(expression)
+	for i := range parts {
+		if i >= len(cronFields) {
+			break
+		}
+		for _, rule := range lintRules {
+			rewritten, ok := rule.rewrite(parts[i], cronFields[i])
+			if !ok || rewritten == parts[i] {
+				continue
+			}
+			suggestions = append(suggestions, LintSuggestion{
+				Rule:      rule.name,
+				Field:     cronFields[i].name,
+				Original:  parts[i],
+				Suggested: rewritten,
+				Rationale: rule.rationale,
+			})
+			parts[i] = rewritten
+		}
+	}
+	return strings.Join(parts, " "), suggestions
+}
+
+func (s *Server) lintHandler(w http.ResponseWriter, r *http.Request) {
+	var req NormalizeRequest
+	if !s.decodeJSON(w, r, &req) {
+		return
+	}
+
+	expression := cleanExpression(req.Expression)
+	if _, err := parseExpression(expression); err != nil {
+		invalidCronExpressions.Inc()
+		writeError(w, http.StatusBadRequest, ErrInvalidCron, "Invalid cron expression: "+err.Error())
+		return
+	}
+
+	suggested, suggestions := lintExpression(expression)
+
+	// Never suggest something the parser would reject
+	if _, err := parseExpression(suggested); err != nil {
+		suggested, suggestions = expression, []LintSuggestion{}
+	}
+
+	response := LintResponse{
+		Original:    req.Expression,
+		Suggested:   suggested,
+		Changed:     suggested != expression,
+		Suggestions: suggestions,
+	}
+
+	s.writeJSON(w, r, http.StatusOK, response)
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// maxLoadWindow bounds the load window so the hourly breakdown stays small
+	maxLoadWindow = 31 * 24 * time.Hour
+	// maxLoadRunsPerExpression caps the walk of any single schedule
+	maxLoadRunsPerExpression = 50000
+)
+
+// LoadBucket is the number of runs starting within one hour
+type LoadBucket struct {
+	Hour time.Time `json:"hour"`
+	Runs int       `json:"runs"`
+}
+
+// LoadResponse sums the runs of all enabled stored expressions in a window.
+// Truncated lists expressions whose walk hit maxLoadRunsPerExpression, so
+// their counts are lower bounds.
+type LoadResponse struct {
+	From        time.Time    `json:"from"`
+	To          time.Time    `json:"to"`
+	Expressions int          `json:"expressions"`
+	TotalRuns   int          `json:"totalRuns"`
+	Hourly      []LoadBucket `json:"hourly"`
+	Truncated   []int        `json:"truncated"`
+}
+
+// scheduleLoadHandler reports how many runs all enabled stored expressions
+// have between ?from= and ?to= (RFC 3339), broken down by hour, for capacity
+// planning. Schedules are walked in parallel, one worker per CPU.
+func (s *Server) scheduleLoadHandler(w http.ResponseWriter, r *http.Request) {
+	from, err := time.Parse(time.RFC3339, r.URL.Query().Get("from"))
+	if err != nil {
+		writeFieldError(w, http.StatusBadRequest, ErrInvalidRequest, "from", "from must be an RFC 3339 time")
+		return
+	}
+	to, err := time.Parse(time.RFC3339, r.URL.Query().Get("to"))
+	if err != nil {
+		writeFieldError(w, http.StatusBadRequest, ErrInvalidRequest, "to", "to must be an RFC 3339 time")
+		return
+	}
+	if !to.After(from) || to.Sub(from) > maxLoadWindow {
+		writeFieldError(w, http.StatusBadRequest, ErrInvalidRequest, "to", fmt.Sprintf("to must be after from and at most %s later", maxLoadWindow))
+		return
+	}
+
+	ctx, cancel := s.dbContext(r)
+	defer cancel()
+
+	expressions, err := s.enabledExpressions(ctx)
+	if err != nil {
+		writeDBError(w, ctx, err)
+		return
+	}
+
+	jobs := make(chan CronExpression)
+	var mu sync.Mutex
+	hourly := map[time.Time]int{}
+	response := LoadResponse{From: from, To: to, Expressions: len(expressions), Truncated: []int{}}
+
+	var wg sync.WaitGroup
+	for i := 0; i < runtime.NumCPU(); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for exp := range jobs {
+				counts, runs, truncated := s.expressionLoad(exp, from, to)
+				mu.Lock()
+				for hour, n := range counts {
+					hourly[hour] += n
+				}
+				response.TotalRuns += runs
+				if truncated {
+					response.Truncated = append(response.Truncated, exp.ID)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, exp := range expressions {
+		jobs <- exp
+	}
+	close(jobs)
+	wg.Wait()
+
+	response.Hourly = make([]LoadBucket, 0, len(hourly))
+	for hour, runs := range hourly {
+		response.Hourly = append(response.Hourly, LoadBucket{Hour: hour, Runs: runs})
+	}
+	sort.Slice(response.Hourly, func(i, j int) bool { return response.Hourly[i].Hour.Before(response.Hourly[j].Hour) })
+	sort.Ints(response.Truncated)
+
+	s.writeJSON(w, r, http.StatusOK, response)
+}
+
+// expressionLoad counts the runs of one stored expression between from and
+// to by UTC hour. Expressions that no longer parse are skipped.
+func (s *Server) expressionLoad(exp CronExpression, from, to time.Time) (map[time.Time]int, int, bool) {
+	counts := map[time.Time]int{}
+	schedule, err := parseExpression(exp.Expression)
+	if err != nil {
+		return counts, 0, false
+	}
+
+	runs := 0
+	loc := s.expressionLocation(exp)
+	for next := schedule.Next(from.In(loc).Add(-time.Second)); !next.IsZero() && next.Before(to); next = schedule.Next(next) {
+		if runs == maxLoadRunsPerExpression {
+			return counts, runs, true
+		}
+		counts[next.UTC().Truncate(time.Hour)]++
+		runs++
+	}
+	return counts, runs, false
+}
+
+// enabledExpressions loads every live, enabled stored expression
+func (s *Server) enabledExpressions(ctx context.Context) ([]CronExpression, error) {
+	rows, err := s.db.queryRead(ctx, `
+		SELECT `+expressionColumns+`
+		FROM cron_expressions e
+		WHERE e.deleted_at IS NULL AND e.enabled
+		ORDER BY e.id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	expressions := []CronExpression{}
+	for rows.Next() {
+		var exp CronExpression
+		if err := scanExpression(rows, &exp); err != nil {
+			return nil, err
+		}
+		expressions = append(expressions, exp)
+	}
+	return expressions, rows.Err()
+}
@@ -0,0 +1,41 @@
+package main
+
+import "net/http"
+
+// getExpressionsByValueHandler returns every stored row whose expression
+// string exactly matches ?value=, so automation can check for an existing
+// schedule without listing everything
+func (s *Server) getExpressionsByValueHandler(w http.ResponseWriter, r *http.Request) {
+	value := r.URL.Query().Get("value")
+	if value == "" {
+		writeFieldError(w, http.StatusBadRequest, ErrInvalidRequest, "value", "value query parameter is required")
+		return
+	}
+
+	ctx, cancel := s.dbContext(r)
+	defer cancel()
+
+	rows, err := s.db.queryRead(ctx, `
+		SELECT `+expressionColumns+`
+		FROM cron_expressions e
+		WHERE e.expression = $1 AND e.deleted_at IS NULL
+		ORDER BY e.id
+	`, value)
+	if err != nil {
+		writeDBError(w, ctx, err)
+		return
+	}
+	defer rows.Close()
+
+	expressions := []CronExpression{}
+	for rows.Next() {
+		var exp CronExpression
+		if err := scanExpression(rows, &exp); err != nil {
+			writeDBError(w, ctx, err)
+			return
+		}
+		expressions = append(expressions, exp)
+	}
+
+	s.writeJSON(w, r, http.StatusOK, expressions)
+}
@@ -1,13 +1,17 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
+	"net/url"
 	"os"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -15,8 +19,7 @@ import (
 	"github.com/joho/godotenv"
 	_ "github.com/lib/pq"
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/prometheus/client_golang/prometheus/promauto"
-	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/client_golang/prometheus/collectors"
 	"github.com/robfig/cron/v3"
 )
 
@@ -26,63 +29,68 @@ type CronExpression struct {
 	Name        string    `json:"name"`
 	Expression  string    `json:"expression"`
 	Description string    `json:"description"`
+	CategoryID  *int      `json:"category_id"`
+	Category    *Category `json:"category,omitempty"`
+	Enabled     bool      `json:"enabled"`
+	Timezone    string    `json:"timezone"`
+	// Metadata is free-form client data such as team or runbook URL; it
+	// must be a JSON object
+	Metadata json.RawMessage `json:"metadata"`
+	// Environment is one of environments; empty on input means dev
+	Environment string    `json:"environment"`
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
+	// LastValidatedAt is when the expression last parsed successfully, on
+	// save or in a validation sweep; nil if it never has
+	LastValidatedAt *time.Time `json:"last_validated_at"`
 }
 
 // ConvertRequest is the request body for converting a cron expression
 type ConvertRequest struct {
 	Expression string `json:"expression"`
+	Standard   string `json:"standard"`
+	Timezone   string `json:"timezone"`
+	// From is an RFC 3339 instant to compute next executions after; it
+	// defaults to now
+	From string `json:"from"`
 }
 
 // ConvertResponse is the response for a converted cron expression
 type ConvertResponse struct {
-	Description    string   `json:"description"`
-	NextExecutions []string `json:"nextExecutions"`
-}
-
-var db *sql.DB
-
-// Prometheus metrics
-var (
-	httpRequestsTotal = promauto.NewCounterVec(
-		prometheus.CounterOpts{
-			Name: "http_requests_total",
-			Help: "Total number of HTTP requests by endpoint and status",
-		},
-		[]string{"endpoint", "status"},
-	)
-
-	httpRequestDuration = promauto.NewHistogramVec(
-		prometheus.HistogramOpts{
-			Name:    "http_request_duration_seconds",
-			Help:    "Duration of HTTP requests in seconds",
-			Buckets: prometheus.DefBuckets,
-		},
-		[]string{"endpoint"},
-	)
-
-	cronExpressionsTotal = promauto.NewCounter(
-		prometheus.CounterOpts{
-			Name: "cron_expressions_total",
-			Help: "Total number of cron expressions stored",
-		},
-	)
-
-	dbConnectionErrors = promauto.NewCounter(
-		prometheus.CounterOpts{
-			Name: "db_connection_errors_total",
-			Help: "Total number of database connection errors",
-		},
-	)
-
-	invalidCronExpressions = promauto.NewCounter(
-		prometheus.CounterOpts{
-			Name: "invalid_cron_expressions_total",
-			Help: "Total number of invalid cron expressions submitted",
-		},
-	)
-)
+	Expression         string   `json:"expression"`
+	RawExpression      string   `json:"rawExpression"`
+	StandardExpression string   `json:"standardExpression,omitempty"`
+	Descriptor         string   `json:"descriptor,omitempty"`
+	Description        string   `json:"description"`
+	NextExecutions     []string `json:"nextExecutions"`
+	Tags               []string `json:"tags,omitempty"`
+	RelativeExecutions []string `json:"relativeExecutions,omitempty"`
+	Timezone           string   `json:"timezone,omitempty"`
+	DSTWarning         *bool    `json:"dstWarning,omitempty"`
+	DSTMessage         string   `json:"dstMessage,omitempty"`
+	// ExecutionsByDate maps each calendar date to its run times, for agenda
+	// views; see groupExecutionsByDate
+	ExecutionsByDate map[string][]string `json:"executionsByDate,omitempty"`
+	// DayTriggers is set when both day fields are restricted, showing which
+	// one fired each run under cron's OR rule
+	DayTriggers []RunTrigger `json:"dayTriggers,omitempty"`
+}
+
+// standardParser parses standard 5-field Unix cron expressions
+var standardParser = cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow)
+
+// parseExpression validates a standard cron expression or descriptor such as
+// @daily and returns its schedule
+func parseExpression(expression string) (cron.Schedule, error) {
+	if _, equivalent, ok := resolveDescriptor(expression); ok {
+		expression = equivalent
+	}
+	expression = expandWrapRanges(expression)
+	if schedule, ok, err := parseWeekdayExpression(expression); ok {
+		return schedule, err
+	}
+	return standardParser.Parse(expression)
+}
 
 func main() {
 
@@ -100,39 +108,38 @@ func main() {
 		log.Println("Warning: Error loading .env file")
 	}
 
-	// Connect to database
-	initDB()
-
-	// Create router
-	r := mux.NewRouter()
+	config := loadConfig()
 
-	// Define routes with metrics middleware
-	r.HandleFunc("/api/convert", metricMiddleware("/api/convert", convertCronHandler)).Methods("POST")
-	r.HandleFunc("/api/expressions", metricMiddleware("/api/expressions", getExpressionsHandler)).Methods("GET")
-	r.HandleFunc("/api/expressions", metricMiddleware("/api/expressions", createExpressionHandler)).Methods("POST")
-	r.HandleFunc("/api/expressions/{id}", metricMiddleware("/api/expressions/{id}", getExpressionHandler)).Methods("GET")
-	r.HandleFunc("/api/expressions/{id}", metricMiddleware("/api/expressions/{id}", updateExpressionHandler)).Methods("PUT")
-	r.HandleFunc("/api/expressions/{id}", metricMiddleware("/api/expressions/{id}", deleteExpressionHandler)).Methods("DELETE")
+	// Connect to database
+	server := NewServer(initDB(), config)
 
-	// Add Prometheus metrics endpoint
-	r.Handle("/metrics", promhttp.Handler())
+	// Report stored expressions that no longer validate
+	if report, err := server.validateStoredExpressions(context.Background()); err != nil {
+		log.Printf("Warning: could not validate stored expressions: %v", err)
+	} else {
+		log.Printf("Validated %d stored expressions, %d invalid", report.Checked, len(report.Invalid))
+	}
 
-	// Serve static files
-	r.PathPrefix("/").Handler(http.FileServer(http.Dir("./static")))
+	// Permanently remove soft-deleted expressions past their retention
+	server.startPurgeJob()
 
-	// Start server
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
+	// Deliver webhooks when their expressions fire
+	if config.EnableWebhooks {
+		server.startWebhookScheduler()
+		log.Printf("Webhook scheduler started")
 	}
 
-	log.Printf("Server starting on port %s", port)
+	log.Printf("Server starting on port %s", config.Port)
 	log.Printf("Prometheus metrics available at /metrics")
-	log.Fatal(http.ListenAndServe(":"+port, r))
+	log.Fatal(http.ListenAndServe(":"+config.Port, server.handler()))
 }
 
 // Middleware to record metrics for each request
-func metricMiddleware(endpoint string, next http.HandlerFunc) http.HandlerFunc {
+func (s *Server) metricMiddleware(endpoint string, next http.HandlerFunc) http.HandlerFunc {
+	if exemptRoutes[endpoint].unmetered {
+		return next
+	}
+
 	return func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
 
@@ -143,9 +150,15 @@ func metricMiddleware(endpoint string, next http.HandlerFunc) http.HandlerFunc {
 		next(crw, r)
 
 		// Record metrics after request is processed
-		duration := time.Since(start).Seconds()
-		httpRequestDuration.WithLabelValues(endpoint).Observe(duration)
+		elapsed := time.Since(start)
+		httpRequestDuration.WithLabelValues(endpoint).Observe(elapsed.Seconds())
 		httpRequestsTotal.WithLabelValues(endpoint, fmt.Sprintf("%d", crw.statusCode)).Inc()
+
+		// Flag slow requests, ignoring long-lived event streams
+		if elapsed > s.config.SlowRequestThreshold && crw.Header().Get("Content-Type") != "text/event-stream" {
+			slowRequestsTotal.WithLabelValues(endpoint).Inc()
+			log.Printf("WARN slow request: endpoint=%s method=%s client=%s duration=%s", endpoint, r.Method, s.clientIP(r), elapsed)
+		}
 	}
 }
 
@@ -164,16 +177,26 @@ func (crw *customResponseWriter) WriteHeader(code int) {
 	crw.ResponseWriter.WriteHeader(code)
 }
 
-func initDB() {
-	var err error
+// Flush lets streaming handlers flush through the metrics wrapper
+func (crw *customResponseWriter) Flush() {
+	if f, ok := crw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func initDB() *store {
 
 	// Get database connection details from environment variables
 	host := os.Getenv("DB_HOST")
 	port := os.Getenv("DB_PORT")
 	user := os.Getenv("DB_USER")
-	password := os.Getenv("DB_PASSWORD")
 	dbname := os.Getenv("DB_NAME")
 
+	password, err := dbPassword()
+	if err != nil {
+		log.Fatal(err)
+	}
+
 	// Set defaults if not provided
 	if host == "" {
 		host = "localhost"
@@ -188,87 +211,369 @@ func initDB() {
 		dbname = "cronconverter"
 	}
 
-	// Construct the connection string
-	dbURL := fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=disable",
-		user, password, host, port, dbname)
+	// Construct the connection string, escaping any special characters
+	dbURL := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(user, password),
+		Host:     host + ":" + port,
+		Path:     dbname,
+		RawQuery: "sslmode=disable",
+	}
+
+	// Only ever log the DSN with the password masked
+	log.Printf("Connecting to database %s", dbURL.Redacted())
 
-	db, err = sql.Open("postgres", dbURL)
+	primary, err := sql.Open("postgres", dbURL.String())
 	if err != nil {
 		dbConnectionErrors.Inc()
 		log.Fatal(err)
 	}
 
-	err = db.Ping()
+	err = primary.Ping()
 	if err != nil {
 		dbConnectionErrors.Inc()
 		log.Fatal(err)
 	}
 
-	// Create table if not exists
-	_, err = db.Exec(`
-        CREATE TABLE IF NOT EXISTS cron_expressions (
-            id SERIAL PRIMARY KEY,
-            name VARCHAR(255) NOT NULL,
-            expression VARCHAR(255) NOT NULL,
-            description TEXT,
-            created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-            updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-        )
-    `)
-	if err != nil {
-		log.Fatal(err)
+	replica := openReplica()
+	db := newStore(primary, replica)
+
+	// Expose connection pool metrics for each pool
+	prometheus.MustRegister(collectors.NewDBStatsCollector(primary, "primary"))
+	if replica != nil {
+		prometheus.MustRegister(collectors.NewDBStatsCollector(replica, "replica"))
 	}
 
+	// Create or upgrade tables
+	RunMigrations(db.writer())
+
 	// Count existing expressions for initial metric
-	var count int
-	err = db.QueryRow("SELECT COUNT(*) FROM cron_expressions").Scan(&count)
-	if err == nil && count > 0 {
+	count, err := db.countExpressions(context.Background())
+	if err == nil {
 		cronExpressionsTotal.Add(float64(count))
+		cronExpressionsStored.Set(float64(count))
 	}
 
 	log.Println("Database connected successfully")
+	return db
 }
 
-func convertCronHandler(w http.ResponseWriter, r *http.Request) {
-	var req ConvertRequest
-	err := json.NewDecoder(r.Body).Decode(&req)
+// dbPassword returns the database password, preferring the contents of the
+// file named by DB_PASSWORD_FILE (Docker/Kubernetes secret mounts) over the
+// DB_PASSWORD environment variable
+func dbPassword() (string, error) {
+	if path := os.Getenv("DB_PASSWORD_FILE"); path != "" {
+		secret, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("reading DB_PASSWORD_FILE: %w", err)
+		}
+		return strings.TrimRight(string(secret), "\r\n"), nil
+	}
+	return os.Getenv("DB_PASSWORD"), nil
+}
+
+// openReplica connects to the read replica in DB_REPLICA_URL, if configured.
+// It returns nil when no replica is configured or it can't be reached, in
+// which case reads fall back to the primary.
+func openReplica() *sql.DB {
+	replicaURL := os.Getenv("DB_REPLICA_URL")
+	if replicaURL == "" {
+		return nil
+	}
+
+	replica, err := sql.Open("postgres", replicaURL)
+	if err == nil {
+		err = replica.Ping()
+	}
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		dbConnectionErrors.Inc()
+		log.Printf("Warning: read replica unavailable, reading from primary: %v", err)
+		return nil
+	}
+
+	log.Println("Read replica connected successfully")
+	return replica
+}
+
+func (s *Server) convertCronHandler(w http.ResponseWriter, r *http.Request) {
+	var req ConvertRequest
+	if !s.decodeJSON(w, r, &req) {
 		return
 	}
 
+	response, ok := s.convert(w, r, req)
+	if !ok {
+		return
+	}
+	s.recent.add(response.Expression, response.Description, time.Now())
+
+	s.writeNegotiated(w, r, http.StatusOK, response)
+}
+
+// convert validates, describes and schedules req, reading presentation
+// options such as ?relative= from r. On failure it writes the error response
+// and returns false.
+func (s *Server) convert(w http.ResponseWriter, r *http.Request, req ConvertRequest) (ConvertResponse, bool) {
+	// Strip quotes and comments pasted along with the expression
+	expression := cleanExpression(req.Expression)
+
+	// Quartz input is part of the Quartz converter and can be disabled
+	if strings.EqualFold(req.Standard, StandardQuartz) && !s.converterEnabled(ConverterQuartz) {
+		writeError(w, http.StatusNotImplemented, ErrNotImplemented, "Quartz conversion is disabled")
+		return ConvertResponse{}, false
+	}
+
+	// Map other standards, such as Quartz, down to a 5-field expression
+	standardExpression, err := toStandardExpression(req.Standard, expression)
+	if err != nil {
+		invalidCronExpressions.Inc()
+		writeError(w, http.StatusBadRequest, ErrInvalidCron, "Invalid cron expression: "+err.Error())
+		return ConvertResponse{}, false
+	}
+
+	// Point at the offending field when the input's fields map one to one
+	unixYear := strings.EqualFold(req.Standard, StandardUnixYear)
+	if !strings.EqualFold(req.Standard, StandardQuartz) {
+		if fieldErr := locateFieldError(standardExpression); fieldErr != nil {
+			writeInvalidExpression(w, fieldErr)
+			return ConvertResponse{}, false
+		}
+	}
+
 	// Validate cron expression
-	parser := cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow)
-	_, err = parser.Parse(req.Expression)
+	schedule, err := parseExpression(standardExpression)
+	if err == nil && unixYear {
+		// Only run in the years listed in the sixth field
+		schedule, err = withYears(schedule, strings.Fields(expression)[5])
+	}
 	if err != nil {
 		invalidCronExpressions.Inc()
-		http.Error(w, "Invalid cron expression: "+err.Error(), http.StatusBadRequest)
-		return
+		writeError(w, http.StatusBadRequest, ErrInvalidCron, "Invalid cron expression: "+err.Error())
+		return ConvertResponse{}, false
+	}
+
+	weekStart, err := parseWeekStart(r.URL.Query().Get("weekStart"))
+	if err != nil {
+		writeFieldError(w, http.StatusBadRequest, ErrInvalidRequest, "weekStart", err.Error())
+		return ConvertResponse{}, false
 	}
 
 	// Generate human readable description
-	description := generateDescription(req.Expression)
+	var description string
+	if unixYear {
+		start := time.Now()
+		description = describeWithYear(expression, weekStart)
+		descriptionDuration.Observe(time.Since(start).Seconds())
+	} else {
+		description = timedDescription(standardExpression, weekStart)
+	}
+
+	// Calculate next execution times, using a single "now" so relative times
+	// line up with the absolute ones. A pinned "from" replaces the current
+	// time, e.g. to see what fires after next Monday.
+	now := time.Now()
+	if req.From != "" {
+		now, err = time.Parse(time.RFC3339, req.From)
+		if err != nil {
+			writeFieldError(w, http.StatusBadRequest, ErrInvalidRequest, "from", "from must be an RFC 3339 time: "+req.From)
+			return ConvertResponse{}, false
+		}
+		// Without a timezone, evaluate in the server's zone as for now
+		now = now.In(time.Local)
+	}
+
+	// Evaluate the schedule in the requested timezone, else the configured
+	// one, so runs and their date groups agree
+	var loc *time.Location
+	now = now.In(s.config.DefaultTimezone)
+	if req.Timezone != "" {
+		loc, err = time.LoadLocation(req.Timezone)
+		if err != nil {
+			writeFieldError(w, http.StatusBadRequest, ErrInvalidTimezone, "timezone", "Invalid timezone: "+req.Timezone)
+			return ConvertResponse{}, false
+		}
+		now = now.In(loc)
+	}
 
-	// Calculate next execution times
-	nextExecutions := calculateNextExecutions(req.Expression, 5)
+	nextTimes := nextExecutionTimes(schedule, now, 5)
 
 	response := ConvertResponse{
+		Expression:     expression,
+		RawExpression:  req.Expression,
 		Description:    description,
-		NextExecutions: nextExecutions,
+		NextExecutions: formatExecutions(nextTimes),
+	}
+	response.DayTriggers = dayTriggers(schedule, nextTimes)
+
+	if canonical, equivalent, ok := resolveDescriptor(standardExpression); ok {
+		// Report the canonical descriptor, e.g. @daily for @midnight
+		response.Descriptor = canonical
+		response.StandardExpression = equivalent
+	} else if standardExpression != expression {
+		response.StandardExpression = standardExpression
+	}
+
+	if loc != nil {
+		// Flag runs that a clock change in this zone skips or repeats
+		warning, message := dstWarning(schedule, loc, now)
+		response.Timezone = loc.String()
+		response.DSTWarning = &warning
+		response.DSTMessage = message
+	}
+
+	if r.URL.Query().Get("relative") == "true" {
+		response.RelativeExecutions = relativeExecutions(nextTimes, now)
+	}
+
+	if r.URL.Query().Get("withIcons") == "true" {
+		response.Tags = scheduleTags(schedule)
+	}
+
+	if r.URL.Query().Get("groupByDate") == "true" {
+		// Date boundaries follow the zone the runs were evaluated in
+		response.ExecutionsByDate = groupExecutionsByDate(nextTimes, now.Location())
+	}
+
+	return response, true
+}
+
+// expressionFilters builds the WHERE clause and arguments for the list
+// filters in the query string, shared by the list and count queries so both
+// always apply the same filters
+func expressionFilters(r *http.Request) (string, []interface{}, error) {
+	conditions := []string{"e.deleted_at IS NULL"}
+	args := []interface{}{}
+	if category := r.URL.Query().Get("category"); category != "" {
+		if _, err := strconv.Atoi(category); err != nil {
+			return "", nil, fmt.Errorf("Invalid category id")
+		}
+		args = append(args, category)
+		conditions = append(conditions, fmt.Sprintf("e.category_id = $%d", len(args)))
+	}
+	if environment := r.URL.Query().Get("environment"); environment != "" {
+		if !validEnvironment(environment) {
+			return "", nil, fmt.Errorf("Invalid environment")
+		}
+		args = append(args, environment)
+		conditions = append(conditions, fmt.Sprintf("e.environment = $%d", len(args)))
+	}
+
+	// ?meta.team=payments matches a top-level metadata key, sorted so the
+	// query text is stable
+	keys := []string{}
+	for key := range r.URL.Query() {
+		if strings.HasPrefix(key, "meta.") && len(key) > len("meta.") {
+			keys = append(keys, key)
+		}
 	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		args = append(args, strings.TrimPrefix(key, "meta."), r.URL.Query().Get(key))
+		conditions = append(conditions, fmt.Sprintf("e.metadata ->> $%d = $%d", len(args)-1, len(args)))
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), args, nil
+}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+// sortColumns are the columns the expression list can be sorted by
+var sortColumns = map[string]string{
+	"created_at":        "e.created_at",
+	"updated_at":        "e.updated_at",
+	"name":              "e.name",
+	"last_validated_at": "e.last_validated_at",
+}
+
+// expressionOrder builds the ORDER BY clause for ?sort=, a column from
+// sortColumns prefixed with "-" for descending order. Never-validated rows
+// sort as the oldest, so ?sort=last_validated_at lists them first.
+func expressionOrder(sort string) (string, error) {
+	if sort == "" {
+		sort = "-created_at"
+	}
+	column, ok := sortColumns[strings.TrimPrefix(sort, "-")]
+	if !ok {
+		return "", fmt.Errorf("Invalid sort %q", sort)
+	}
+	if strings.HasPrefix(sort, "-") {
+		return " ORDER BY " + column + " DESC NULLS LAST", nil
+	}
+	return " ORDER BY " + column + " ASC NULLS FIRST", nil
 }
 
-func getExpressionsHandler(w http.ResponseWriter, r *http.Request) {
-	rows, err := db.Query(`
-		SELECT id, name, expression, description, created_at, updated_at 
-		FROM cron_expressions 
-		ORDER BY created_at DESC
-	`)
+// getExpressionsHandler lists stored expressions, newest first unless ?sort=
+// says otherwise, paginated with ?limit= and ?offset=. ?field=hour&value=9 keeps expressions whose
+// field includes the value; that can't be expressed in SQL, so it scans
+// every row matching the other filters and paginates afterwards. Use it with
+// a limit on large datasets.
+func (s *Server) getExpressionsHandler(w http.ResponseWriter, r *http.Request) {
+	where, args, err := expressionFilters(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, ErrInvalidRequest, err.Error())
+		return
+	}
+	field, err := parseFieldFilter(r.URL.Query())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, ErrInvalidRequest, err.Error())
+		return
+	}
+	limit, offset, err := parsePage(r.URL.Query())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, ErrInvalidRequest, err.Error())
+		return
+	}
+	order, err := expressionOrder(r.URL.Query().Get("sort"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, ErrInvalidRequest, err.Error())
+		return
+	}
+	countOnly := r.URL.Query().Get("countOnly") == "true"
+
+	ctx, cancel := s.dbContext(r)
+	defer cancel()
+
+	// ?countOnly=true returns just the number of matching rows
+	if countOnly && field == nil {
+		var total int
+		err := s.db.retryRead(ctx, func(db *sql.DB) error {
+			return db.QueryRowContext(ctx, "SELECT COUNT(*) FROM cron_expressions e"+where, args...).Scan(&total)
+		})
+		if err != nil {
+			writeDBError(w, ctx, err)
+			return
+		}
+		s.writeNegotiated(w, r, http.StatusOK, map[string]int{"total": total})
+		return
+	}
+
+	cacheKey := listCacheKey(r.URL.Query())
+	if expressions, ok := s.lists.get(cacheKey); ok {
+		s.writeNegotiated(w, r, http.StatusOK, expressions)
+		return
+	}
+
+	// ?expand=category joins in the full category for each expression
+	expand := r.URL.Query().Get("expand") == "category"
+
+	query := "SELECT " + expressionColumns
+	if expand {
+		query += ", c.id, c.name, c.description"
+	}
+	query += " FROM cron_expressions e"
+	if expand {
+		query += " LEFT JOIN categories c ON c.id = e.category_id"
+	}
+	query += where + order
+	if field == nil && limit > 0 {
+		args = append(args, limit)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+	if field == nil && offset > 0 {
+		args = append(args, offset)
+		query += fmt.Sprintf(" OFFSET $%d", len(args))
+	}
+
+	rows, err := s.db.queryRead(ctx, query, args...)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeDBError(w, ctx, err)
 		return
 	}
 	defer rows.Close()
@@ -276,165 +581,280 @@ func getExpressionsHandler(w http.ResponseWriter, r *http.Request) {
 	expressions := []CronExpression{}
 	for rows.Next() {
 		var exp CronExpression
-		err := rows.Scan(&exp.ID, &exp.Name, &exp.Expression, &exp.Description, &exp.CreatedAt, &exp.UpdatedAt)
+		var categoryID sql.NullInt64
+		var categoryName, categoryDescription sql.NullString
+		extra := []interface{}{}
+		if expand {
+			extra = append(extra, &categoryID, &categoryName, &categoryDescription)
+		}
+		err := scanExpression(rows, &exp, extra...)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			writeDBError(w, ctx, err)
 			return
 		}
+		if field != nil && !field.matches(exp.Expression) {
+			continue
+		}
+		if categoryID.Valid {
+			exp.Category = &Category{
+				ID:          int(categoryID.Int64),
+				Name:        categoryName.String,
+				Description: categoryDescription.String,
+			}
+		}
 		expressions = append(expressions, exp)
 	}
+	if field != nil {
+		if countOnly {
+			s.writeNegotiated(w, r, http.StatusOK, map[string]int{"total": len(expressions)})
+			return
+		}
+		expressions = expressions[min(offset, len(expressions)):]
+		if limit > 0 && len(expressions) > limit {
+			expressions = expressions[:limit]
+		}
+	}
+	s.lists.set(cacheKey, expressions)
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(expressions)
+	s.writeNegotiated(w, r, http.StatusOK, expressions)
 }
 
-func createExpressionHandler(w http.ResponseWriter, r *http.Request) {
+func (s *Server) createExpressionHandler(w http.ResponseWriter, r *http.Request) {
 	var exp CronExpression
-	err := json.NewDecoder(r.Body).Decode(&exp)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	if !s.decodeJSON(w, r, &exp) {
 		return
 	}
 
 	// Validate expression
-	parser := cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow)
-	_, err = parser.Parse(exp.Expression)
+	schedule, err := parseExpression(exp.Expression)
 	if err != nil {
 		invalidCronExpressions.Inc()
-		http.Error(w, "Invalid cron expression: "+err.Error(), http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, ErrInvalidCron, "Invalid cron expression: "+err.Error())
 		return
 	}
 
+	if !validTimezone(exp.Timezone) {
+		writeFieldError(w, http.StatusBadRequest, ErrInvalidTimezone, "timezone", "Invalid timezone: "+exp.Timezone)
+		return
+	}
+
+	if !validMetadata(exp.Metadata) {
+		writeFieldError(w, http.StatusBadRequest, ErrInvalidRequest, "metadata", "metadata must be a JSON object")
+		return
+	}
+
+	if exp.Environment == "" {
+		exp.Environment = defaultEnvironment
+	}
+	if !validEnvironment(exp.Environment) {
+		writeFieldError(w, http.StatusBadRequest, ErrInvalidRequest, "environment", "Invalid environment: "+exp.Environment)
+		return
+	}
+
+	if err := s.checkMinInterval(schedule, s.expressionLocation(exp)); err != nil {
+		writeError(w, http.StatusUnprocessableEntity, ErrTooFrequent, "Expression runs too often: "+err.Error())
+		return
+	}
+
+	ctx, cancel := s.dbContext(r)
+	defer cancel()
+
 	// Insert into database
-	now := time.Now()
-	err = db.QueryRow(`
-		INSERT INTO cron_expressions (name, expression, description, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5)
-		RETURNING id, created_at, updated_at
-	`, exp.Name, exp.Expression, exp.Description, now, now).Scan(&exp.ID, &exp.CreatedAt, &exp.UpdatedAt)
+	err = insertExpression(ctx, s.db.writer(), &exp)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		if isUniqueViolation(err) {
+			writeError(w, http.StatusConflict, ErrDuplicateName, "Expression already exists")
+		} else if isForeignKeyViolation(err) {
+			writeFieldError(w, http.StatusBadRequest, ErrInvalidRequest, "category_id", "Category not found")
+		} else {
+			writeDBError(w, ctx, err)
+		}
 		return
 	}
 
 	// Increment the counter for expressions
 	cronExpressionsTotal.Inc()
+	cronExpressionsStored.Inc()
+	s.lists.invalidate()
+	s.recordAudit(ctx, r, exp.ID, AuditCreate, &exp)
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(exp)
+	s.writeJSON(w, r, http.StatusCreated, exp)
 }
 
-func getExpressionHandler(w http.ResponseWriter, r *http.Request) {
+func (s *Server) getExpressionHandler(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id := vars["id"]
 
-	var exp CronExpression
-	err := db.QueryRow(`
-		SELECT id, name, expression, description, created_at, updated_at 
-		FROM cron_expressions 
-		WHERE id = $1
-	`, id).Scan(&exp.ID, &exp.Name, &exp.Expression, &exp.Description, &exp.CreatedAt, &exp.UpdatedAt)
+	ctx, cancel := s.dbContext(r)
+	defer cancel()
 
+	exp, err := s.db.expressionByID(ctx, id)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			http.Error(w, "Expression not found", http.StatusNotFound)
+			writeError(w, http.StatusNotFound, ErrNotFound, "Expression not found")
 		} else {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			writeDBError(w, ctx, err)
 		}
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(exp)
+	s.writeJSON(w, r, http.StatusOK, exp)
 }
 
-func updateExpressionHandler(w http.ResponseWriter, r *http.Request) {
+func (s *Server) updateExpressionHandler(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id := vars["id"]
 
 	var exp CronExpression
-	err := json.NewDecoder(r.Body).Decode(&exp)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	if !s.decodeJSON(w, r, &exp) {
 		return
 	}
 
 	// Validate expression
-	parser := cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow)
-	_, err = parser.Parse(exp.Expression)
+	schedule, err := parseExpression(exp.Expression)
 	if err != nil {
 		invalidCronExpressions.Inc()
-		http.Error(w, "Invalid cron expression: "+err.Error(), http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, ErrInvalidCron, "Invalid cron expression: "+err.Error())
+		return
+	}
+
+	if !validTimezone(exp.Timezone) {
+		writeFieldError(w, http.StatusBadRequest, ErrInvalidTimezone, "timezone", "Invalid timezone: "+exp.Timezone)
+		return
+	}
+
+	if !validMetadata(exp.Metadata) {
+		writeFieldError(w, http.StatusBadRequest, ErrInvalidRequest, "metadata", "metadata must be a JSON object")
+		return
+	}
+
+	if exp.Environment == "" {
+		exp.Environment = defaultEnvironment
+	}
+	if !validEnvironment(exp.Environment) {
+		writeFieldError(w, http.StatusBadRequest, ErrInvalidRequest, "environment", "Invalid environment: "+exp.Environment)
 		return
 	}
 
+	if err := s.checkMinInterval(schedule, s.expressionLocation(exp)); err != nil {
+		writeError(w, http.StatusUnprocessableEntity, ErrTooFrequent, "Expression runs too often: "+err.Error())
+		return
+	}
+
+	ctx, cancel := s.dbContext(r)
+	defer cancel()
+
 	// Update in database
 	now := time.Now()
-	result, err := db.Exec(`
+	result, err := s.db.writer().ExecContext(ctx, `
 		UPDATE cron_expressions 
-		SET name = $1, expression = $2, description = $3, updated_at = $4
-		WHERE id = $5
-	`, exp.Name, exp.Expression, exp.Description, now, id)
+		SET name = $1, expression = $2, description = $3, category_id = $4, timezone = $5, metadata = $6, environment = $7, updated_at = $8, last_validated_at = $8
+		WHERE id = $9 AND deleted_at IS NULL
+	`, exp.Name, exp.Expression, exp.Description, exp.CategoryID, nullIfEmpty(exp.Timezone), metadataValue(exp.Metadata), exp.Environment, now, id)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		if isUniqueViolation(err) {
+			writeError(w, http.StatusConflict, ErrDuplicateName, "Expression already exists")
+		} else if isForeignKeyViolation(err) {
+			writeFieldError(w, http.StatusBadRequest, ErrInvalidRequest, "category_id", "Category not found")
+		} else {
+			writeDBError(w, ctx, err)
+		}
 		return
 	}
 
 	rowsAffected, err := result.RowsAffected()
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, ErrDBError, err.Error())
 		return
 	}
 
 	if rowsAffected == 0 {
-		http.Error(w, "Expression not found", http.StatusNotFound)
+		writeError(w, http.StatusNotFound, ErrNotFound, "Expression not found")
 		return
 	}
-
-	// Get updated record
-	err = db.QueryRow(`
-		SELECT id, name, expression, description, created_at, updated_at 
-		FROM cron_expressions 
-		WHERE id = $1
-	`, id).Scan(&exp.ID, &exp.Name, &exp.Expression, &exp.Description, &exp.CreatedAt, &exp.UpdatedAt)
+	s.lists.invalidate()
+
+	// Get updated record from the primary to avoid replication lag
+	err = scanExpression(s.db.writer().QueryRowContext(ctx, `
+		SELECT `+expressionColumns+`
+		FROM cron_expressions e
+		WHERE e.id = $1 AND e.deleted_at IS NULL
+	`, id), &exp)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeDBError(w, ctx, err)
 		return
 	}
+	s.recordAudit(ctx, r, exp.ID, AuditUpdate, &exp)
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(exp)
+	s.writeJSON(w, r, http.StatusOK, exp)
 }
 
-func deleteExpressionHandler(w http.ResponseWriter, r *http.Request) {
+func (s *Server) deleteExpressionHandler(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id := vars["id"]
 
-	result, err := db.Exec("DELETE FROM cron_expressions WHERE id = $1", id)
+	ctx, cancel := s.dbContext(r)
+	defer cancel()
+
+	// Soft delete; rows are purged after the retention period by the purge job
+	result, err := s.db.writer().ExecContext(ctx, `
+		UPDATE cron_expressions
+		SET deleted_at = $1
+		WHERE id = $2 AND deleted_at IS NULL
+	`, time.Now(), id)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeDBError(w, ctx, err)
 		return
 	}
 
 	rowsAffected, err := result.RowsAffected()
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, ErrDBError, err.Error())
 		return
 	}
 
 	if rowsAffected == 0 {
-		http.Error(w, "Expression not found", http.StatusNotFound)
+		writeError(w, http.StatusNotFound, ErrNotFound, "Expression not found")
 		return
 	}
+	cronExpressionsStored.Dec()
+	s.lists.invalidate()
+	s.recordAudit(ctx, r, id, AuditDelete, nil)
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{"message": "Expression deleted successfully"})
+	s.writeJSON(w, r, http.StatusOK, map[string]string{"message": "Expression deleted successfully"})
 }
 
+// generateDescription describes an expression for a Sunday-first week
 func generateDescription(expression string) string {
+	return describeExpression(expression, time.Sunday)
+}
+
+// timedDescription is describeExpression observed by
+// description_generation_duration_seconds. The convert handlers use it so
+// slower description code shows up in the histogram.
+func timedDescription(expression string, weekStart time.Weekday) string {
+	start := time.Now()
+	description := describeExpression(expression, weekStart)
+	descriptionDuration.Observe(time.Since(start).Seconds())
+	return description
+}
+
+// describeExpression describes an expression, ordering day-of-week lists and
+// ranges for a week starting on weekStart
+func describeExpression(expression string, weekStart time.Weekday) string {
+	// Describe descriptors such as @daily by their 5-field equivalent
+	if _, equivalent, ok := resolveDescriptor(expression); ok {
+		expression = equivalent
+	}
+
 	parts := strings.Fields(expression)
+	if len(parts) == 6 {
+		// Six fields lead with seconds; unix-year expressions, which append a
+		// year instead, are described by describeWithYear
+		return describeWithSeconds(expression, weekStart)
+	}
 	if len(parts) != 5 {
+		descriptionFallbacks.WithLabelValues("expression").Inc()
 		return "Invalid cron expression"
 	}
 
@@ -465,14 +885,23 @@ func generateDescription(expression string) string {
 		minuteDesc = "every 30 minutes"
 	default:
 		if strings.Contains(minute, ",") {
-			minuteDesc = fmt.Sprintf("at minutes %s", minute)
-		} else if strings.Contains(minute, "-") {
-			minuteDesc = fmt.Sprintf("every minute from %s", minute)
+			minuteDesc = fmt.Sprintf("at minutes %s", joinNatural(strings.Split(minute, ",")))
 		} else if strings.Contains(minute, "/") {
+			// Parse the base and step together so "10-50/5" keeps its bounds
 			parts := strings.Split(minute, "/")
 			if len(parts) == 2 {
 				minuteDesc = fmt.Sprintf("every %s minute(s)", parts[1])
+				if bounds := strings.Split(parts[0], "-"); len(bounds) == 2 {
+					minuteDesc = fmt.Sprintf("every %s minutes from minute %s to %s", parts[1], bounds[0], bounds[1])
+				} else if parts[0] != "*" {
+					minuteDesc = fmt.Sprintf("every %s minutes starting at minute %s", parts[1], parts[0])
+				}
 			}
+		} else if start, end, ok := wrapRange(minute, cronFields[0]); ok {
+			minuteDesc = fmt.Sprintf("every minute from minute %d through minute %d of the next hour", start, end)
+		} else if strings.Contains(minute, "-") {
+			descriptionFallbacks.WithLabelValues("minute").Inc()
+			minuteDesc = fmt.Sprintf("every minute from %s", minute)
 		} else {
 			minuteDesc = fmt.Sprintf("at minute %s", minute)
 		}
@@ -491,14 +920,23 @@ func generateDescription(expression string) string {
 		hourDesc = "at noon"
 	default:
 		if strings.Contains(hour, ",") {
-			hourDesc = fmt.Sprintf("at hours %s", hour)
-		} else if strings.Contains(hour, "-") {
-			hourDesc = fmt.Sprintf("every hour from %s", hour)
+			hourDesc = fmt.Sprintf("at hours %s", joinNatural(strings.Split(hour, ",")))
 		} else if strings.Contains(hour, "/") {
+			// Parse the base and step together so "2-22/4" keeps its bounds
 			parts := strings.Split(hour, "/")
 			if len(parts) == 2 {
 				hourDesc = fmt.Sprintf("every %s hour(s)", parts[1])
+				if bounds := strings.Split(parts[0], "-"); len(bounds) == 2 {
+					hourDesc = fmt.Sprintf("every %s hours from %s to %s", parts[1], clockHour(bounds[0]), clockHour(bounds[1]))
+				} else if parts[0] != "*" {
+					hourDesc = fmt.Sprintf("every %s hours starting at %s", parts[1], clockHour(parts[0]))
+				}
 			}
+		} else if start, end, ok := wrapRange(hour, cronFields[1]); ok {
+			hourDesc = fmt.Sprintf("every hour from %02d:00 through %02d:00 (overnight)", start, end)
+		} else if strings.Contains(hour, "-") {
+			descriptionFallbacks.WithLabelValues("hour").Inc()
+			hourDesc = fmt.Sprintf("every hour from %s", hour)
 		} else {
 			hourDesc = fmt.Sprintf("at %s:00", hour)
 		}
@@ -517,10 +955,15 @@ func generateDescription(expression string) string {
 		domDesc = "on the 3rd of the month"
 	case "L":
 		domDesc = "on the last day of the month"
+	case "LW":
+		domDesc = "on the last weekday of the month"
 	default:
-		if strings.Contains(dayOfMonth, ",") {
-			domDesc = fmt.Sprintf("on days %s of the month", dayOfMonth)
+		if day := strings.TrimSuffix(strings.ToUpper(dayOfMonth), "W"); day != strings.ToUpper(dayOfMonth) {
+			domDesc = fmt.Sprintf("on the weekday nearest the %s%s of the month", day, ordinalSuffix(day))
+		} else if strings.Contains(dayOfMonth, ",") {
+			domDesc = fmt.Sprintf("on days %s of the month", joinNatural(strings.Split(dayOfMonth, ",")))
 		} else if strings.Contains(dayOfMonth, "-") {
+			descriptionFallbacks.WithLabelValues("day-of-month").Inc()
 			domDesc = fmt.Sprintf("on days %s of the month", dayOfMonth)
 		} else if strings.Contains(dayOfMonth, "/") {
 			parts := strings.Split(dayOfMonth, "/")
@@ -528,15 +971,7 @@ func generateDescription(expression string) string {
 				domDesc = fmt.Sprintf("every %s day(s) of the month", parts[1])
 			}
 		} else {
-			suffix := "th"
-			if dayOfMonth == "1" || dayOfMonth == "21" || dayOfMonth == "31" {
-				suffix = "st"
-			} else if dayOfMonth == "2" || dayOfMonth == "22" {
-				suffix = "nd"
-			} else if dayOfMonth == "3" || dayOfMonth == "23" {
-				suffix = "rd"
-			}
-			domDesc = fmt.Sprintf("on the %s%s of the month", dayOfMonth, suffix)
+			domDesc = fmt.Sprintf("on the %s%s of the month", dayOfMonth, ordinalSuffix(dayOfMonth))
 		}
 	}
 
@@ -551,32 +986,35 @@ func generateDescription(expression string) string {
 			parts := strings.Split(month, ",")
 			months := []string{}
 			for _, m := range parts {
-				if i, err := fmt.Sscanf(m, "%d", new(int)); err == nil && i > 0 && i <= 12 {
+				if i, err := strconv.Atoi(m); err == nil && i > 0 && i <= 12 {
 					months = append(months, monthNames[i])
 				} else {
 					months = append(months, m)
 				}
 			}
-			monthDesc = fmt.Sprintf("in %s", strings.Join(months, ", "))
+			monthDesc = fmt.Sprintf("in %s", joinNatural(months))
+		} else if start, end, ok := wrapRange(month, cronFields[3]); ok {
+			monthDesc = fmt.Sprintf("from %s through %s (across the new year)", monthNames[start], monthNames[end])
 		} else if strings.Contains(month, "-") {
 			parts := strings.Split(month, "-")
 			if len(parts) == 2 {
 				start, end := "", ""
-				if i, err := fmt.Sscanf(parts[0], "%d", new(int)); err == nil && i > 0 && i <= 12 {
+				if i, err := strconv.Atoi(parts[0]); err == nil && i > 0 && i <= 12 {
 					start = monthNames[i]
 				} else {
 					start = parts[0]
 				}
-				if i, err := fmt.Sscanf(parts[1], "%d", new(int)); err == nil && i > 0 && i <= 12 {
+				if i, err := strconv.Atoi(parts[1]); err == nil && i > 0 && i <= 12 {
 					end = monthNames[i]
 				} else {
 					end = parts[1]
 				}
 				monthDesc = fmt.Sprintf("from %s to %s", start, end)
 			}
-		} else if i, err := fmt.Sscanf(month, "%d", new(int)); err == nil && i > 0 && i <= 12 {
+		} else if i, err := strconv.Atoi(month); err == nil && i > 0 && i <= 12 {
 			monthDesc = fmt.Sprintf("in %s", monthNames[i])
 		} else {
+			descriptionFallbacks.WithLabelValues("month").Inc()
 			monthDesc = fmt.Sprintf("in month %s", month)
 		}
 	}
@@ -603,14 +1041,17 @@ func generateDescription(expression string) string {
 		dowDesc = "on Saturdays"
 	case "1-5":
 		dowDesc = "on weekdays"
-	case "0,6", "6,0", "6,7":
+	case "0,6", "6,0", "6,7", "7,6":
 		dowDesc = "on weekends"
 	default:
 		if strings.Contains(dayOfWeek, ",") {
 			parts := strings.Split(dayOfWeek, ",")
+			if days, ok := weekOrderedDays(parts, weekStart); ok {
+				parts = days
+			}
 			days := []string{}
 			for _, d := range parts {
-				if i, err := fmt.Sscanf(d, "%d", new(int)); err == nil && i >= 0 && i <= 7 {
+				if i, err := strconv.Atoi(d); err == nil && i >= 0 && i <= 7 {
 					idx := i
 					if idx == 7 {
 						idx = 0 // Both 0 and 7 represent Sunday
@@ -620,12 +1061,14 @@ func generateDescription(expression string) string {
 					days = append(days, d)
 				}
 			}
-			dowDesc = fmt.Sprintf("on %s", strings.Join(days, ", "))
+			dowDesc = fmt.Sprintf("on %s", joinNatural(days))
+		} else if start, end, ok := wrapRange(dayOfWeek, cronFields[4]); ok {
+			dowDesc = fmt.Sprintf("from %s through %s", dowNames[start], dowNames[end])
 		} else if strings.Contains(dayOfWeek, "-") {
 			parts := strings.Split(dayOfWeek, "-")
 			if len(parts) == 2 {
 				start, end := "", ""
-				if i, err := fmt.Sscanf(parts[0], "%d", new(int)); err == nil && i >= 0 && i <= 7 {
+				if i, err := strconv.Atoi(parts[0]); err == nil && i >= 0 && i <= 7 {
 					idx := i
 					if idx == 7 {
 						idx = 0
@@ -634,7 +1077,7 @@ func generateDescription(expression string) string {
 				} else {
 					start = parts[0]
 				}
-				if i, err := fmt.Sscanf(parts[1], "%d", new(int)); err == nil && i >= 0 && i <= 7 {
+				if i, err := strconv.Atoi(parts[1]); err == nil && i >= 0 && i <= 7 {
 					idx := i
 					if idx == 7 {
 						idx = 0
@@ -644,8 +1087,13 @@ func generateDescription(expression string) string {
 					end = parts[1]
 				}
 				dowDesc = fmt.Sprintf("from %s to %s", start, end)
+				if weekStart == time.Monday && start == "Sunday" && end != "Sunday" && end != "Saturday" {
+					// Sunday closes a Monday-first week, so read it last
+					dowDesc = fmt.Sprintf("from Monday to %s and on Sundays", end)
+				}
 			}
 		} else {
+			descriptionFallbacks.WithLabelValues("day-of-week").Inc()
 			dowDesc = fmt.Sprintf("on day %s of the week", dayOfWeek)
 		}
 	}
@@ -663,49 +1111,184 @@ func generateDescription(expression string) string {
 		return "This cron expression will run at the start of every hour."
 	}
 
-	// Combine descriptions
-	if minute == "*" && hour == "*" {
-		description += minuteDesc + " " + hourDesc
-	} else if minute == "*" {
-		description += "every minute " + hourDesc
-	} else if hour == "*" {
-		description += minuteDesc + " of every hour"
-	} else {
-		description += minuteDesc + " " + hourDesc
-	}
+	description += describeTimeOfDay(minute, hour, minuteDesc, hourDesc)
 
-	// Add day of month and month only if they're not wildcards
-	if dayOfMonth != "*" {
+	// Cron fires when either day field matches once both are restricted
+	if dayOfMonth != "*" && dayOfWeek != "*" {
+		description += " " + domDesc + " or " + dowDesc
+	} else if dayOfMonth != "*" {
 		description += " " + domDesc
+	} else if dayOfWeek != "*" {
+		description += " " + dowDesc
 	}
 
 	if month != "*" {
 		description += " " + monthDesc
 	}
 
-	// Add day of week only if it's not a wildcard
-	if dayOfWeek != "*" {
-		description += " " + dowDesc
+	return description + "."
+}
+
+// describeTimeOfDay joins the minute and hour descriptions into one phrase,
+// folding fixed values into clock times so "30 9" reads "at 09:30" and
+// "30 *" reads "at 30 minutes past every hour".
+func describeTimeOfDay(minute, hour, minuteDesc, hourDesc string) string {
+	everyHour := hour == "*" || hour == "*/1"
+	singleHour, singleErr := strconv.Atoi(hour)
+
+	if minute == "*" || minute == "*/1" {
+		if everyHour {
+			return "every minute"
+		}
+		if singleErr == nil {
+			return fmt.Sprintf("every minute between %02d:00 and %02d:59", singleHour, singleHour)
+		}
+		return "every minute " + hourDesc
 	}
 
-	return description + "."
+	m, err := strconv.Atoi(minute)
+	if err != nil {
+		switch {
+		case everyHour && (strings.Contains(minute, "/") || strings.HasSuffix(minuteDesc, "hour")):
+			// Steps and wrapped ranges already read as recurring each hour
+			return minuteDesc
+		case everyHour:
+			return minuteDesc + " of every hour"
+		case singleErr == nil:
+			return fmt.Sprintf("%s between %02d:00 and %02d:59", minuteDesc, singleHour, singleHour)
+		}
+		return minuteDesc + " " + hourDesc
+	}
+
+	if everyHour {
+		return minutesPast(m) + " every hour"
+	}
+
+	// A stepped hour such as "*/2", "3/6" or "2-22/4" reads as "at 15
+	// minutes past every 2 hours", keeping any start or bounds
+	if base, step, found := strings.Cut(hour, "/"); found {
+		if n, err := strconv.Atoi(step); err == nil && n > 1 {
+			description := fmt.Sprintf("%s every %d hours", minutesPast(m), n)
+			if start, end, isRange := strings.Cut(base, "-"); isRange {
+				return fmt.Sprintf("%s from %s to %s", description, clockHour(start), clockHour(end))
+			} else if base != "*" {
+				return fmt.Sprintf("%s starting at %s", description, clockHour(base))
+			}
+			return description
+		}
+	}
+
+	if hours, ok := plainValues(hour, cronFields[1]); ok {
+		if len(hours) == 1 && m == 0 {
+			switch hours[0] {
+			case 0:
+				return "at midnight"
+			case 12:
+				return "at noon"
+			}
+		}
+		times := make([]string, len(hours))
+		for i, h := range hours {
+			times[i] = fmt.Sprintf("%02d:%02d", h, m)
+		}
+		return "at " + joinNatural(times)
+	}
+	if m == 0 && strings.HasPrefix(hourDesc, "every hour") {
+		// "at the start of every hour from 22:00 through 02:00 (overnight)"
+		return "at the start of " + hourDesc
+	}
+	return minuteDesc + " " + hourDesc
+}
+
+// minutesPast phrases a fixed minute relative to the hour, e.g. "at 15
+// minutes past", for describeTimeOfDay to complete with the hours
+func minutesPast(minute int) string {
+	switch minute {
+	case 0:
+		return "at the start of"
+	case 1:
+		return "at 1 minute past"
+	}
+	return fmt.Sprintf("at %d minutes past", minute)
+}
+
+// clockHour formats an hour field value as HH:00, leaving non-numeric values as is
+func clockHour(hour string) string {
+	h, err := strconv.Atoi(hour)
+	if err != nil {
+		return hour
+	}
+	return fmt.Sprintf("%02d:00", h)
+}
+
+// ordinalSuffix returns the English ordinal suffix for a day of the month
+func ordinalSuffix(day string) string {
+	switch day {
+	case "1", "21", "31":
+		return "st"
+	case "2", "22":
+		return "nd"
+	case "3", "23":
+		return "rd"
+	}
+	return "th"
+}
+
+// joinNatural joins list items as prose: "a and b", or "a, b, and c" with an
+// Oxford comma
+func joinNatural(items []string) string {
+	switch len(items) {
+	case 0:
+		return ""
+	case 1:
+		return items[0]
+	case 2:
+		return items[0] + " and " + items[1]
+	}
+	return strings.Join(items[:len(items)-1], ", ") + ", and " + items[len(items)-1]
 }
 
 func calculateNextExecutions(expression string, count int) []string {
-	parser := cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow)
-	schedule, err := parser.Parse(expression)
+	schedule, err := parseExpression(expression)
 	if err != nil {
 		return []string{fmt.Sprintf("Error parsing cron expression: %s", err.Error())}
 	}
 
-	now := time.Now()
-	next := schedule.Next(now)
-	executions := []string{}
+	return formatExecutions(nextExecutionTimes(schedule, time.Now(), count))
+}
 
-	for i := 0; i < count; i++ {
-		executions = append(executions, next.Format("Mon Jan 2 2006 at 15:04:05"))
+// executionFormat is the layout used for next execution times in responses
+const executionFormat = "Mon Jan 2 2006 at 15:04:05"
+
+// nextExecutionTimes returns the next count activations of schedule after from
+func nextExecutionTimes(schedule cron.Schedule, from time.Time, count int) []time.Time {
+	times := []time.Time{}
+	next := schedule.Next(from)
+	// A zero time means the schedule will not fire again, e.g. a past year
+	for i := 0; i < count && !next.IsZero(); i++ {
+		times = append(times, next)
 		next = schedule.Next(next)
 	}
+	return times
+}
 
+// groupExecutionsByDate groups execution times under their calendar date in
+// loc, e.g. {"2024-01-15": ["09:00", "17:00"]}
+func groupExecutionsByDate(times []time.Time, loc *time.Location) map[string][]string {
+	grouped := map[string][]string{}
+	for _, t := range times {
+		t = t.In(loc)
+		date := t.Format("2006-01-02")
+		grouped[date] = append(grouped[date], t.Format("15:04"))
+	}
+	return grouped
+}
+
+// formatExecutions formats execution times for display
+func formatExecutions(times []time.Time) []string {
+	executions := []string{}
+	for _, t := range times {
+		executions = append(executions, t.Format(executionFormat))
+	}
 	return executions
 }
@@ -1,11 +1,36 @@
 package main
 
 import (
+	"bufio"
 	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"os"
 	"os/exec"
+	"reflect"
+	"regexp"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/robfig/cron/v3"
+	"github.com/vmihailenco/msgpack/v5"
 )
 
 func TestCronTimeConverter(t *testing.T) {
@@ -153,3 +178,4935 @@ func TestCronHelperProcess(t *testing.T) {
 	os.Stdout.WriteString(jenkinsCron)
 	os.Exit(0)
 }
+
+// readEvent reads the next server-sent event, skipping keep-alive comments
+func readEvent(t *testing.T, reader *bufio.Reader) (event, data string) {
+	t.Helper()
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("Reading stream: %v", err)
+		}
+		line = strings.TrimRight(line, "\n")
+		switch {
+		case strings.HasPrefix(line, "event: "):
+			event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			data = strings.TrimPrefix(line, "data: ")
+		case line == "" && event != "":
+			return event, data
+		}
+	}
+}
+
+func TestConvertStreamSession(t *testing.T) {
+	s := NewServer(nil, testConfig())
+	r := s.routes()
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/api/convert/stream?session=chosen&expression=0+9+*+*+*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	reader := bufio.NewReader(resp.Body)
+
+	event, data := readEvent(t, reader)
+	var opened map[string]string
+	json.Unmarshal([]byte(data), &opened)
+	session := opened["session"]
+	if event != "session" || !regexp.MustCompile(`^[0-9a-f]{32}$`).MatchString(session) {
+		t.Fatalf("Expected a generated session ID, got %s %s", event, data)
+	}
+	if header := resp.Header.Get("X-Stream-Session"); header != session {
+		t.Errorf("X-Stream-Session = %q, expected %q", header, session)
+	}
+	if event, _ := readEvent(t, reader); event != "result" {
+		t.Errorf("Expected a result event for the initial expression, got %q", event)
+	}
+
+	post := func(r http.Handler, session, body string) int {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest("POST", "/api/convert/stream/"+session, strings.NewReader(body)))
+		return rec.Code
+	}
+
+	// The client-chosen ID is ignored
+	if code := post(r, "chosen", `{"expression":"0 10 * * *"}`); code != http.StatusNotFound {
+		t.Errorf("Expected status %d for a client-chosen session but got %d", http.StatusNotFound, code)
+	}
+	// Sessions belong to the server that opened them
+	if code := post(NewServer(nil, testConfig()).routes(), session, `{"expression":"0 10 * * *"}`); code != http.StatusNotFound {
+		t.Errorf("Expected status %d for another server's session but got %d", http.StatusNotFound, code)
+	}
+	if code := post(r, session, `{"expression":`); code != http.StatusBadRequest {
+		t.Errorf("Expected status %d for a bad body but got %d", http.StatusBadRequest, code)
+	}
+
+	if code := post(r, session, `{"expression":"0 10 * * *"}`); code != http.StatusAccepted {
+		t.Fatalf("Expected status %d but got %d", http.StatusAccepted, code)
+	}
+	_, data = readEvent(t, reader)
+	var result StreamResult
+	json.Unmarshal([]byte(data), &result)
+	if result.Expression != "0 10 * * *" || !result.Valid {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+}
+
+func TestNewSessionID(t *testing.T) {
+	a, err := newSessionID()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := newSessionID()
+	if len(a) != 32 || a == b {
+		t.Errorf("Expected distinct 32-character IDs, got %q and %q", a, b)
+	}
+}
+
+func TestGroupCrontabEntries(t *testing.T) {
+	entries := parseCrontab(`MAILTO=ops@example.com
+# hourly polling
+0,15,30,45 * * * * /usr/bin/poll
+*/15  *  *  *  * /usr/bin/sync
+0 0 * * * /usr/bin/backup
+00 0 * * * /usr/bin/rotate
+0 9 * * mon /usr/bin/standup
+0 9 * * MON /usr/bin/coffee
+61 * * * * /usr/bin/broken
+0 10 * * *
+`)
+	groups := groupCrontabEntries(entries)
+
+	expected := []struct {
+		expression string
+		lines      []int
+	}{
+		{"0,15,30,45 * * * *", []int{3, 4}},
+		{"0 0 * * *", []int{5, 6}},
+		{"0 9 * * mon", []int{7, 8}},
+		{"61 * * * *", []int{9}},
+		{"0 10 * * *", []int{10}},
+	}
+	if len(groups) != len(expected) {
+		t.Fatalf("Expected %d groups, got %d: %+v", len(expected), len(groups), groups)
+	}
+	for i, group := range groups {
+		lines := []int{}
+		for _, command := range group.Commands {
+			lines = append(lines, command.Line)
+		}
+		if group.Expression != expected[i].expression || !reflect.DeepEqual(lines, expected[i].lines) {
+			t.Errorf("Group %d = %q lines %v, expected %q lines %v", i, group.Expression, lines, expected[i].expression, expected[i].lines)
+		}
+	}
+	if groups[3].Error == "" || groups[4].Error != "Missing command" {
+		t.Errorf("Expected errors on the invalid groups, got %+v", groups[3:])
+	}
+}
+
+func TestParseCrontabHandler(t *testing.T) {
+	s := NewServer(nil, testConfig())
+	body := `{"crontab":"*/15 * * * * a\n0,15,30,45 * * * * b\n"}`
+
+	rec := callHandler(s.parseCrontabHandler, "POST", "/api/crontab/parse", body)
+	var entries map[string][]CrontabEntry
+	json.NewDecoder(rec.Body).Decode(&entries)
+	if len(entries["entries"]) != 2 || entries["entries"][1].Command != "b" {
+		t.Errorf("Expected two entries, got %+v", entries)
+	}
+
+	rec = callHandler(s.parseCrontabHandler, "POST", "/api/crontab/parse?groupBySchedule=true", body)
+	var groups map[string][]CrontabGroup
+	json.NewDecoder(rec.Body).Decode(&groups)
+	if len(groups["groups"]) != 1 || len(groups["groups"][0].Commands) != 2 {
+		t.Errorf("Expected one group of two commands, got %+v", groups)
+	}
+
+	rec = callHandler(s.parseCrontabHandler, "POST", "/api/crontab/parse", `{"crontab":`)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d for bad JSON but got %d", http.StatusBadRequest, rec.Code)
+	}
+}
+
+// callHandler runs a single handler against a request with the given body
+func callHandler(handler http.HandlerFunc, method, target, body string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(method, target, strings.NewReader(body)))
+	return rec
+}
+
+func TestValidatePolicyHandler(t *testing.T) {
+	s := NewServer(nil, testConfig())
+	tests := []struct {
+		name       string
+		body       string
+		compliant  bool
+		violations []string
+	}{
+		{"weekday mornings",
+			`{"expression":"0 9 * * 1-5","policy":{"forbiddenWindows":[{"start":"00:00","end":"06:00"}],"weekdaysOnly":true}}`,
+			true, nil},
+		{"fires at night",
+			`{"expression":"0 3 * * *","policy":{"forbiddenWindows":[{"start":"00:00","end":"06:00"}]}}`,
+			false, []string{"forbiddenWindow 00:00-06:00"}},
+		{"window wraps past midnight",
+			`{"expression":"0 23 * * *","policy":{"forbiddenWindows":[{"start":"22:00","end":"02:00"}]}}`,
+			false, []string{"forbiddenWindow 22:00-02:00"}},
+		{"fires on weekends",
+			`{"expression":"0 12 * * *","samples":14,"policy":{"weekdaysOnly":true}}`,
+			false, []string{"weekdaysOnly"}},
+	}
+	for _, tt := range tests {
+		rec := callHandler(s.validatePolicyHandler, "POST", "/api/validate/policy", tt.body)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: expected status %d but got %d: %s", tt.name, http.StatusOK, rec.Code, rec.Body.String())
+		}
+		var response PolicyResponse
+		json.NewDecoder(rec.Body).Decode(&response)
+		if response.Compliant != tt.compliant || len(response.Violations) != len(tt.violations) {
+			t.Fatalf("%s: expected compliant=%v with %v, got %+v", tt.name, tt.compliant, tt.violations, response)
+		}
+		for i, v := range response.Violations {
+			if v.Rule != tt.violations[i] || v.Occurrences == 0 || v.FirstViolation == "" {
+				t.Errorf("%s: unexpected violation %+v, expected rule %q", tt.name, v, tt.violations[i])
+			}
+		}
+		if response.SamplesChecked == 0 {
+			t.Errorf("%s: no samples checked", tt.name)
+		}
+	}
+}
+
+func TestValidatePolicyHandlerRejects(t *testing.T) {
+	s := NewServer(nil, testConfig())
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"invalid expression", `{"expression":"61 * * * *","policy":{"weekdaysOnly":true}}`},
+		{"conflicting day rules", `{"expression":"0 9 * * *","policy":{"weekdaysOnly":true,"weekendsOnly":true}}`},
+		{"bad window time", `{"expression":"0 9 * * *","policy":{"forbiddenWindows":[{"start":"25:00","end":"06:00"}]}}`},
+		{"bad JSON", `{"expression":`},
+	}
+	for _, tt := range tests {
+		rec := callHandler(s.validatePolicyHandler, "POST", "/api/validate/policy", tt.body)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status %d but got %d", tt.name, http.StatusBadRequest, rec.Code)
+		}
+	}
+}
+
+func TestScheduleTags(t *testing.T) {
+	tests := []struct {
+		expression string
+		expected   []string
+	}{
+		{"*/5 * * * *", []string{TagFrequent}},
+		{"*/30 9-17 * * 1-5", []string{TagFrequent, TagWeekday}},
+		{"0 * * * *", []string{TagHourly}},
+		{"0 2 * * *", []string{TagDaily, TagNight}},
+		{"0,30 9 * * *", []string{TagDaily, TagMorning}},
+		{"0 9 * * 1-5", []string{TagDaily, TagWeekday, TagMorning}},
+		{"0 10 * * 6,0", []string{TagDaily, TagWeekend, TagMorning}},
+		{"0 14 * * 1", []string{TagWeekly, TagWeekday, TagAfternoon}},
+		{"0 19 1 * *", []string{TagMonthly, TagEvening}},
+		{"0,30 9 1 * *", []string{TagMonthly, TagMorning}},
+		{"0 0 1 1 *", []string{TagYearly, TagNight}},
+	}
+	for _, tt := range tests {
+		schedule, err := parseExpression(tt.expression)
+		if err != nil {
+			t.Fatalf("parseExpression(%q) error: %v", tt.expression, err)
+		}
+		if got := scheduleTags(schedule); !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("scheduleTags(%q) = %v, expected %v", tt.expression, got, tt.expected)
+		}
+	}
+}
+
+func TestConvertWithIcons(t *testing.T) {
+	s := NewServer(nil, testConfig())
+	rec := callHandler(s.convertCronHandler, "POST", "/api/convert?withIcons=true", `{"expression":"0 9 * * 1-5"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status %d but got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	var response ConvertResponse
+	json.NewDecoder(rec.Body).Decode(&response)
+	if expected := []string{TagDaily, TagWeekday, TagMorning}; !reflect.DeepEqual(response.Tags, expected) {
+		t.Errorf("Expected tags %v, got %v", expected, response.Tags)
+	}
+
+	rec = callHandler(s.convertCronHandler, "POST", "/api/convert", `{"expression":"0 9 * * 1-5"}`)
+	var plain map[string]interface{}
+	json.NewDecoder(rec.Body).Decode(&plain)
+	if _, ok := plain["tags"]; ok {
+		t.Errorf("Expected no tags without withIcons, got %v", plain["tags"])
+	}
+
+	rec = callHandler(s.convertCronHandler, "POST", "/api/convert?withIcons=true", `{"expression":"0 25 * * *"}`)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d for an invalid expression, got %d", http.StatusBadRequest, rec.Code)
+	}
+}
+
+func TestStorePools(t *testing.T) {
+	primaryDB, _ := sql.Open("postgres", "postgres://primary/cron?sslmode=disable")
+	defer primaryDB.Close()
+	replicaDB, _ := sql.Open("postgres", "postgres://replica/cron?sslmode=disable")
+	defer replicaDB.Close()
+
+	withReplica := &store{primary: primaryDB, replica: replicaDB}
+	if withReplica.reader() != replicaDB || withReplica.writer() != primaryDB {
+		t.Errorf("Expected reads from the replica and writes to the primary")
+	}
+	primaryOnly := &store{primary: primaryDB}
+	if primaryOnly.reader() != primaryDB || primaryOnly.writer() != primaryDB {
+		t.Errorf("Expected reads to fall back to the primary without a replica")
+	}
+}
+
+func TestOpenReplica(t *testing.T) {
+	t.Setenv("DB_REPLICA_URL", "")
+	if replica := openReplica(); replica != nil {
+		replica.Close()
+		t.Errorf("Expected no replica when DB_REPLICA_URL is unset")
+	}
+
+	// An unreachable replica is skipped rather than failing startup
+	t.Setenv("DB_REPLICA_URL", "postgres://127.0.0.1:1/cron?sslmode=disable&connect_timeout=1")
+	if replica := openReplica(); replica != nil {
+		replica.Close()
+		t.Errorf("Expected no replica when it can't be reached")
+	}
+}
+
+// testConfig is a fixed configuration for tests, so they don't depend on the
+// developer's environment the way loadConfig would
+func testConfig() Config {
+	return Config{
+		Port:                 "8080",
+		StaticDir:            "./static",
+		SlowRequestThreshold: 250 * time.Millisecond,
+		CORSAllowedOrigins:   []string{},
+		SoftDeleteRetention:  30 * 24 * time.Hour,
+		DefaultTimezone:      time.UTC,
+		EnabledConverters:    parseEnabledConverters(""),
+		DBQueryTimeout:       5 * time.Second,
+		ReadyzTimeout:        2 * time.Second,
+	}
+}
+
+// newTestServer returns a server backed by a sqlmock database
+func newTestServer(t *testing.T) (*Server, sqlmock.Sqlmock) {
+	t.Helper()
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create sqlmock: %v", err)
+	}
+	t.Cleanup(func() { mockDB.Close() })
+	return NewServer(newStore(mockDB, nil), testConfig()), mock
+}
+
+// serve sends a request through the server's router and returns the recorder
+func serve(s *Server, method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	s.handler().ServeHTTP(rec, req)
+	return rec
+}
+
+var expressionRowColumns = []string{"id", "name", "expression", "description", "category_id", "enabled", "timezone", "metadata", "environment", "created_at", "updated_at", "last_validated_at"}
+
+func TestCreateExpressionHandler(t *testing.T) {
+	insert := regexp.QuoteMeta("INSERT INTO cron_expressions")
+
+	t.Run("success", func(t *testing.T) {
+		s, mock := newTestServer(t)
+		now := time.Now()
+		mock.ExpectQuery(insert).
+			WithArgs("Nightly", "0 0 * * *", "Backup", nil, sqlmock.AnyArg(), sqlmock.AnyArg(), "dev", sqlmock.AnyArg(), sqlmock.AnyArg()).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "updated_at"}).AddRow(1, now, now))
+
+		rec := serve(s, "POST", "/api/expressions", `{"name":"Nightly","expression":"0 0 * * *","description":"Backup"}`)
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("Expected status %d but got %d: %s", http.StatusCreated, rec.Code, rec.Body.String())
+		}
+		if !strings.Contains(rec.Body.String(), `"id":1`) || !strings.Contains(rec.Body.String(), `"metadata":{}`) {
+			t.Errorf("Expected created expression with empty metadata in body, got %s", rec.Body.String())
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("validation failure", func(t *testing.T) {
+		s, mock := newTestServer(t)
+		rec := serve(s, "POST", "/api/expressions", `{"name":"Broken","expression":"61 * * * *"}`)
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("Expected status %d but got %d", http.StatusBadRequest, rec.Code)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("invalid timezone", func(t *testing.T) {
+		s, mock := newTestServer(t)
+		rec := serve(s, "POST", "/api/expressions", `{"name":"Nightly","expression":"0 0 * * *","timezone":"Mars/Olympus"}`)
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("Expected status %d but got %d", http.StatusBadRequest, rec.Code)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("duplicate", func(t *testing.T) {
+		s, mock := newTestServer(t)
+		mock.ExpectQuery(insert).WillReturnError(&pq.Error{Code: pqUniqueViolation})
+
+		rec := serve(s, "POST", "/api/expressions", `{"name":"Nightly","expression":"0 0 * * *"}`)
+		if rec.Code != http.StatusConflict {
+			t.Fatalf("Expected status %d but got %d", http.StatusConflict, rec.Code)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+	})
+}
+
+func TestGetExpressionHandler(t *testing.T) {
+	query := regexp.QuoteMeta("FROM cron_expressions")
+
+	t.Run("found", func(t *testing.T) {
+		s, mock := newTestServer(t)
+		now := time.Now()
+		mock.ExpectQuery(query).WithArgs("7").
+			WillReturnRows(sqlmock.NewRows(expressionRowColumns).AddRow(7, "Hourly", "0 * * * *", "", nil, true, "", "{}", "dev", now, now, nil))
+
+		rec := serve(s, "GET", "/api/expressions/7", "")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected status %d but got %d", http.StatusOK, rec.Code)
+		}
+		if !strings.Contains(rec.Body.String(), `"name":"Hourly"`) {
+			t.Errorf("Expected expression in body, got %s", rec.Body.String())
+		}
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		s, mock := newTestServer(t)
+		mock.ExpectQuery(query).WithArgs("8").WillReturnError(sql.ErrNoRows)
+
+		rec := serve(s, "GET", "/api/expressions/8", "")
+		if rec.Code != http.StatusNotFound {
+			t.Fatalf("Expected status %d but got %d", http.StatusNotFound, rec.Code)
+		}
+	})
+}
+
+func TestErrorResponses(t *testing.T) {
+	insert := regexp.QuoteMeta("INSERT INTO cron_expressions")
+	query := regexp.QuoteMeta("FROM cron_expressions")
+
+	tests := []struct {
+		name   string
+		setup  func(s *Server, mock sqlmock.Sqlmock)
+		method string
+		path   string
+		body   string
+		status int
+		want   ErrorBody
+	}{
+		{
+			name:   "invalid cron field",
+			method: "POST", path: "/api/convert", body: `{"expression":"0 25 * * *"}`,
+			status: http.StatusBadRequest,
+			want:   ErrorBody{Code: ErrInvalidCron, Field: "hour", Token: "25"},
+		},
+		{
+			name:   "invalid timezone",
+			method: "POST", path: "/api/expressions", body: `{"name":"Nightly","expression":"0 0 * * *","timezone":"Mars/Olympus"}`,
+			status: http.StatusBadRequest,
+			want:   ErrorBody{Code: ErrInvalidTimezone, Field: "timezone"},
+		},
+		{
+			name: "not found",
+			setup: func(s *Server, mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(query).WithArgs("8").WillReturnError(sql.ErrNoRows)
+			},
+			method: "GET", path: "/api/expressions/8",
+			status: http.StatusNotFound,
+			want:   ErrorBody{Code: ErrNotFound},
+		},
+		{
+			name: "duplicate name",
+			setup: func(s *Server, mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(insert).WillReturnError(&pq.Error{Code: pqUniqueViolation})
+			},
+			method: "POST", path: "/api/expressions", body: `{"name":"Nightly","expression":"0 0 * * *"}`,
+			status: http.StatusConflict,
+			want:   ErrorBody{Code: ErrDuplicateName},
+		},
+		{
+			name: "database error",
+			setup: func(s *Server, mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(query).WithArgs("8").WillReturnError(errors.New("connection reset"))
+			},
+			method: "GET", path: "/api/expressions/8",
+			status: http.StatusInternalServerError,
+			want:   ErrorBody{Code: ErrDBError},
+		},
+		{
+			name: "unauthorized",
+			setup: func(s *Server, mock sqlmock.Sqlmock) {
+				s.config.APIToken = "secret"
+			},
+			method: "GET", path: "/api/expressions",
+			status: http.StatusUnauthorized,
+			want:   ErrorBody{Code: ErrUnauthorized},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, mock := newTestServer(t)
+			if tt.setup != nil {
+				tt.setup(s, mock)
+			}
+			rec := serve(s, tt.method, tt.path, tt.body)
+			if rec.Code != tt.status {
+				t.Fatalf("Expected status %d but got %d", tt.status, rec.Code)
+			}
+			if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+				t.Errorf("Expected JSON content type, got %q", ct)
+			}
+			var response ErrorResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Expected JSON error body, got %s", rec.Body.String())
+			}
+			got := response.Error
+			if got.Code != tt.want.Code || got.Field != tt.want.Field || got.Token != tt.want.Token || got.Message == "" {
+				t.Errorf("Expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestNextRunHandler(t *testing.T) {
+	query := regexp.QuoteMeta("FROM cron_expressions")
+
+	t.Run("found", func(t *testing.T) {
+		s, mock := newTestServer(t)
+		s.config.DefaultTimezone = time.UTC
+		now := time.Now()
+		mock.ExpectQuery(query).WithArgs("7").
+			WillReturnRows(sqlmock.NewRows(expressionRowColumns).AddRow(7, "Hourly", "0 * * * *", "", nil, true, "", "{}", "dev", now, now, nil))
+
+		rec := serve(s, "GET", "/api/expressions/7/next", "")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected status %d but got %d", http.StatusOK, rec.Code)
+		}
+		next, err := time.Parse(http.TimeFormat, rec.Header().Get("X-Next-Run"))
+		if err != nil {
+			t.Fatalf("Expected RFC1123 X-Next-Run header, got %q", rec.Header().Get("X-Next-Run"))
+		}
+		if next.Minute() != 0 || !next.After(now) {
+			t.Errorf("Expected next top of the hour after %s, got %s", now, next)
+		}
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		s, mock := newTestServer(t)
+		mock.ExpectQuery(query).WithArgs("8").WillReturnError(sql.ErrNoRows)
+
+		rec := serve(s, "GET", "/api/expressions/8/next", "")
+		if rec.Code != http.StatusNotFound {
+			t.Fatalf("Expected status %d but got %d", http.StatusNotFound, rec.Code)
+		}
+	})
+}
+
+func TestWorldclockHandler(t *testing.T) {
+	query := regexp.QuoteMeta("FROM cron_expressions")
+
+	t.Run("found", func(t *testing.T) {
+		s, mock := newTestServer(t)
+		now := time.Now()
+		mock.ExpectQuery(query).WithArgs("7").
+			WillReturnRows(sqlmock.NewRows(expressionRowColumns).AddRow(7, "Standup", "30 9 * * *", "", nil, true, "Europe/London", "{}", "dev", now, now, nil))
+
+		rec := serve(s, "GET", "/api/expressions/7/worldclock?zones=America/New_York,Asia/Tokyo", "")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected status %d but got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+		}
+		var response WorldclockResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+			t.Fatal(err)
+		}
+		if response.NextRun == nil || len(response.Zones) != 2 {
+			t.Fatalf("Expected a next run in 2 zones, got %+v", response)
+		}
+		for _, zone := range response.Zones {
+			if zone.NextRun == nil || !zone.NextRun.Equal(*response.NextRun) {
+				t.Errorf("Expected %s to show the same instant as %s, got %+v", zone.Zone, response.NextRun, zone)
+			}
+		}
+		if response.Zones[0].Zone != "America/New_York" || response.Zones[1].Offset != "+09:00" {
+			t.Errorf("Expected zones in request order with their offsets, got %+v", response.Zones)
+		}
+	})
+
+	t.Run("invalid zones", func(t *testing.T) {
+		s, mock := newTestServer(t)
+		rec := serve(s, "GET", "/api/expressions/7/worldclock?zones=Asia/Tokyo,Mars/Olympus,Nowhere", "")
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("Expected status %d but got %d", http.StatusBadRequest, rec.Code)
+		}
+		var response ErrorResponse
+		json.Unmarshal(rec.Body.Bytes(), &response)
+		if !reflect.DeepEqual(response.Error.Problems, []string{"Mars/Olympus", "Nowhere"}) {
+			t.Errorf("Expected both invalid zones listed, got %+v", response.Error)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		s, mock := newTestServer(t)
+		mock.ExpectQuery(query).WithArgs("8").WillReturnError(sql.ErrNoRows)
+
+		rec := serve(s, "GET", "/api/expressions/8/worldclock?zones=UTC", "")
+		if rec.Code != http.StatusNotFound {
+			t.Fatalf("Expected status %d but got %d", http.StatusNotFound, rec.Code)
+		}
+	})
+}
+
+func TestUpdateExpressionHandler(t *testing.T) {
+	update := regexp.QuoteMeta("UPDATE cron_expressions")
+	body := `{"name":"Hourly","expression":"0 * * * *"}`
+
+	t.Run("found", func(t *testing.T) {
+		s, mock := newTestServer(t)
+		now := time.Now()
+		mock.ExpectExec(update).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery(regexp.QuoteMeta("FROM cron_expressions")).WithArgs("7").
+			WillReturnRows(sqlmock.NewRows(expressionRowColumns).AddRow(7, "Hourly", "0 * * * *", "", nil, true, "", "{}", "dev", now, now, nil))
+
+		rec := serve(s, "PUT", "/api/expressions/7", body)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected status %d but got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("duplicate", func(t *testing.T) {
+		s, mock := newTestServer(t)
+		mock.ExpectExec(update).WillReturnError(&pq.Error{Code: pqUniqueViolation})
+
+		rec := serve(s, "PUT", "/api/expressions/8", body)
+		if rec.Code != http.StatusConflict {
+			t.Fatalf("Expected status %d but got %d", http.StatusConflict, rec.Code)
+		}
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		s, mock := newTestServer(t)
+		mock.ExpectExec(update).WillReturnResult(sqlmock.NewResult(0, 0))
+
+		rec := serve(s, "PUT", "/api/expressions/8", body)
+		if rec.Code != http.StatusNotFound {
+			t.Fatalf("Expected status %d but got %d", http.StatusNotFound, rec.Code)
+		}
+	})
+}
+
+func TestDeleteExpressionHandler(t *testing.T) {
+	deleteQuery := regexp.QuoteMeta("UPDATE cron_expressions")
+
+	t.Run("found", func(t *testing.T) {
+		s, mock := newTestServer(t)
+		mock.ExpectExec(deleteQuery).WithArgs(sqlmock.AnyArg(), "7").WillReturnResult(sqlmock.NewResult(0, 1))
+
+		rec := serve(s, "DELETE", "/api/expressions/7", "")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected status %d but got %d", http.StatusOK, rec.Code)
+		}
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		s, mock := newTestServer(t)
+		mock.ExpectExec(deleteQuery).WithArgs(sqlmock.AnyArg(), "8").WillReturnResult(sqlmock.NewResult(0, 0))
+
+		rec := serve(s, "DELETE", "/api/expressions/8", "")
+		if rec.Code != http.StatusNotFound {
+			t.Fatalf("Expected status %d but got %d", http.StatusNotFound, rec.Code)
+		}
+	})
+}
+
+// newReplicaTestServer returns a server with separate primary and replica mocks
+func newReplicaTestServer(t *testing.T) (*Server, sqlmock.Sqlmock, sqlmock.Sqlmock) {
+	t.Helper()
+	primaryDB, primary, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create sqlmock: %v", err)
+	}
+	replicaDB, replica, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create sqlmock: %v", err)
+	}
+	t.Cleanup(func() {
+		primaryDB.Close()
+		replicaDB.Close()
+	})
+	return NewServer(newStore(primaryDB, replicaDB), testConfig()), primary, replica
+}
+
+func TestReplicaReads(t *testing.T) {
+	query := regexp.QuoteMeta("FROM cron_expressions")
+
+	t.Run("reads from the replica", func(t *testing.T) {
+		s, primary, replica := newReplicaTestServer(t)
+		now := time.Now()
+		replica.ExpectQuery(query).WithArgs("5").
+			WillReturnRows(sqlmock.NewRows(expressionRowColumns).AddRow(5, "Nightly", "0 0 * * *", "", nil, true, "", "{}", "dev", now, now, now))
+
+		rec := serve(s, "GET", "/api/expressions/5", "")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected status %d but got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+		}
+		if err := replica.ExpectationsWereMet(); err != nil {
+			t.Errorf("Unfulfilled replica expectations: %v", err)
+		}
+		if err := primary.ExpectationsWereMet(); err != nil {
+			t.Errorf("Unexpected primary queries: %v", err)
+		}
+	})
+
+	t.Run("writes to the primary", func(t *testing.T) {
+		s, primary, replica := newReplicaTestServer(t)
+		primary.ExpectExec(regexp.QuoteMeta("UPDATE cron_expressions")).WithArgs(sqlmock.AnyArg(), "5").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		if rec := serve(s, "DELETE", "/api/expressions/5", ""); rec.Code != http.StatusOK {
+			t.Fatalf("Expected status %d but got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+		}
+		if err := primary.ExpectationsWereMet(); err != nil {
+			t.Errorf("Unfulfilled primary expectations: %v", err)
+		}
+		if err := replica.ExpectationsWereMet(); err != nil {
+			t.Errorf("Unexpected replica queries: %v", err)
+		}
+	})
+
+	t.Run("replica error", func(t *testing.T) {
+		s, primary, replica := newReplicaTestServer(t)
+		replica.ExpectQuery(query).WithArgs("5").WillReturnError(errors.New("connection reset"))
+
+		rec := serve(s, "GET", "/api/expressions/5", "")
+		if rec.Code != http.StatusInternalServerError {
+			t.Fatalf("Expected status %d but got %d: %s", http.StatusInternalServerError, rec.Code, rec.Body.String())
+		}
+		if err := primary.ExpectationsWereMet(); err != nil {
+			t.Errorf("Unexpected primary queries: %v", err)
+		}
+	})
+}
+
+func TestNewServerInjection(t *testing.T) {
+	// Each server only talks to the store it was given
+	okServer, okMock := newTestServer(t)
+	failServer, failMock := newTestServer(t)
+	query := regexp.QuoteMeta("FROM cron_expressions")
+	now := time.Now()
+	okMock.ExpectQuery(query).WithArgs("5").
+		WillReturnRows(sqlmock.NewRows(expressionRowColumns).AddRow(5, "Nightly", "0 0 * * *", "", nil, true, "", "{}", "dev", now, now, now))
+	failMock.ExpectQuery(query).WithArgs("5").WillReturnError(errors.New("connection reset"))
+
+	if rec := serve(okServer, "GET", "/api/expressions/5", ""); rec.Code != http.StatusOK {
+		t.Errorf("Expected status %d but got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	if rec := serve(failServer, "GET", "/api/expressions/5", ""); rec.Code != http.StatusInternalServerError {
+		t.Errorf("Expected status %d but got %d: %s", http.StatusInternalServerError, rec.Code, rec.Body.String())
+	}
+	for _, mock := range []sqlmock.Sqlmock{okMock, failMock} {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+	}
+
+	config := testConfig()
+	config.StaticDir = "./assets"
+	if s := NewServer(okServer.db, config); s.config.StaticDir != "./assets" || s.db != okServer.db {
+		t.Errorf("Expected NewServer to keep the injected store and config")
+	}
+}
+
+func TestCategoryHandlers(t *testing.T) {
+	categoryColumns := []string{"id", "name", "description", "created_at", "updated_at"}
+	now := time.Now()
+
+	t.Run("create", func(t *testing.T) {
+		s, mock := newTestServer(t)
+		mock.ExpectQuery(regexp.QuoteMeta("INSERT INTO categories")).
+			WithArgs("Backups", "Nightly jobs", sqlmock.AnyArg(), sqlmock.AnyArg()).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "updated_at"}).AddRow(3, now, now))
+
+		rec := serve(s, "POST", "/api/categories", `{"name":"Backups","description":"Nightly jobs"}`)
+		if rec.Code != http.StatusCreated || !strings.Contains(rec.Body.String(), `"id":3`) {
+			t.Fatalf("Expected status %d with the new category but got %d: %s", http.StatusCreated, rec.Code, rec.Body.String())
+		}
+	})
+
+	t.Run("create without a name", func(t *testing.T) {
+		s, _ := newTestServer(t)
+		if rec := serve(s, "POST", "/api/categories", `{"description":"Nameless"}`); rec.Code != http.StatusBadRequest {
+			t.Errorf("Expected status %d but got %d", http.StatusBadRequest, rec.Code)
+		}
+	})
+
+	t.Run("duplicate name", func(t *testing.T) {
+		s, mock := newTestServer(t)
+		mock.ExpectQuery(regexp.QuoteMeta("UPDATE categories")).WillReturnError(&pq.Error{Code: pqUniqueViolation})
+		if rec := serve(s, "PUT", "/api/categories/3", `{"name":"Backups"}`); rec.Code != http.StatusConflict {
+			t.Errorf("Expected status %d but got %d", http.StatusConflict, rec.Code)
+		}
+	})
+
+	t.Run("list and get", func(t *testing.T) {
+		s, mock := newTestServer(t)
+		mock.ExpectQuery(regexp.QuoteMeta("FROM categories")).
+			WillReturnRows(sqlmock.NewRows(categoryColumns).AddRow(3, "Backups", "", now, now).AddRow(4, "Reports", "", now, now))
+		mock.ExpectQuery(regexp.QuoteMeta("FROM categories")).WithArgs("9").WillReturnError(sql.ErrNoRows)
+
+		rec := serve(s, "GET", "/api/categories", "")
+		var categories []Category
+		json.NewDecoder(rec.Body).Decode(&categories)
+		if len(categories) != 2 || categories[1].Name != "Reports" {
+			t.Errorf("Expected two categories, got %+v", categories)
+		}
+		if rec := serve(s, "GET", "/api/categories/9", ""); rec.Code != http.StatusNotFound {
+			t.Errorf("Expected status %d but got %d", http.StatusNotFound, rec.Code)
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		s, mock := newTestServer(t)
+		mock.ExpectExec(regexp.QuoteMeta("DELETE FROM categories")).WithArgs("3").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta("DELETE FROM categories")).WithArgs("9").WillReturnResult(sqlmock.NewResult(0, 0))
+		if rec := serve(s, "DELETE", "/api/categories/3", ""); rec.Code != http.StatusOK {
+			t.Errorf("Expected status %d but got %d", http.StatusOK, rec.Code)
+		}
+		if rec := serve(s, "DELETE", "/api/categories/9", ""); rec.Code != http.StatusNotFound {
+			t.Errorf("Expected status %d but got %d", http.StatusNotFound, rec.Code)
+		}
+	})
+}
+
+func TestGetExpressionsByCategory(t *testing.T) {
+	s, mock := newTestServer(t)
+	now := time.Now()
+	mock.ExpectQuery(regexp.QuoteMeta("LEFT JOIN categories c ON c.id = e.category_id WHERE e.deleted_at IS NULL AND e.category_id = $1")).WithArgs("3").
+		WillReturnRows(sqlmock.NewRows(append(expressionRowColumns, "c_id", "c_name", "c_description")).
+			AddRow(7, "Nightly", "0 0 * * *", "", 3, true, "", "{}", "dev", now, now, now, 3, "Backups", "Nightly jobs"))
+
+	rec := serve(s, "GET", "/api/expressions?category=3&expand=category", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status %d but got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	var expressions []CronExpression
+	json.NewDecoder(rec.Body).Decode(&expressions)
+	if len(expressions) != 1 || expressions[0].Category == nil || expressions[0].Category.Name != "Backups" {
+		t.Errorf("Expected the expression with its category, got %+v", expressions)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+
+	if rec := serve(s, "GET", "/api/expressions?category=abc", ""); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d for a non-numeric category but got %d", http.StatusBadRequest, rec.Code)
+	}
+}
+
+func TestBatchConvertHandler(t *testing.T) {
+	s, _ := newTestServer(t)
+
+	rec := serve(s, "POST", "/api/convert/batch", `{"expressions":["0 9 * * 1-5","*/15 * * * *","61 * * * *","0 9 * * 1-5"]}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status %d but got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	var response BatchConvertResponse
+	json.NewDecoder(rec.Body).Decode(&response)
+	if len(response.Results) != 4 || response.Unique != 3 {
+		t.Fatalf("Expected 4 results from 3 unique expressions, got %+v", response)
+	}
+
+	// Results keep input order, with duplicates sharing one conversion
+	if !reflect.DeepEqual(response.Results[0], response.Results[3]) || response.Results[1].Expression != "*/15 * * * *" {
+		t.Errorf("Expected results in input order, got %+v", response.Results)
+	}
+	for _, i := range []int{0, 1} {
+		result := response.Results[i]
+		if result.Error != "" || result.Description == "" || len(result.NextExecutions) != 5 {
+			t.Errorf("Result %d: expected a description and 5 runs, got %+v", i, result)
+		}
+	}
+	if invalid := response.Results[2]; invalid.Error == "" || invalid.Description != "" {
+		t.Errorf("Expected the invalid expression to carry only an error, got %+v", invalid)
+	}
+}
+
+func TestBatchConvertHandlerTooLarge(t *testing.T) {
+	s, _ := newTestServer(t)
+
+	expressions := make([]string, maxBatchSize+1)
+	for i := range expressions {
+		expressions[i] = `"* * * * *"`
+	}
+	body := fmt.Sprintf(`{"expressions":[%s]}`, strings.Join(expressions, ","))
+	if rec := serve(s, "POST", "/api/convert/batch", body); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d but got %d", http.StatusBadRequest, rec.Code)
+	}
+}
+
+func TestHumanizeDuration(t *testing.T) {
+	tests := []struct {
+		d        time.Duration
+		expected string
+	}{
+		{0, "in less than a minute"},
+		{59 * time.Second, "in less than a minute"},
+		{time.Minute, "in 1 minute"},
+		{45*time.Minute + 30*time.Second, "in 45 minutes"},
+		{time.Hour, "in 1 hour"},
+		{3*time.Hour + 59*time.Minute, "in 3 hours"},
+		{24 * time.Hour, "in 1 day"},
+		{50 * time.Hour, "in 2 days"},
+	}
+	for _, tt := range tests {
+		if got := humanizeDuration(tt.d); got != tt.expected {
+			t.Errorf("humanizeDuration(%v) = %q, expected %q", tt.d, got, tt.expected)
+		}
+	}
+}
+
+func TestRelativeExecutions(t *testing.T) {
+	schedule, _ := parseExpression("CRON_TZ=UTC 0 */6 * * *")
+	now := time.Date(2024, 1, 15, 9, 0, 0, 0, time.UTC)
+	times := nextExecutionTimes(schedule, now, 5)
+
+	expected := []string{"in 3 hours", "in 9 hours", "in 15 hours", "in 21 hours", "in 1 day"}
+	if got := relativeExecutions(times, now); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected relative executions %v, got %v", expected, got)
+	}
+}
+
+func TestConvertRelative(t *testing.T) {
+	s, _ := newTestServer(t)
+
+	rec := serve(s, "POST", "/api/convert?relative=true", `{"expression":"0 */6 * * *"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status %d but got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	var response ConvertResponse
+	json.NewDecoder(rec.Body).Decode(&response)
+	if len(response.RelativeExecutions) != len(response.NextExecutions) || !strings.HasPrefix(response.RelativeExecutions[0], "in ") {
+		t.Errorf("Expected a relative time for each run, got %v for %v", response.RelativeExecutions, response.NextExecutions)
+	}
+
+	rec = serve(s, "POST", "/api/convert", `{"expression":"0 */6 * * *"}`)
+	response = ConvertResponse{}
+	json.NewDecoder(rec.Body).Decode(&response)
+	if response.RelativeExecutions != nil {
+		t.Errorf("Expected no relative executions without the flag, got %v", response.RelativeExecutions)
+	}
+}
+
+func TestClassifyCadence(t *testing.T) {
+	tests := []struct {
+		expression string
+		expected   string
+	}{
+		{"* * * * *", CadenceEveryMinute},
+		{"*/15 * * * *", CadenceSubHourly},
+		{"5 * * * *", CadenceHourly},
+		{"0 2 * * *", CadenceDaily},
+		{"0 9 * * 1", CadenceWeekly},
+		{"0 0 1 * *", CadenceMonthly},
+		{"0 0 1 1 *", CadenceYearly},
+		{"0 9,17 * * *", CadenceIrregular},
+		{"0 9 * * 1-5", CadenceIrregular},
+		{"*/5 9-17 * * *", CadenceIrregular},
+	}
+	for _, tt := range tests {
+		schedule, err := parseExpression(tt.expression)
+		if err != nil {
+			t.Fatalf("parseExpression(%q) error: %v", tt.expression, err)
+		}
+		if got := classifyCadence(schedule); got != tt.expected {
+			t.Errorf("classifyCadence(%q) = %q, expected %q", tt.expression, got, tt.expected)
+		}
+	}
+}
+
+func TestValidateCadenceHandler(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		status   int
+		detected string
+		matches  bool
+	}{
+		{"matching", `{"expression":"0 2 * * *","cadence":"daily"}`, http.StatusOK, CadenceDaily, true},
+		{"case insensitive", `{"expression":"0 9 * * 1","cadence":"Weekly"}`, http.StatusOK, CadenceWeekly, true},
+		{"mismatch", `{"expression":"0 9,17 * * *","cadence":"daily"}`, http.StatusOK, CadenceIrregular, false},
+		{"unknown cadence", `{"expression":"0 2 * * *","cadence":"fortnightly"}`, http.StatusBadRequest, "", false},
+		{"invalid expression", `{"expression":"0 24 * * *","cadence":"daily"}`, http.StatusBadRequest, "", false},
+	}
+	for _, tt := range tests {
+		s, _ := newTestServer(t)
+		rec := serve(s, "POST", "/api/validate/cadence", tt.body)
+		if rec.Code != tt.status {
+			t.Errorf("%s: expected status %d but got %d: %s", tt.name, tt.status, rec.Code, rec.Body.String())
+			continue
+		}
+		if tt.status != http.StatusOK {
+			continue
+		}
+		var response CadenceResponse
+		json.NewDecoder(rec.Body).Decode(&response)
+		if response.Detected != tt.detected || response.Matches != tt.matches {
+			t.Errorf("%s: expected detected=%q matches=%v, got %+v", tt.name, tt.detected, tt.matches, response)
+		}
+	}
+}
+
+func TestCleanExpression(t *testing.T) {
+	tests := []struct {
+		name     string
+		raw      string
+		expected string
+	}{
+		{"Plain", "*/5 * * * *", "*/5 * * * *"},
+		{"Double quoted", `"*/5 * * * *"`, "*/5 * * * *"},
+		{"Single quoted", "'0 9 * * 1-5'", "0 9 * * 1-5"},
+		{"Trailing comment", "0 0 * * * # nightly", "0 0 * * *"},
+		{"Quoted with comment", `"*/5 * * * *" # run backup`, "*/5 * * * *"},
+		{"Comment inside quotes", `"*/5 * * * * # run backup"`, "*/5 * * * *"},
+		{"Surrounding whitespace", "  0 12 * * *  ", "0 12 * * *"},
+		{"Quartz nth weekday kept", "0 0 12 ? * 5#3", "0 0 12 ? * 5#3"},
+		{"Bare minute step", "/5 * * * *", "*/5 * * * *"},
+		{"Bare hour step", "0 /2 * * *", "0 */2 * * *"},
+		{"Bare day-of-month step", "0 0 /10 * *", "0 0 */10 * *"},
+		{"Bare month step", "0 0 1 /3 *", "0 0 1 */3 *"},
+		{"Bare day-of-week step", "0 0 * * /2", "0 0 * * */2"},
+		{"Bare step in list", "0,/20 * * * *", "0,*/20 * * * *"},
+		{"Ranged step kept", "10-50/5 * * * *", "10-50/5 * * * *"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cleanExpression(tt.raw); got != tt.expected {
+				t.Errorf("cleanExpression(%q) = %q, expected %q", tt.raw, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestSlowRequests(t *testing.T) {
+	s := NewServer(nil, testConfig())
+	s.config.SlowRequestThreshold = 10 * time.Millisecond
+	handler := func(delay time.Duration) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) { time.Sleep(delay) }
+	}
+
+	before := testutil.ToFloat64(slowRequestsTotal.WithLabelValues("/test/slow"))
+	s.metricMiddleware("/test/slow", handler(20*time.Millisecond))(httptest.NewRecorder(), httptest.NewRequest("GET", "/test/slow", nil))
+	s.metricMiddleware("/test/slow", handler(0))(httptest.NewRecorder(), httptest.NewRequest("GET", "/test/slow", nil))
+	if got := testutil.ToFloat64(slowRequestsTotal.WithLabelValues("/test/slow")) - before; got != 1 {
+		t.Errorf("Expected 1 slow request, counted %v", got)
+	}
+}
+
+func TestLoadConfigSlowRequestThreshold(t *testing.T) {
+	t.Setenv("SLOW_REQUEST_MS", "1500")
+	if got := loadConfig().SlowRequestThreshold; got != 1500*time.Millisecond {
+		t.Errorf("Expected a 1.5s threshold, got %s", got)
+	}
+	t.Setenv("SLOW_REQUEST_MS", "fast")
+	if got := loadConfig().SlowRequestThreshold; got != 250*time.Millisecond {
+		t.Errorf("Expected the default threshold for an invalid value, got %s", got)
+	}
+}
+
+func TestQuartzToStandard(t *testing.T) {
+	tests := []struct {
+		expression string
+		expected   string
+	}{
+		{"0 0 12 ? * MON", "0 12 * * MON"},
+		{"0 30 9 ? * 2-6", "30 9 * * 1-5"},
+		{"0 0 8 ? * 1,7", "0 8 * * 0,6"},
+		{"0 15 10 1 * ?", "15 10 1 * *"},
+		{"0 0 0 ? * 2/2 *", "0 0 * * 1/2"},
+	}
+	for _, tt := range tests {
+		got, err := quartzToStandard(tt.expression)
+		if err != nil {
+			t.Errorf("quartzToStandard(%q) error: %v", tt.expression, err)
+		} else if got != tt.expected {
+			t.Errorf("quartzToStandard(%q) = %q, expected %q", tt.expression, got, tt.expected)
+		}
+	}
+
+	tests = []struct {
+		expression string
+		expected   string
+	}{
+		{"0 12 * * *", "expected 6 or 7"},
+		{"0 0 12 ? * 8", "out of range"},
+		{"0 0 12 ? * 0", "out of range"},
+		{"0 0 12 L * ?", "no standard cron equivalent"},
+		{"0 0 12 15W * ?", "no standard cron equivalent"},
+		{"0 0 12 ? * 6#3", "no standard cron equivalent"},
+		{"0 0 12 ? * 6L", "no standard cron equivalent"},
+		{"30 0 12 * * ?", `seconds field "30"`},
+		{"*/10 0 12 * * ?", `seconds field "*/10"`},
+		{"0 0 12 * * ? 2030", `year field "2030"`},
+	}
+	for _, tt := range tests {
+		if got, err := quartzToStandard(tt.expression); err == nil || !strings.Contains(err.Error(), tt.expected) {
+			t.Errorf("quartzToStandard(%q) = %q, %v, expected an error mentioning %q", tt.expression, got, err, tt.expected)
+		}
+	}
+}
+
+func TestConvertQuartzStandard(t *testing.T) {
+	s, _ := newTestServer(t)
+
+	rec := serve(s, "POST", "/api/convert", `{"expression":"0 30 9 ? * 2-6","standard":"quartz"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status %d but got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	var response ConvertResponse
+	json.NewDecoder(rec.Body).Decode(&response)
+	if response.StandardExpression != "30 9 * * 1-5" {
+		t.Errorf("Expected standard expression %q, got %q", "30 9 * * 1-5", response.StandardExpression)
+	}
+	if len(response.NextExecutions) == 0 || !strings.HasSuffix(response.NextExecutions[0], "at 09:30:00") {
+		t.Errorf("Expected the first run at 09:30, got %v", response.NextExecutions)
+	}
+
+	for _, body := range []string{
+		`{"expression":"0 0 12 ? * 6#3","standard":"quartz"}`,
+		`{"expression":"15 0 12 ? * MON","standard":"quartz"}`,
+		`{"expression":"0 0 12 * * *","standard":"jenkins"}`,
+	} {
+		if rec := serve(s, "POST", "/api/convert", body); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status %d but got %d", body, http.StatusBadRequest, rec.Code)
+		}
+	}
+}
+
+func TestDescriptionCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	c := newDescriptionCache(2)
+	c.describe("0 0 * * *")
+	c.describe("0 * * * *")
+	// Using the first entry again makes the second the oldest
+	c.describe("0 0 * * *")
+	c.describe("*/5 * * * *")
+
+	if c.len() != 2 {
+		t.Fatalf("Expected 2 cached descriptions, got %d", c.len())
+	}
+	for expression, cached := range map[string]bool{"0 0 * * *": true, "0 * * * *": false, "*/5 * * * *": true} {
+		if _, ok := c.entries[expression]; ok != cached {
+			t.Errorf("Expected %q cached = %v", expression, cached)
+		}
+	}
+	if got, want := c.describe("0 0 * * *"), generateDescription("0 0 * * *"); got != want {
+		t.Errorf("describe() = %q, want %q", got, want)
+	}
+}
+
+func TestDescriptionCacheBounded(t *testing.T) {
+	c := newDescriptionCache(50)
+	var wg sync.WaitGroup
+	for worker := 0; worker < 4; worker++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				c.describe(fmt.Sprintf("%d %d * * *", i%60, worker))
+			}
+		}(worker)
+	}
+	wg.Wait()
+	if c.len() != 50 || len(c.entries) != 50 {
+		t.Errorf("Expected the cache capped at 50, got %d (%d indexed)", c.len(), len(c.entries))
+	}
+}
+
+func TestDistinctExpressionsHandler(t *testing.T) {
+	s, mock := newTestServer(t)
+	mock.ExpectQuery("SELECT expression, COUNT").
+		WillReturnRows(sqlmock.NewRows([]string{"expression", "count"}).AddRow("0 0 * * *", 3).AddRow("*/5 * * * *", 1))
+
+	rec := serve(s, "GET", "/api/expressions/distinct", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status %d but got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	var distinct []DistinctExpression
+	json.NewDecoder(rec.Body).Decode(&distinct)
+	if len(distinct) != 2 || distinct[0].Count != 3 || distinct[0].Description != generateDescription("0 0 * * *") {
+		t.Errorf("Unexpected distinct expressions: %+v", distinct)
+	}
+	if s.descriptions.len() != 2 {
+		t.Errorf("Expected the descriptions cached on the server, got %d entries", s.descriptions.len())
+	}
+}
+
+func TestValidBearerToken(t *testing.T) {
+	tests := []struct {
+		header string
+		want   bool
+	}{
+		{"Bearer secret", true},
+		{"secret", false},
+		{"bearer secret", false},
+		{"Bearer wrong", false},
+		{"Bearer ", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", "/api/expressions", nil)
+		if tt.header != "" {
+			req.Header.Set("Authorization", tt.header)
+		}
+		if got := validBearerToken(req, "secret"); got != tt.want {
+			t.Errorf("validBearerToken(%q) = %v, want %v", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestAuthMiddleware(t *testing.T) {
+	s, _ := newTestServer(t)
+	s.config.APIToken = "secret"
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		token  string
+		status int
+	}{
+		{"missing token", "POST", "/api/convert", "", http.StatusUnauthorized},
+		{"bare token", "POST", "/api/convert", "secret", http.StatusUnauthorized},
+		{"bearer token", "POST", "/api/convert", "Bearer secret", http.StatusOK},
+		{"public route", "GET", "/api/version", "", http.StatusOK},
+		{"unrouted path", "GET", "/api/openapi.json", "", http.StatusUnauthorized},
+		{"probe", "GET", "/healthz", "", http.StatusOK},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(`{"expression":"0 9 * * *"}`))
+		if tt.token != "" {
+			req.Header.Set("Authorization", tt.token)
+		}
+		rec := httptest.NewRecorder()
+		s.handler().ServeHTTP(rec, req)
+		if rec.Code != tt.status {
+			t.Errorf("%s: expected status %d but got %d", tt.name, tt.status, rec.Code)
+		}
+	}
+}
+
+func TestCORSPreflight(t *testing.T) {
+	s, _ := newTestServer(t)
+	s.config.APIToken = "secret"
+	s.config.CORSAllowedOrigins = []string{"https://app.example.com"}
+
+	preflight := func(origin string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("OPTIONS", "/api/convert", nil)
+		req.Header.Set("Origin", origin)
+		req.Header.Set("Access-Control-Request-Method", "POST")
+		rec := httptest.NewRecorder()
+		s.handler().ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := preflight("https://app.example.com")
+	if rec.Code != http.StatusNoContent || rec.Header().Get("Access-Control-Allow-Origin") != "https://app.example.com" {
+		t.Errorf("Expected an allowed preflight, got %d %v", rec.Code, rec.Header())
+	}
+	if rec := preflight("https://evil.example.com"); rec.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Errorf("Expected no CORS headers for an unlisted origin, got %v", rec.Header())
+	}
+}
+
+func TestReadyz(t *testing.T) {
+	mockDB, mock, err := sqlmock.New(sqlmock.MonitorPingsOption(true))
+	if err != nil {
+		t.Fatalf("Failed to create sqlmock: %v", err)
+	}
+	defer mockDB.Close()
+	s := NewServer(newStore(mockDB, nil), testConfig())
+
+	mock.ExpectPing()
+	if rec := serve(s, "GET", "/readyz", ""); rec.Code != http.StatusOK {
+		t.Errorf("Expected status %d but got %d", http.StatusOK, rec.Code)
+	}
+
+	mock.ExpectPing().WillReturnError(errors.New("dial tcp 10.0.0.5:5432: connection refused"))
+	rec := serve(s, "GET", "/readyz", "")
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status %d but got %d", http.StatusServiceUnavailable, rec.Code)
+	}
+	if strings.Contains(rec.Body.String(), "10.0.0.5") {
+		t.Errorf("Expected no database error in the public response, got %s", rec.Body.String())
+	}
+}
+
+func TestNormalizeExpression(t *testing.T) {
+	tests := []struct {
+		expression string
+		expected   string
+	}{
+		{"0 9 * * mon", "0 9 * * MON"},
+		{"*/1 ? * * *", "* * * * *"},
+		{"30,0,15,0 9-9 * jan-jan *", "0,15,30 9 * JAN *"},
+		{"0 0 1-5/1 * sun,sat,mon", "0 0 1-5 * SUN,MON,SAT"},
+		{"0 9 1,* * *", "0 9 * * *"},
+		{"@every 5m", "@every 5m"},
+		{"@DAILY", "@daily"},
+		{"@midnight", "@daily"},
+	}
+	for _, tt := range tests {
+		if got := normalizeExpression(tt.expression); got != tt.expected {
+			t.Errorf("normalizeExpression(%q) = %q, expected %q", tt.expression, got, tt.expected)
+		}
+	}
+}
+
+func TestNormalizeHandler(t *testing.T) {
+	s, _ := newTestServer(t)
+
+	rec := serve(s, "POST", "/api/normalize", `{"expression":"\"0 9 * * mon-fri\" # weekdays"}`)
+	var response NormalizeResponse
+	json.NewDecoder(rec.Body).Decode(&response)
+	if rec.Code != http.StatusOK || response.Normalized != "0 9 * * MON-FRI" || !response.Changed {
+		t.Errorf("Expected the canonical form, got %d %+v", rec.Code, response)
+	}
+
+	rec = serve(s, "POST", "/api/normalize", `{"expression":"0 9 * * 1"}`)
+	response = NormalizeResponse{}
+	json.NewDecoder(rec.Body).Decode(&response)
+	if response.Changed {
+		t.Errorf("Expected a canonical expression unchanged, got %+v", response)
+	}
+
+	if rec := serve(s, "POST", "/api/normalize", `{"expression":"0 9 * * 8"}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d for an invalid expression but got %d", http.StatusBadRequest, rec.Code)
+	}
+}
+
+// timeNear matches a time argument within a second of want
+type timeNear struct{ want time.Time }
+
+func (m timeNear) Match(v driver.Value) bool {
+	t, ok := v.(time.Time)
+	return ok && t.Sub(m.want).Abs() < time.Second
+}
+
+func TestPurgeSoftDeleted(t *testing.T) {
+	s, mock := newTestServer(t)
+	s.config.SoftDeleteRetention = 7 * 24 * time.Hour
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM cron_expressions")).
+		WithArgs(timeNear{time.Now().Add(-7 * 24 * time.Hour)}).
+		WillReturnResult(sqlmock.NewResult(0, 4))
+
+	purged, err := s.purgeSoftDeleted()
+	if err != nil || purged != 4 {
+		t.Errorf("Expected 4 rows purged, got %d, %v", purged, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestLoadConfigSoftDeleteRetention(t *testing.T) {
+	t.Setenv("SOFT_DELETE_RETENTION_DAYS", "7")
+	if got := loadConfig().SoftDeleteRetention; got != 7*24*time.Hour {
+		t.Errorf("Expected 7 days, got %s", got)
+	}
+	t.Setenv("SOFT_DELETE_RETENTION_DAYS", "-1")
+	if got := loadConfig().SoftDeleteRetention; got != 30*24*time.Hour {
+		t.Errorf("Expected the 30 day default for an invalid value, got %s", got)
+	}
+}
+
+func TestRandomExpression(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for _, cadence := range randomCadences {
+		for i := 0; i < 50; i++ {
+			expression, err := randomExpression(rng, cadence)
+			if err != nil {
+				t.Fatalf("randomExpression(%q) error: %v", cadence, err)
+			}
+			schedule, err := parseExpression(expression)
+			if err != nil {
+				t.Fatalf("randomExpression(%q) = %q, which doesn't parse: %v", cadence, expression, err)
+			}
+			if got := classifyCadence(schedule); got != cadence {
+				t.Errorf("randomExpression(%q) = %q, classified as %q", cadence, expression, got)
+			}
+		}
+	}
+
+	if _, err := randomExpression(rng, CadenceIrregular); err == nil {
+		t.Error("Expected an error for a cadence that can't be generated")
+	}
+}
+
+func TestRandomExpressionHandler(t *testing.T) {
+	s, _ := newTestServer(t)
+
+	rec := serve(s, "GET", "/api/random?cadence=Daily", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status %d but got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	var response RandomResponse
+	json.NewDecoder(rec.Body).Decode(&response)
+	schedule, err := parseExpression(response.Expression)
+	if err != nil || response.Cadence != CadenceDaily || classifyCadence(schedule) != CadenceDaily || response.Description == "" {
+		t.Errorf("Expected a described daily expression, got %+v", response)
+	}
+
+	rec = serve(s, "GET", "/api/random", "")
+	response = RandomResponse{}
+	json.NewDecoder(rec.Body).Decode(&response)
+	known := false
+	for _, cadence := range randomCadences {
+		known = known || response.Cadence == cadence
+	}
+	if rec.Code != http.StatusOK || !known {
+		t.Errorf("Expected a generated cadence without one requested, got %d %+v", rec.Code, response)
+	}
+
+	if rec := serve(s, "GET", "/api/random?cadence=fortnightly", ""); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d for an unknown cadence, got %d", http.StatusBadRequest, rec.Code)
+	}
+}
+
+func TestResolveDescriptor(t *testing.T) {
+	tests := []struct {
+		expression string
+		canonical  string
+		equivalent string
+		ok         bool
+	}{
+		{"@daily", "@daily", "0 0 * * *", true},
+		{"@midnight", "@daily", "0 0 * * *", true},
+		{" @Annually ", "@yearly", "0 0 1 1 *", true},
+		{"@weekly", "@weekly", "0 0 * * 0", true},
+		{"@reboot", "", "", false},
+		{"0 0 * * *", "", "", false},
+	}
+	for _, tt := range tests {
+		canonical, equivalent, ok := resolveDescriptor(tt.expression)
+		if ok != tt.ok || (ok && (canonical != tt.canonical || equivalent != tt.equivalent)) {
+			t.Errorf("resolveDescriptor(%q) = (%q, %q, %v), expected (%q, %q, %v)", tt.expression, canonical, equivalent, ok, tt.canonical, tt.equivalent, tt.ok)
+		}
+	}
+}
+
+func TestConvertDescriptorAlias(t *testing.T) {
+	s, _ := newTestServer(t)
+
+	rec := serve(s, "POST", "/api/convert", `{"expression":"@annually"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status %d but got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	var response ConvertResponse
+	json.NewDecoder(rec.Body).Decode(&response)
+	if response.Descriptor != "@yearly" || response.StandardExpression != "0 0 1 1 *" || response.Description == "" {
+		t.Errorf("Expected @annually to resolve to @yearly, got %+v", response)
+	}
+
+	if rec := serve(s, "POST", "/api/convert", `{"expression":"@fortnightly"}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d for an unknown descriptor but got %d", http.StatusBadRequest, rec.Code)
+	}
+}
+
+func TestExpressionCount(t *testing.T) {
+	t.Run("filters apply to the count", func(t *testing.T) {
+		s, mock := newTestServer(t)
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM cron_expressions e WHERE e.deleted_at IS NULL AND e.category_id = $1")).
+			WithArgs("3").
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+
+		rec := serve(s, "GET", "/api/expressions?countOnly=true&category=3", "")
+		if rec.Code != http.StatusOK || strings.TrimSpace(rec.Body.String()) != `{"total":3}` {
+			t.Errorf("Expected {\"total\":3}, got %d %s", rec.Code, rec.Body.String())
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("Unfulfilled expectations: %v", err)
+		}
+	})
+
+	t.Run("invalid filter", func(t *testing.T) {
+		s, mock := newTestServer(t)
+		if rec := serve(s, "GET", "/api/expressions?countOnly=true&category=abc", ""); rec.Code != http.StatusBadRequest {
+			t.Errorf("Expected status %d but got %d", http.StatusBadRequest, rec.Code)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("Unexpected queries: %v", err)
+		}
+	})
+
+	t.Run("database error", func(t *testing.T) {
+		s, mock := newTestServer(t)
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*)")).WillReturnError(errors.New("connection reset"))
+		if rec := serve(s, "GET", "/api/expressions?countOnly=true", ""); rec.Code != http.StatusInternalServerError {
+			t.Errorf("Expected status %d but got %d", http.StatusInternalServerError, rec.Code)
+		}
+	})
+}
+
+func TestDBPassword(t *testing.T) {
+	secret := t.TempDir() + "/db-password"
+	if err := os.WriteFile(secret, []byte("from-file\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		file     string
+		env      string
+		expected string
+	}{
+		{"file wins over env", secret, "from-env", "from-file"},
+		{"env without file", "", "from-env", "from-env"},
+		{"neither set", "", "", ""},
+	}
+	for _, tt := range tests {
+		t.Setenv("DB_PASSWORD_FILE", tt.file)
+		t.Setenv("DB_PASSWORD", tt.env)
+		got, err := dbPassword()
+		if err != nil || got != tt.expected {
+			t.Errorf("%s: dbPassword() = (%q, %v), expected %q", tt.name, got, err, tt.expected)
+		}
+	}
+
+	t.Setenv("DB_PASSWORD_FILE", secret+".missing")
+	t.Setenv("DB_PASSWORD", "from-env")
+	got, err := dbPassword()
+	if err == nil || got != "" {
+		t.Errorf("Expected an error for a missing password file, got (%q, %v)", got, err)
+	} else if strings.Contains(err.Error(), "from-env") {
+		t.Errorf("Error leaks the password: %v", err)
+	}
+}
+
+func TestHeatmapHandler(t *testing.T) {
+	s, _ := newTestServer(t)
+
+	rec := serve(s, "POST", "/api/schedule/heatmap", `{"expression":"0 9 * * 1-5"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status %d but got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	var response HeatmapResponse
+	json.NewDecoder(rec.Body).Decode(&response)
+	if response.Total != 5 || response.Truncated || len(response.Days) != 7 || response.Days[0] != "Sunday" {
+		t.Fatalf("Expected 5 runs over a week starting Sunday, got %+v", response)
+	}
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		for h := 0; h < 24; h++ {
+			expected := 0
+			if h == 9 && d != time.Sunday && d != time.Saturday {
+				expected = 1
+			}
+			if response.Grid[d][h] != expected {
+				t.Errorf("Grid[%s][%d] = %d, expected %d", d, h, response.Grid[d][h], expected)
+			}
+		}
+	}
+
+	// Every minute over the longest window stays under the walk cap
+	rec = serve(s, "POST", "/api/schedule/heatmap", `{"expression":"* * * * *","days":28}`)
+	response = HeatmapResponse{}
+	json.NewDecoder(rec.Body).Decode(&response)
+	if response.Total != 28*24*60 || response.Truncated {
+		t.Errorf("Expected %d untruncated runs, got %d (truncated=%v)", 28*24*60, response.Total, response.Truncated)
+	}
+}
+
+func TestHeatmapHandlerRejects(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"too many days", `{"expression":"0 9 * * *","days":29}`},
+		{"negative days", `{"expression":"0 9 * * *","days":-1}`},
+		{"invalid expression", `{"expression":"0 9 * * 8"}`},
+	}
+	for _, tt := range tests {
+		s, _ := newTestServer(t)
+		if rec := serve(s, "POST", "/api/schedule/heatmap", tt.body); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status %d but got %d", tt.name, http.StatusBadRequest, rec.Code)
+		}
+	}
+}
+
+func TestParseEnabledConverters(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected map[string]bool
+	}{
+		{"", map[string]bool{ConverterJenkins: true, ConverterAWS: true, ConverterQuartz: true, ConverterKubernetes: true, ConverterRRule: true, ConverterSystemd: true}},
+		{"Quartz, systemd", map[string]bool{ConverterQuartz: true, ConverterSystemd: true}},
+		{"jenkins,cobol", map[string]bool{ConverterJenkins: true}},
+	}
+	for _, tt := range tests {
+		if got := parseEnabledConverters(tt.value); !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("parseEnabledConverters(%q) = %v, expected %v", tt.value, got, tt.expected)
+		}
+	}
+}
+
+func TestDisabledConverters(t *testing.T) {
+	enabled, _ := newTestServer(t)
+	disabled, _ := newTestServer(t)
+	disabled.config.EnabledConverters = parseEnabledConverters("jenkins")
+
+	body := `{"expression":"0 9 * * 1-5"}`
+	if rec := serve(enabled, "POST", "/api/convert/systemd", body); rec.Code != http.StatusOK {
+		t.Errorf("Expected status %d from an enabled converter but got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	if rec := serve(disabled, "POST", "/api/convert/systemd", body); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d from an unregistered converter but got %d", http.StatusNotFound, rec.Code)
+	}
+
+	quartz := `{"expression":"0 30 9 ? * 2-6","standard":"quartz"}`
+	if rec := serve(enabled, "POST", "/api/convert", quartz); rec.Code != http.StatusOK {
+		t.Errorf("Expected status %d for Quartz input but got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	if rec := serve(disabled, "POST", "/api/convert", quartz); rec.Code != http.StatusNotImplemented {
+		t.Errorf("Expected status %d for disabled Quartz input but got %d", http.StatusNotImplemented, rec.Code)
+	}
+}
+
+func TestDetectHandler(t *testing.T) {
+	tests := []struct {
+		expression string
+		standards  []string
+	}{
+		{"0 9 * * *", []string{StandardUnix}},
+		{"0 0 9 * * *", []string{StandardSeconds, StandardQuartz}},
+		{"30 0 9 * * *", []string{StandardSeconds}},
+		{"0 0 12 ? * MON", []string{StandardSeconds, StandardQuartz}},
+		{"@midnight", []string{StandardDescriptor}},
+		{"@fortnightly", []string{}},
+		{"0 0 12 ? * 1#2", []string{}},
+		{"61 * *", []string{}},
+	}
+	for _, tt := range tests {
+		s, _ := newTestServer(t)
+		body, _ := json.Marshal(DetectRequest{Expression: tt.expression})
+		rec := serve(s, "POST", "/api/detect", string(body))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%q: expected status %d but got %d: %s", tt.expression, http.StatusOK, rec.Code, rec.Body.String())
+		}
+		var response DetectResponse
+		json.NewDecoder(rec.Body).Decode(&response)
+		if !reflect.DeepEqual(response.Standards, tt.standards) {
+			t.Errorf("%q: expected standards %v, got %v", tt.expression, tt.standards, response.Standards)
+		}
+		// Every standard tried reports either a description or why it failed
+		for _, result := range response.Results {
+			if result.Valid == (result.Description == "") || result.Valid == (result.Error != "") {
+				t.Errorf("%q: inconsistent result %+v", tt.expression, result)
+			}
+		}
+	}
+}
+
+func TestRenderCommand(t *testing.T) {
+	exp := CronExpression{ID: 7, Name: "x; rm -rf ~", Expression: "0 0 * * *", Description: "it's 100% safe\nnext"}
+	got := renderCommand("/usr/local/bin/run {id} {name} {description}", exp)
+	expected := `/usr/local/bin/run '7' 'x; rm -rf ~' 'it'\''s 100\% safe next'`
+	if got != expected {
+		t.Errorf("renderCommand() = %q, expected %q", got, expected)
+	}
+
+	// Check the quoting with a real shell, after undoing cron's \% escape
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("no sh available")
+	}
+	command := strings.ReplaceAll(renderCommand("printf '%s|' {name} {description}", exp), `\%`, "%")
+	out, err := exec.Command(sh, "-c", command).Output()
+	if err != nil {
+		t.Fatalf("sh -c %q: %v", command, err)
+	}
+	if string(out) != "x; rm -rf ~|it's 100% safe next|" {
+		t.Errorf("Expected each value as one argument, got %q", out)
+	}
+}
+
+func TestExportCrontabHandler(t *testing.T) {
+	s, mock := newTestServer(t)
+	now := time.Now()
+	mock.ExpectQuery("SELECT .* FROM cron_expressions e").
+		WillReturnRows(sqlmock.NewRows(expressionRowColumns).
+			AddRow(1, "Nightly", "0 0 * * *", "", nil, true, "", "{}", "dev", now, now, now).
+			AddRow(2, "x; rm -rf ~", "*/5 * * * *", "", nil, false, "", "{}", "dev", now, now, now))
+
+	rec := serve(s, "GET", "/api/crontab/export?command_template=/opt/run+{name}", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status %d but got %d", http.StatusOK, rec.Code)
+	}
+	lines := strings.Split(strings.TrimSpace(rec.Body.String()), "\n")
+	expected := []string{
+		"0 0 * * * /opt/run 'Nightly'",
+		"# paused: */5 * * * * /opt/run 'x; rm -rf ~'",
+	}
+	if len(lines) != 3 || lines[1] != expected[0] || lines[2] != expected[1] {
+		t.Errorf("Expected quoted entries %q, got %q", expected, lines)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+
+	s, mock = newTestServer(t)
+	mock.ExpectQuery("SELECT .* FROM cron_expressions e").WillReturnError(errors.New("connection reset"))
+	if rec := serve(s, "GET", "/api/crontab/export", ""); rec.Code != http.StatusInternalServerError {
+		t.Errorf("Expected status %d for a database error but got %d", http.StatusInternalServerError, rec.Code)
+	}
+}
+
+func TestParseYearField(t *testing.T) {
+	tests := []struct {
+		field    string
+		expected []int
+	}{
+		{"2026", []int{2026}},
+		{"2026,2024", []int{2024, 2026}},
+		{"2025-2027", []int{2025, 2026, 2027}},
+		{"2090/4", []int{2090, 2094, 2098}},
+		{"2024-2030/3", []int{2024, 2027, 2030}},
+	}
+	for _, tt := range tests {
+		got, err := parseYearField(tt.field)
+		if err != nil || !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("parseYearField(%q) = (%v, %v), expected %v", tt.field, got, err, tt.expected)
+		}
+	}
+	if got, _ := parseYearField("*"); len(got) != maxYear-minYear+1 {
+		t.Errorf("Expected * to match every year, got %d years", len(got))
+	}
+
+	for _, field := range []string{"1969", "2100", "2027-2025", "2025/0", "next"} {
+		if _, err := parseYearField(field); err == nil {
+			t.Errorf("parseYearField(%q) expected an error", field)
+		}
+	}
+}
+
+func TestYearScheduleNext(t *testing.T) {
+	base, _ := parseExpression("0 0 1 1 *")
+	schedule, err := withYears(base, "2025,2027")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	from := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	expected := []time.Time{
+		time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+	for _, want := range expected {
+		from = schedule.Next(from)
+		if !from.Equal(want) {
+			t.Fatalf("Expected next run %v, got %v", want, from)
+		}
+	}
+	if next := schedule.Next(from); !next.IsZero() {
+		t.Errorf("Expected no runs after the last year, got %v", next)
+	}
+}
+
+func TestConvertUnixYear(t *testing.T) {
+	s, _ := newTestServer(t)
+
+	rec := serve(s, "POST", "/api/convert", `{"expression":"0 0 1 1 * 2090-2092","standard":"unix-year"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status %d but got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	var response ConvertResponse
+	json.NewDecoder(rec.Body).Decode(&response)
+	if len(response.NextExecutions) != 3 || !strings.Contains(response.NextExecutions[0], "Jan 1 2090") || !strings.Contains(response.NextExecutions[2], "Jan 1 2092") {
+		t.Errorf("Expected one run on Jan 1 of each year 2090-2092, got %v", response.NextExecutions)
+	}
+	if !strings.Contains(response.Description, "in years 2090 to 2092") {
+		t.Errorf("Expected the year range in the description, got %q", response.Description)
+	}
+
+	for _, body := range []string{
+		`{"expression":"0 0 1 1 * 2100","standard":"unix-year"}`,
+		`{"expression":"0 0 1 1 *","standard":"unix-year"}`,
+	} {
+		if rec := serve(s, "POST", "/api/convert", body); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status %d but got %d", body, http.StatusBadRequest, rec.Code)
+		}
+	}
+}
+
+func TestResyncMetricsHandler(t *testing.T) {
+	t.Run("disabled without token", func(t *testing.T) {
+		s, _ := newTestServer(t)
+		s.config.APIToken = ""
+
+		rec := serve(s, "POST", "/api/admin/metrics/resync", "")
+		if rec.Code != http.StatusForbidden {
+			t.Fatalf("Expected status %d but got %d", http.StatusForbidden, rec.Code)
+		}
+	})
+
+	t.Run("resyncs gauge", func(t *testing.T) {
+		s, mock := newTestServer(t)
+		s.config.APIToken = "secret"
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM cron_expressions")).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(42))
+
+		req := httptest.NewRequest("POST", "/api/admin/metrics/resync", nil)
+		req.Header.Set("Authorization", "Bearer secret")
+		rec := httptest.NewRecorder()
+		s.handler().ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected status %d but got %d", http.StatusOK, rec.Code)
+		}
+		if got := testutil.ToFloat64(cronExpressionsStored); got != 42 {
+			t.Errorf("Expected gauge 42 but got %v", got)
+		}
+	})
+}
+
+func TestDSTWarning(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("timezone data unavailable: %v", err)
+	}
+	// New York springs forward at 02:00 on Mar 8 2026 and falls back at
+	// 02:00 on Nov 1 2026
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("transitions", func(t *testing.T) {
+		// The search narrows each change down to within a second
+		near := func(at, want time.Time) bool {
+			return !at.Before(want) && at.Before(want.Add(time.Second))
+		}
+		got := dstTransitions(loc, now, now.Add(dstLookahead))
+		if len(got) != 2 {
+			t.Fatalf("Expected 2 transitions, got %d: %v", len(got), got)
+		}
+		spring := time.Date(2026, 3, 8, 7, 0, 0, 0, time.UTC)
+		fall := time.Date(2026, 11, 1, 6, 0, 0, 0, time.UTC)
+		if !near(got[0].at, spring) || got[0].oldOffset != -5*3600 || got[0].newOffset != -4*3600 {
+			t.Errorf("Expected spring-forward at %v from -5h to -4h, got %+v", spring, got[0])
+		}
+		if !near(got[1].at, fall) || got[1].oldOffset != -4*3600 || got[1].newOffset != -5*3600 {
+			t.Errorf("Expected fall-back at %v from -4h to -5h, got %+v", fall, got[1])
+		}
+	})
+
+	tests := []struct {
+		name       string
+		expression string
+		warning    bool
+		message    string
+	}{
+		{
+			name:       "spring forward skips",
+			expression: "30 2 * * *",
+			warning:    true,
+			message:    "Runs at 02:30 are skipped on Sun Mar 8 2026 when America/New_York springs forward",
+		},
+		{
+			name:       "fall back repeats",
+			expression: "30 1 * * *",
+			warning:    true,
+			message:    "Runs at 01:30 happen twice on Sun Nov 1 2026 when America/New_York falls back",
+		},
+		{
+			name:       "day-of-week outside the transitions",
+			expression: "30 2 * * 1-5",
+		},
+		{
+			name:       "clear of the transitions",
+			expression: "0 12 * * *",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule, err := parseExpression(tt.expression)
+			if err != nil {
+				t.Fatalf("parseExpression(%q) error: %v", tt.expression, err)
+			}
+			warning, message := dstWarning(schedule, loc, now)
+			if warning != tt.warning || message != tt.message {
+				t.Errorf("dstWarning(%q) = (%v, %q), expected (%v, %q)", tt.expression, warning, message, tt.warning, tt.message)
+			}
+		})
+	}
+
+	t.Run("zone without DST", func(t *testing.T) {
+		schedule, _ := parseExpression("30 2 * * *")
+		if warning, message := dstWarning(schedule, time.UTC, now); warning || message != "" {
+			t.Errorf("Expected no warning in UTC, got (%v, %q)", warning, message)
+		}
+	})
+}
+
+func TestConvertDSTWarning(t *testing.T) {
+	if _, err := time.LoadLocation("America/New_York"); err != nil {
+		t.Skipf("timezone data unavailable: %v", err)
+	}
+	s, _ := newTestServer(t)
+
+	tests := []struct {
+		name    string
+		body    string
+		present bool
+		warning bool
+	}{
+		{name: "in a gap", body: `{"expression":"30 2 * * *","timezone":"America/New_York"}`, present: true, warning: true},
+		{name: "no clock change", body: `{"expression":"30 2 * * *","timezone":"UTC"}`, present: true},
+		{name: "no timezone", body: `{"expression":"30 2 * * *"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serve(s, "POST", "/api/convert", tt.body)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("Expected status %d but got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+			}
+			var response ConvertResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if (response.DSTWarning != nil) != tt.present {
+				t.Fatalf("Expected dstWarning present=%v, got %v", tt.present, response.DSTWarning)
+			}
+			if tt.present && *response.DSTWarning != tt.warning {
+				t.Errorf("Expected dstWarning %v, got %v", tt.warning, *response.DSTWarning)
+			}
+			if (response.DSTMessage != "") != tt.warning {
+				t.Errorf("Unexpected dstMessage %q", response.DSTMessage)
+			}
+		})
+	}
+}
+
+func TestRecoverMiddleware(t *testing.T) {
+	handler := requestIDMiddleware(recoverMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/panic" {
+			var names []string
+			_ = names[3]
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})))
+
+	t.Run("panic", func(t *testing.T) {
+		before := testutil.ToFloat64(panicsTotal)
+		req := httptest.NewRequest("GET", "/panic", nil)
+		req.Header.Set("X-Request-ID", "req-123")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		var response ErrorResponse
+		json.NewDecoder(rec.Body).Decode(&response)
+		if rec.Code != http.StatusInternalServerError {
+			t.Fatalf("Expected status %d but got %d", http.StatusInternalServerError, rec.Code)
+		}
+		if response.Error.Code != ErrInternal || response.Error.RequestID != "req-123" || rec.Header().Get("X-Request-ID") != "req-123" {
+			t.Errorf("Expected request ID req-123 in the error, got %v", response)
+		}
+		if got := testutil.ToFloat64(panicsTotal); got != before+1 {
+			t.Errorf("Expected panics_total to increase by 1, went from %v to %v", before, got)
+		}
+	})
+
+	t.Run("no panic", func(t *testing.T) {
+		before := testutil.ToFloat64(panicsTotal)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("GET", "/ok", nil))
+		if rec.Code != http.StatusNoContent {
+			t.Errorf("Expected status %d but got %d", http.StatusNoContent, rec.Code)
+		}
+		if got := testutil.ToFloat64(panicsTotal); got != before {
+			t.Errorf("Expected panics_total unchanged, went from %v to %v", before, got)
+		}
+	})
+
+	t.Run("abort handler", func(t *testing.T) {
+		abort := recoverMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			panic(http.ErrAbortHandler)
+		}))
+		defer func() {
+			if recovered := recover(); recovered != http.ErrAbortHandler {
+				t.Errorf("Expected http.ErrAbortHandler to propagate, got %v", recovered)
+			}
+		}()
+		abort.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	})
+}
+
+func TestSetExpressionEnabled(t *testing.T) {
+	update := regexp.QuoteMeta("UPDATE cron_expressions e")
+	now := time.Now()
+	row := func(enabled bool) *sqlmock.Rows {
+		return sqlmock.NewRows(expressionRowColumns).
+			AddRow(5, "Nightly", "0 0 * * *", "", nil, enabled, "", "{}", "dev", now, now, now)
+	}
+
+	tests := []struct {
+		name    string
+		path    string
+		enabled bool
+		setup   func(mock sqlmock.Sqlmock)
+		status  int
+	}{
+		{
+			name: "pause", path: "/api/expressions/5/pause", enabled: false,
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(update).WithArgs(false, sqlmock.AnyArg(), "5").WillReturnRows(row(false))
+			},
+			status: http.StatusOK,
+		},
+		{
+			name: "resume", path: "/api/expressions/5/resume", enabled: true,
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(update).WithArgs(true, sqlmock.AnyArg(), "5").WillReturnRows(row(true))
+			},
+			status: http.StatusOK,
+		},
+		{
+			// Pausing is idempotent: an already paused expression stays paused
+			name: "already paused", path: "/api/expressions/5/pause", enabled: false,
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(update).WithArgs(false, sqlmock.AnyArg(), "5").WillReturnRows(row(false))
+			},
+			status: http.StatusOK,
+		},
+		{
+			name: "already resumed", path: "/api/expressions/5/resume", enabled: true,
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(update).WithArgs(true, sqlmock.AnyArg(), "5").WillReturnRows(row(true))
+			},
+			status: http.StatusOK,
+		},
+		{
+			name: "not found", path: "/api/expressions/9/pause",
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(update).WithArgs(false, sqlmock.AnyArg(), "9").WillReturnError(sql.ErrNoRows)
+			},
+			status: http.StatusNotFound,
+		},
+		{
+			name: "database error", path: "/api/expressions/5/resume",
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(update).WillReturnError(errors.New("connection reset"))
+			},
+			status: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, mock := newTestServer(t)
+			tt.setup(mock)
+
+			rec := serve(s, "POST", tt.path, "")
+			if rec.Code != tt.status {
+				t.Fatalf("Expected status %d but got %d: %s", tt.status, rec.Code, rec.Body.String())
+			}
+			if tt.status == http.StatusOK {
+				var exp CronExpression
+				json.NewDecoder(rec.Body).Decode(&exp)
+				if exp.ID != 5 || exp.Enabled != tt.enabled {
+					t.Errorf("Expected expression 5 with enabled=%v, got %+v", tt.enabled, exp)
+				}
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("Unfulfilled expectations: %v", err)
+			}
+		})
+	}
+}
+
+// appMetricNames returns the names of the registered metrics that aren't Go
+// runtime or process metrics
+func appMetricNames() ([]string, error) {
+	families, err := prometheus.DefaultGatherer.Gather()
+	if err != nil {
+		return nil, err
+	}
+	names := []string{}
+	for _, family := range families {
+		name := family.GetName()
+		if !strings.HasPrefix(name, "go_") && !strings.HasPrefix(name, "process_") {
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
+
+func TestMetricsNamespace(t *testing.T) {
+	// The namespace is read in init, so check it in a fresh process
+	cmd := exec.Command(os.Args[0], "-test.run=TestMetricsNamespaceHelper")
+	cmd.Env = append(os.Environ(), "GO_WANT_HELPER_PROCESS=1", "METRICS_NAMESPACE=cronops")
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Errorf("Expected namespaced metrics, got %v: %s", err, output)
+	}
+
+	if os.Getenv("METRICS_NAMESPACE") != "" {
+		t.Skip("METRICS_NAMESPACE is set for this process")
+	}
+	names, err := appMetricNames()
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, name := range names {
+		found = found || name == "panics_total"
+	}
+	if !found {
+		t.Errorf("Expected unprefixed metric names without METRICS_NAMESPACE, got %v", names)
+	}
+}
+
+// TestMetricsNamespaceHelper runs in the process started by TestMetricsNamespace
+func TestMetricsNamespaceHelper(t *testing.T) {
+	if os.Getenv("GO_WANT_HELPER_PROCESS") != "1" {
+		return
+	}
+	prefix := os.Getenv("METRICS_NAMESPACE") + "_"
+	names, err := appMetricNames()
+	if err != nil || len(names) == 0 {
+		fmt.Fprintf(os.Stderr, "gathering metrics: %v %v\n", names, err)
+		os.Exit(1)
+	}
+	for _, name := range names {
+		if !strings.HasPrefix(name, prefix) {
+			fmt.Fprintf(os.Stderr, "metric %s is missing prefix %s\n", name, prefix)
+			os.Exit(1)
+		}
+	}
+
+	// Go runtime metrics keep their standard names
+	families, _ := prometheus.DefaultGatherer.Gather()
+	for _, family := range families {
+		if strings.HasPrefix(family.GetName(), prefix+"go_") {
+			fmt.Fprintf(os.Stderr, "runtime metric %s was prefixed\n", family.GetName())
+			os.Exit(1)
+		}
+	}
+	os.Exit(0)
+}
+
+func TestPartialConvertHandler(t *testing.T) {
+	tests := []struct {
+		input      string
+		expression string
+		defaulted  []string
+	}{
+		{"0 9", "0 9 * * *", []string{"day-of-month", "month", "day-of-week"}},
+		{"*/15", "*/15 * * * *", []string{"hour", "day-of-month", "month", "day-of-week"}},
+		{"", "* * * * *", []string{"minute", "hour", "day-of-month", "month", "day-of-week"}},
+		{"0 9 * * 1-5", "0 9 * * 1-5", []string{}},
+	}
+	for _, tt := range tests {
+		s, _ := newTestServer(t)
+		body, _ := json.Marshal(ConvertRequest{Expression: tt.input})
+		rec := serve(s, "POST", "/api/convert/partial", string(body))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%q: expected status %d but got %d: %s", tt.input, http.StatusOK, rec.Code, rec.Body.String())
+		}
+		var response PartialConvertResponse
+		json.NewDecoder(rec.Body).Decode(&response)
+		if response.Expression != tt.expression || !reflect.DeepEqual(response.DefaultedFields, tt.defaulted) {
+			t.Errorf("%q: expected %q defaulting %v, got %q defaulting %v", tt.input, tt.expression, tt.defaulted, response.Expression, response.DefaultedFields)
+		}
+		if response.Description == "" || len(response.NextExecutions) != 5 {
+			t.Errorf("%q: expected a description and 5 runs, got %+v", tt.input, response)
+		}
+	}
+}
+
+func TestPartialConvertHandlerRejects(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"out of range", `{"expression":"0 25"}`},
+		{"too many fields", `{"expression":"0 0 9 * * 1 2026"}`},
+	}
+	for _, tt := range tests {
+		s, _ := newTestServer(t)
+		if rec := serve(s, "POST", "/api/convert/partial", tt.body); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status %d but got %d", tt.name, http.StatusBadRequest, rec.Code)
+		}
+	}
+}
+
+func TestValidateAllHandler(t *testing.T) {
+	s, mock := newTestServer(t)
+	s.config.APIToken = "secret"
+	now := time.Now()
+	mock.ExpectQuery("SELECT .* FROM cron_expressions e").
+		WillReturnRows(sqlmock.NewRows(expressionRowColumns).
+			AddRow(7, "Hourly", "0 * * * *", "", nil, true, "", "{}", "dev", now, now, nil).
+			AddRow(8, "Legacy", "0 25 * * *", "", nil, true, "", "{}", "dev", now, now, nil))
+	mock.ExpectExec(regexp.QuoteMeta("SET last_validated_at = $1 WHERE id = ANY($2)")).
+		WithArgs(sqlmock.AnyArg(), pq.Array([]int64{7})).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	req := httptest.NewRequest("POST", "/api/admin/validate-all", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	rec := httptest.NewRecorder()
+	s.handler().ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status %d but got %d", http.StatusOK, rec.Code)
+	}
+
+	var report ValidationReport
+	if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil {
+		t.Fatalf("Failed to decode report: %v", err)
+	}
+	if report.Checked != 2 || len(report.Invalid) != 1 || report.Invalid[0].ID != 8 {
+		t.Errorf("Unexpected report: %+v", report)
+	}
+	if got := testutil.ToFloat64(invalidStoredExpressions); got != 1 {
+		t.Errorf("Expected gauge 1 but got %v", got)
+	}
+}
+
+func TestExpressionListSort(t *testing.T) {
+	s, mock := newTestServer(t)
+	validated := time.Now()
+	mock.ExpectQuery(regexp.QuoteMeta("ORDER BY e.last_validated_at ASC NULLS FIRST")).
+		WillReturnRows(sqlmock.NewRows(expressionRowColumns).
+			AddRow(2, "Legacy", "0 3 * * *", "", nil, true, "", "{}", "dev", validated, validated, nil).
+			AddRow(1, "Nightly", "0 0 * * *", "", nil, true, "", "{}", "dev", validated, validated, validated))
+
+	rec := serve(s, "GET", "/api/expressions?sort=last_validated_at", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status %d but got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	var expressions []CronExpression
+	json.Unmarshal(rec.Body.Bytes(), &expressions)
+	if len(expressions) != 2 || expressions[0].LastValidatedAt != nil || expressions[1].LastValidatedAt == nil {
+		t.Errorf("Unexpected expressions: %+v", expressions)
+	}
+
+	if rec := serve(s, "GET", "/api/expressions?sort=expression", ""); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d for an unknown sort but got %d", http.StatusBadRequest, rec.Code)
+	}
+}
+
+func TestBulkDescriptionsHandler(t *testing.T) {
+	s, mock := newTestServer(t)
+	update := regexp.QuoteMeta("UPDATE cron_expressions")
+	now := time.Now()
+	mock.ExpectBegin()
+	mock.ExpectQuery(update).WithArgs("Nightly backup", sqlmock.AnyArg(), 3).
+		WillReturnRows(sqlmock.NewRows(expressionRowColumns).
+			AddRow(3, "Nightly", "0 0 * * *", "Nightly backup", nil, true, "", "{}", "dev", now, now, now))
+	mock.ExpectQuery(update).WithArgs("Gone", sqlmock.AnyArg(), 99).
+		WillReturnError(sql.ErrNoRows)
+	mock.ExpectCommit()
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO expression_audit")).
+		WithArgs(3, AuditUpdate, sqlmock.AnyArg(), nil, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	rec := serve(s, "POST", "/api/expressions/descriptions", `{"99":"Gone","3":"Nightly backup"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status %d but got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+
+	var response BulkDescriptionsResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if response.Updated != 1 || len(response.Skipped) != 1 || response.Skipped[0] != 99 {
+		t.Errorf("Unexpected response: %+v", response)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unmet expectations: %v", err)
+	}
+}
+
+func TestUploadExpressionsHandler(t *testing.T) {
+	s, mock := newTestServer(t)
+	now := time.Now()
+	mock.ExpectBegin()
+	mock.ExpectExec("SAVEPOINT upload_item").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(regexp.QuoteMeta("INSERT INTO cron_expressions")).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "updated_at"}).AddRow(11, now, now))
+	mock.ExpectExec("SAVEPOINT upload_item").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(regexp.QuoteMeta("INSERT INTO cron_expressions")).
+		WillReturnError(&pq.Error{Code: pqUniqueViolation})
+	mock.ExpectExec("ROLLBACK TO SAVEPOINT upload_item").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO expression_audit")).
+		WithArgs(11, AuditCreate, sqlmock.AnyArg(), nil, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, _ := form.CreateFormFile("file", "expressions.json")
+	part.Write([]byte(`[
+		{"name": "Nightly", "expression": "0 0 * * *"},
+		{"name": "Broken", "expression": "61 * * * *"},
+		{"name": "Duplicate", "expression": "0 * * * *"}
+	]`))
+	form.Close()
+
+	req := httptest.NewRequest("POST", "/api/expressions/upload", &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	rec := httptest.NewRecorder()
+	s.handler().ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status %d but got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+
+	var response UploadResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if response.Created != 1 || response.Failed != 2 {
+		t.Errorf("Expected 1 created and 2 failed, got %+v", response)
+	}
+	if response.Results[0].ID != 11 || response.Results[2].Error != "Expression already exists" {
+		t.Errorf("Unexpected results: %+v", response.Results)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unmet expectations: %v", err)
+	}
+}
+
+func TestScheduleLoadHandler(t *testing.T) {
+	s, mock := newTestServer(t)
+	now := time.Now()
+	mock.ExpectQuery("SELECT .* FROM cron_expressions e").
+		WillReturnRows(sqlmock.NewRows(expressionRowColumns).
+			AddRow(1, "Quarter hourly", "*/15 * * * *", "", nil, true, "UTC", "{}", "dev", now, now, nil).
+			AddRow(2, "Hourly", "0 * * * *", "", nil, true, "UTC", "{}", "dev", now, now, nil))
+
+	rec := serve(s, "GET", "/api/schedule/load?from=2024-01-15T09:00:00Z&to=2024-01-15T11:00:00Z", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status %d but got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+
+	var response LoadResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if response.TotalRuns != 10 || len(response.Hourly) != 2 {
+		t.Fatalf("Expected 10 runs over 2 hours, got %+v", response)
+	}
+	if response.Hourly[0].Runs != 5 || !response.Hourly[0].Hour.Equal(time.Date(2024, 1, 15, 9, 0, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected first bucket: %+v", response.Hourly[0])
+	}
+
+	rec = serve(s, "GET", "/api/schedule/load?from=2024-01-15T09:00:00Z&to=2024-03-15T09:00:00Z", "")
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d for an oversized window but got %d", http.StatusBadRequest, rec.Code)
+	}
+}
+
+func TestShareHandlers(t *testing.T) {
+	s, mock := newTestServer(t)
+
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO shared_conversions")).
+		WithArgs(sqlmock.AnyArg(), []byte(`{"expression":"0 9 * * 1-5","standard":"","timezone":"UTC","from":""}`), []byte(`{"relative":"true"}`), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	rec := serve(s, "POST", "/api/share", `{"expression":"0 9 * * 1-5","timezone":"UTC","options":{"relative":"true"},"expiresInHours":24}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d but got %d: %s", http.StatusCreated, rec.Code, rec.Body.String())
+	}
+	var created ShareResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &created); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if created.Token == "" || created.ExpiresAt == nil || len(created.Conversion.RelativeExecutions) == 0 {
+		t.Errorf("Unexpected share: %+v", created)
+	}
+
+	now := time.Now()
+	shareColumns := []string{"request", "options", "created_at", "expires_at"}
+	mock.ExpectQuery("SELECT request, options").WithArgs(created.Token).
+		WillReturnRows(sqlmock.NewRows(shareColumns).
+			AddRow([]byte(`{"expression":"0 9 * * 1-5","timezone":"UTC"}`), []byte(`{"relative":"true"}`), now, now.Add(time.Hour)))
+	rec = serve(s, "GET", "/api/share/"+created.Token, "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status %d but got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	var shared ShareResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &shared); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if shared.Conversion.Description == "" || len(shared.Conversion.RelativeExecutions) == 0 {
+		t.Errorf("Expected a freshly rendered conversion, got %+v", shared.Conversion)
+	}
+
+	mock.ExpectQuery("SELECT request, options").WithArgs("expired").
+		WillReturnRows(sqlmock.NewRows(shareColumns).
+			AddRow([]byte(`{"expression":"0 9 * * *"}`), []byte(`{}`), now, now.Add(-time.Hour)))
+	rec = serve(s, "GET", "/api/share/expired", "")
+	if rec.Code != http.StatusGone {
+		t.Errorf("Expected status %d for an expired share but got %d", http.StatusGone, rec.Code)
+	}
+
+	rec = serve(s, "POST", "/api/share", `{"expression":"0 9 * * *","options":{"format":"xml"}}`)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d for an unknown option but got %d", http.StatusBadRequest, rec.Code)
+	}
+}
+
+func TestPrettyJSON(t *testing.T) {
+	s, _ := newTestServer(t)
+
+	rec := serve(s, "GET", "/api/version", "")
+	if strings.Contains(rec.Body.String(), "\n  ") {
+		t.Errorf("Expected compact JSON by default, got %q", rec.Body.String())
+	}
+
+	rec = serve(s, "GET", "/api/version?pretty=true", "")
+	if !strings.Contains(rec.Body.String(), "{\n  \"version\"") {
+		t.Errorf("Expected indented JSON with ?pretty=true, got %q", rec.Body.String())
+	}
+
+	s.config.PrettyJSON = true
+	rec = serve(s, "GET", "/api/version", "")
+	if !strings.Contains(rec.Body.String(), "{\n  \"version\"") {
+		t.Errorf("Expected indented JSON with PRETTY_JSON, got %q", rec.Body.String())
+	}
+}
+
+func TestContentTypeEnforcement(t *testing.T) {
+	body := `{"expression":"0 9 * * *"}`
+	tests := []struct {
+		name        string
+		contentType string
+		strict      bool
+		expected    int
+	}{
+		{"JSON", "application/json", false, http.StatusOK},
+		{"JSON with charset", "application/json; charset=utf-8", false, http.StatusOK},
+		{"Form", "application/x-www-form-urlencoded", false, http.StatusUnsupportedMediaType},
+		{"Missing allowed", "", false, http.StatusOK},
+		{"Missing rejected when strict", "", true, http.StatusUnsupportedMediaType},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, _ := newTestServer(t)
+			s.config.StrictContentType = tt.strict
+
+			req := httptest.NewRequest("POST", "/api/convert", strings.NewReader(body))
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			rec := httptest.NewRecorder()
+			s.handler().ServeHTTP(rec, req)
+			if rec.Code != tt.expected {
+				t.Fatalf("Expected status %d but got %d: %s", tt.expected, rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestCountFrequency(t *testing.T) {
+	// Thursday, just past midnight so no run lands on a window boundary
+	from := time.Date(2026, 1, 1, 0, 0, 30, 0, time.UTC)
+	tests := []struct {
+		expression string
+		expected   FrequencyResponse
+	}{
+		{"*/5 * * * *", FrequencyResponse{Next24h: 288, Next7d: 2016, Next30d: 8640}},
+		{"0 9 * * 1-5", FrequencyResponse{Next24h: 1, Next7d: 5, Next30d: 22}},
+		{"* * * * *", FrequencyResponse{Next24h: 1440, Next7d: 10080, Next30d: 43200}},
+		{"0 0 29 2 *", FrequencyResponse{}},
+	}
+	for _, tt := range tests {
+		schedule, err := parseExpression(tt.expression)
+		if err != nil {
+			t.Fatalf("parseExpression(%q) error: %v", tt.expression, err)
+		}
+		var got FrequencyResponse
+		countFrequency(schedule, from, &got)
+		if got != tt.expected {
+			t.Errorf("countFrequency(%q) = %+v, expected %+v", tt.expression, got, tt.expected)
+		}
+	}
+}
+
+func TestFrequencyHandler(t *testing.T) {
+	s, _ := newTestServer(t)
+
+	rec := serve(s, "POST", "/api/schedule/frequency", `{"expression":"*/5 * * * *","timezone":"Asia/Tokyo"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status %d but got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	var response FrequencyResponse
+	json.NewDecoder(rec.Body).Decode(&response)
+	if !response.Enabled || response.Next24h != 288 || response.Next30d != 8640 {
+		t.Errorf("Expected 288 runs a day, got %+v", response)
+	}
+
+	for _, body := range []string{
+		`{"expression":"*/5 * * *"}`,
+		`{"expression":"*/5 * * * *","timezone":"Mars/Olympus"}`,
+	} {
+		if rec := serve(s, "POST", "/api/schedule/frequency", body); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status %d but got %d", body, http.StatusBadRequest, rec.Code)
+		}
+	}
+}
+
+func TestExpressionFrequencyHandler(t *testing.T) {
+	query := regexp.QuoteMeta("FROM cron_expressions e")
+	now := time.Now()
+	row := func(expression string, enabled bool) *sqlmock.Rows {
+		return sqlmock.NewRows(expressionRowColumns).
+			AddRow(5, "Job", expression, "", nil, enabled, "", "{}", "dev", now, now, now)
+	}
+
+	tests := []struct {
+		name     string
+		setup    func(mock sqlmock.Sqlmock)
+		status   int
+		next30d  int
+		disabled bool
+	}{
+		{
+			name:    "enabled",
+			setup:   func(mock sqlmock.Sqlmock) { mock.ExpectQuery(query).WillReturnRows(row("*/5 * * * *", true)) },
+			status:  http.StatusOK,
+			next30d: 8640,
+		},
+		{
+			name:     "paused",
+			setup:    func(mock sqlmock.Sqlmock) { mock.ExpectQuery(query).WillReturnRows(row("*/5 * * * *", false)) },
+			status:   http.StatusOK,
+			disabled: true,
+		},
+		{
+			name:   "not found",
+			setup:  func(mock sqlmock.Sqlmock) { mock.ExpectQuery(query).WillReturnError(sql.ErrNoRows) },
+			status: http.StatusNotFound,
+		},
+		{
+			name:   "invalid stored expression",
+			setup:  func(mock sqlmock.Sqlmock) { mock.ExpectQuery(query).WillReturnRows(row("0 25 * * *", true)) },
+			status: http.StatusInternalServerError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, mock := newTestServer(t)
+			tt.setup(mock)
+
+			rec := serve(s, "GET", "/api/expressions/5/frequency", "")
+			if rec.Code != tt.status {
+				t.Fatalf("Expected status %d but got %d: %s", tt.status, rec.Code, rec.Body.String())
+			}
+			if tt.status != http.StatusOK {
+				return
+			}
+			var response FrequencyResponse
+			json.NewDecoder(rec.Body).Decode(&response)
+			if response.ID != 5 || response.Enabled == tt.disabled || response.Next30d != tt.next30d {
+				t.Errorf("Expected id 5 with %d runs in 30 days, got %+v", tt.next30d, response)
+			}
+		})
+	}
+}
+
+func TestPprofRoutes(t *testing.T) {
+	t.Run("disabled by default", func(t *testing.T) {
+		s, _ := newTestServer(t)
+		s.config.EnablePprof = false
+
+		rec := serve(s, "GET", "/debug/pprof/", "")
+		if rec.Code != http.StatusNotFound {
+			t.Fatalf("Expected status %d but got %d", http.StatusNotFound, rec.Code)
+		}
+	})
+
+	t.Run("not mounted without a metrics token", func(t *testing.T) {
+		s, _ := newTestServer(t)
+		s.config.EnablePprof = true
+		s.config.MetricsToken = ""
+
+		rec := serve(s, "GET", "/debug/pprof/", "")
+		if rec.Code != http.StatusNotFound {
+			t.Fatalf("Expected status %d but got %d", http.StatusNotFound, rec.Code)
+		}
+	})
+
+	t.Run("requires metrics token", func(t *testing.T) {
+		s, _ := newTestServer(t)
+		s.config.EnablePprof = true
+		s.config.MetricsToken = "scrape"
+
+		rec := serve(s, "GET", "/debug/pprof/", "")
+		if rec.Code != http.StatusUnauthorized {
+			t.Fatalf("Expected status %d but got %d", http.StatusUnauthorized, rec.Code)
+		}
+
+		req := httptest.NewRequest("GET", "/debug/pprof/", nil)
+		req.Header.Set("Authorization", "Bearer scrape")
+		rec = httptest.NewRecorder()
+		s.handler().ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected status %d but got %d", http.StatusOK, rec.Code)
+		}
+	})
+}
+
+func TestNextBatchHandler(t *testing.T) {
+	s, _ := newTestServer(t)
+
+	rec := serve(s, "POST", "/api/schedule/next-batch", `{"expressions":["0 9 * * *","*/15  * * * *","0 0 30 2 *"],"from":"2026-01-01T08:00:00Z"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status %d but got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	var response NextBatchResponse
+	json.NewDecoder(rec.Body).Decode(&response)
+	from := time.Date(2026, 1, 1, 8, 0, 0, 0, time.UTC)
+	if !response.From.Equal(from) || len(response.Results) != 3 {
+		t.Fatalf("Expected 3 results from %v, got %+v", from, response)
+	}
+
+	// Every result is computed from the same instant, in input order
+	expected := []time.Time{from.Add(time.Hour), from.Add(15 * time.Minute)}
+	for i, want := range expected {
+		if next := response.Results[i].NextRun; next == nil || !next.Equal(want) {
+			t.Errorf("Result %d: expected next run %v, got %v", i, want, next)
+		}
+	}
+	if response.Results[1].Expression != "*/15 * * * *" || response.Results[1].RawExpression != "*/15  * * * *" {
+		t.Errorf("Expected cleaned and raw expressions, got %+v", response.Results[1])
+	}
+	if response.Results[2].NextRun != nil {
+		t.Errorf("Expected no next run for a date that never occurs, got %v", response.Results[2].NextRun)
+	}
+}
+
+func TestNextBatchHandlerRejects(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		message string
+	}{
+		{"invalid expression", `{"expressions":["0 9 * * *","0 9 * *"]}`, "at index 1"},
+		{"invalid from", `{"expressions":["0 9 * * *"],"from":"tomorrow"}`, "RFC3339"},
+	}
+	for _, tt := range tests {
+		s, _ := newTestServer(t)
+		rec := serve(s, "POST", "/api/schedule/next-batch", tt.body)
+		if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), tt.message) {
+			t.Errorf("%s: expected %d mentioning %q, got %d %s", tt.name, http.StatusBadRequest, tt.message, rec.Code, rec.Body.String())
+		}
+	}
+}
+
+func TestIsTransient(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected bool
+	}{
+		{"Nil", nil, false},
+		{"No rows", sql.ErrNoRows, false},
+		{"Connection failure", &pq.Error{Code: "08006"}, true},
+		{"Serialization failure", &pq.Error{Code: "40001"}, true},
+		{"Admin shutdown", &pq.Error{Code: "57P01"}, true},
+		{"Unique violation", &pq.Error{Code: pqUniqueViolation}, false},
+		{"Other error", errors.New("boom"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isTransient(tt.err); got != tt.expected {
+				t.Errorf("isTransient(%v) = %v, expected %v", tt.err, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestRetryReadRecoversFromTransientError(t *testing.T) {
+	s, mock := newTestServer(t)
+	mock.ExpectQuery("SELECT COUNT").WillReturnError(&pq.Error{Code: "40001"})
+	mock.ExpectQuery("SELECT COUNT").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+
+	var count int
+	err := s.db.retryRead(context.Background(), func(db *sql.DB) error {
+		return db.QueryRowContext(context.Background(), "SELECT COUNT(*) FROM cron_expressions").Scan(&count)
+	})
+	if err != nil || count != 3 {
+		t.Fatalf("Expected count 3 after retry, got %d, %v", count, err)
+	}
+}
+
+func TestMinMinuteGap(t *testing.T) {
+	tests := []struct {
+		expression string
+		expected   int
+	}{
+		// Hour 10 isn't scheduled, so 09:59 isn't followed by 10:00
+		{"0,59 9 * * *", 59},
+		{"0,59 9,10 * * *", 1},
+		{"0,59 * * * *", 1},
+		{"0,59 9,11 * * *", 59},
+		{"0,59 0,23 * * *", 1},
+		{"*/15 * * * *", 15},
+		{"5 9 * * *", 60},
+		{"5 * * * *", 60},
+	}
+	for _, tt := range tests {
+		schedule, err := parseExpression(tt.expression)
+		if err != nil {
+			t.Fatalf("parseExpression(%q): %v", tt.expression, err)
+		}
+		spec := schedule.(*cron.SpecSchedule)
+		if got := minMinuteGap(bitValues(spec.Minute, 0, 59), bitValues(spec.Hour, 0, 23)); got != tt.expected {
+			t.Errorf("minMinuteGap(%q) = %d, expected %d", tt.expression, got, tt.expected)
+		}
+	}
+}
+
+func TestValidateSafetyHandler(t *testing.T) {
+	s, _ := newTestServer(t)
+
+	rec := serve(s, "POST", "/api/validate/safety", `{"expression":"0,59 9 * * *"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status %d but got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	var response SafetyResponse
+	json.NewDecoder(rec.Body).Decode(&response)
+	if !response.Pass {
+		t.Errorf("Expected 0,59 9 * * * to pass, got %+v", response.Findings)
+	}
+
+	rec = serve(s, "POST", "/api/validate/safety", `{"expression":"0,59 9-10 * * *"}`)
+	response = SafetyResponse{}
+	json.NewDecoder(rec.Body).Decode(&response)
+	if response.Pass || len(response.Findings) != 1 || response.Findings[0].Rule != "short-interval" {
+		t.Errorf("Expected a short-interval finding, got %+v", response.Findings)
+	}
+
+	if rec := serve(s, "POST", "/api/validate/safety", `{"expression":"61 * * * *"}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d for an invalid expression but got %d", http.StatusBadRequest, rec.Code)
+	}
+}
+
+func TestLintExpression(t *testing.T) {
+	tests := []struct {
+		name       string
+		expression string
+		expected   string
+		rules      []string
+	}{
+		{"Quarter hours", "0,15,30,45 * * * *", "*/15 * * * *", []string{"step-list"}},
+		{"Weekdays list", "0 9 * * 1,2,3,4,5", "0 9 * * 1-5", []string{"consecutive-range"}},
+		{"Redundant step", "*/1 * * * *", "* * * * *", []string{"redundant-step"}},
+		{"Offset step list", "5,20,35 * * * *", "5-35/15 * * * *", []string{"step-list"}},
+		{"Already canonical", "*/5 9-17 * * 1-5", "*/5 9-17 * * 1-5", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, suggestions := lintExpression(tt.expression)
+			if got != tt.expected {
+				t.Errorf("lintExpression(%q) = %q, expected %q", tt.expression, got, tt.expected)
+			}
+			if len(suggestions) != len(tt.rules) {
+				t.Fatalf("Expected %d suggestions, got %+v", len(tt.rules), suggestions)
+			}
+			for i, rule := range tt.rules {
+				if suggestions[i].Rule != rule {
+					t.Errorf("Expected rule %q, got %q", rule, suggestions[i].Rule)
+				}
+			}
+		})
+	}
+}
+
+func TestEnvelopeResponses(t *testing.T) {
+	t.Run("bare by default", func(t *testing.T) {
+		s, _ := newTestServer(t)
+		s.config.EnvelopeResponses = false
+
+		rec := serve(s, "GET", "/api/version", "")
+		if !strings.HasPrefix(rec.Body.String(), `{"version":`) {
+			t.Errorf("Expected bare response, got %s", rec.Body.String())
+		}
+	})
+
+	t.Run("enveloped when enabled", func(t *testing.T) {
+		s, _ := newTestServer(t)
+		s.config.EnvelopeResponses = true
+
+		rec := serve(s, "GET", "/api/version", "")
+		var envelope Envelope
+		if err := json.Unmarshal(rec.Body.Bytes(), &envelope); err != nil {
+			t.Fatalf("Expected JSON envelope, got %s", rec.Body.String())
+		}
+		if envelope.Data == nil || envelope.Error != nil || envelope.Meta.RequestID == "" {
+			t.Errorf("Expected data and meta in envelope, got %s", rec.Body.String())
+		}
+	})
+}
+
+func TestGetExpressionsByValueHandler(t *testing.T) {
+	query := regexp.QuoteMeta("WHERE e.expression = $1")
+
+	t.Run("matches", func(t *testing.T) {
+		s, mock := newTestServer(t)
+		now := time.Now()
+		mock.ExpectQuery(query).WithArgs("*/5 * * * *").
+			WillReturnRows(sqlmock.NewRows(expressionRowColumns).AddRow(3, "Frequent", "*/5 * * * *", "", nil, true, "", "{}", "dev", now, now, nil))
+
+		rec := serve(s, "GET", "/api/expressions/by-expression?value=%2A%2F5%20%2A%20%2A%20%2A%20%2A", "")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected status %d but got %d", http.StatusOK, rec.Code)
+		}
+		if !strings.Contains(rec.Body.String(), `"id":3`) {
+			t.Errorf("Expected matching expression in body, got %s", rec.Body.String())
+		}
+	})
+
+	t.Run("no matches", func(t *testing.T) {
+		s, mock := newTestServer(t)
+		mock.ExpectQuery(query).WithArgs("0 3 * * *").WillReturnRows(sqlmock.NewRows(expressionRowColumns))
+
+		rec := serve(s, "GET", "/api/expressions/by-expression?value=0+3+*+*+*", "")
+		if rec.Code != http.StatusOK || strings.TrimSpace(rec.Body.String()) != "[]" {
+			t.Fatalf("Expected 200 with an empty array, got %d %s", rec.Code, rec.Body.String())
+		}
+	})
+}
+
+func TestDecodeErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		field    string
+		expected string
+	}{
+		{"Wrong type", `{"name":"Nightly","expression":5}`, "expression", "string"},
+		{"Wrong nested type", `{"name":"Nightly","expression":"0 0 * * *","category_id":"two"}`, "category_id", "int"},
+		{"Malformed", `{"name":`, "", ""},
+		{"Empty", ``, "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, _ := newTestServer(t)
+			rec := serve(s, "POST", "/api/expressions", tt.body)
+			if rec.Code != http.StatusBadRequest {
+				t.Fatalf("Expected status %d but got %d", http.StatusBadRequest, rec.Code)
+			}
+			var response ErrorResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Expected JSON error body, got %s", rec.Body.String())
+			}
+			decodeErr := response.Error
+			if decodeErr.Code != ErrInvalidJSON || decodeErr.Field != tt.field || decodeErr.Expected != tt.expected {
+				t.Errorf("Expected %s on field %q of type %q, got %+v", ErrInvalidJSON, tt.field, tt.expected, decodeErr)
+			}
+		})
+	}
+}
+
+func TestHandlersDecodeJSON(t *testing.T) {
+	paths := []string{
+		"/api/convert/batch",
+		"/api/convert/partial",
+		"/api/convert/stream/abc",
+		"/api/crontab/parse",
+		"/api/detect",
+		"/api/lint",
+		"/api/normalize",
+		"/api/schedule/frequency",
+		"/api/schedule/heatmap",
+		"/api/schedule/next-batch",
+		"/api/validate/cadence",
+		"/api/validate/policy",
+		"/api/validate/safety",
+	}
+	s, _ := newTestServer(t)
+	for _, path := range paths {
+		rec := serve(s, "POST", path, `{"expression":`)
+		var response ErrorResponse
+		json.NewDecoder(rec.Body).Decode(&response)
+		if rec.Code != http.StatusBadRequest || response.Error.Message != "Request body is truncated JSON" {
+			t.Errorf("%s: expected decodeJSON's truncated body error, got %d %+v", path, rec.Code, response)
+		}
+	}
+}
+
+func TestCommonRuns(t *testing.T) {
+	from := time.Date(2026, time.March, 2, 0, 0, 0, 0, time.UTC) // a Monday
+	to := from.AddDate(0, 0, 7)
+	parse := func(expressions ...string) []cron.Schedule {
+		schedules := []cron.Schedule{}
+		for _, e := range expressions {
+			schedule, err := parseExpression(e)
+			if err != nil {
+				t.Fatal(err)
+			}
+			schedules = append(schedules, schedule)
+		}
+		return schedules
+	}
+
+	runs, _ := commonRuns(parse("*/15 * * * *", "0 */6 * * *", "0 12 * * 1-5"), from, to, 10)
+	if len(runs) != 5 {
+		t.Fatalf("Expected 5 weekday noon runs, got %v", runs)
+	}
+	if runs[0] != from.Add(12*time.Hour) {
+		t.Errorf("Expected first shared run at Monday noon, got %s", runs[0])
+	}
+
+	runs, truncated := commonRuns(parse("0 9 * * *", "30 9 * * *"), from, to, 10)
+	if len(runs) != 0 || truncated {
+		t.Errorf("Expected no shared runs, got %v (truncated %v)", runs, truncated)
+	}
+}
+
+func TestMetadataValidation(t *testing.T) {
+	for _, metadata := range []string{`[1,2]`, `"team"`, `42`} {
+		s, mock := newTestServer(t)
+		rec := serve(s, "POST", "/api/expressions", `{"name":"Nightly","expression":"0 0 * * *","metadata":`+metadata+`}`)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("metadata %s: expected status %d but got %d", metadata, http.StatusBadRequest, rec.Code)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("metadata %s: unexpected queries: %v", metadata, err)
+		}
+	}
+
+	s, mock := newTestServer(t)
+	now := time.Now()
+	mock.ExpectQuery(regexp.QuoteMeta("INSERT INTO cron_expressions")).
+		WithArgs("Nightly", "0 0 * * *", "", nil, sqlmock.AnyArg(), sql.NullString{String: `{"team":"payments"}`, Valid: true}, "dev", sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "updated_at"}).AddRow(1, now, now))
+	rec := serve(s, "POST", "/api/expressions", `{"name":"Nightly","expression":"0 0 * * *","metadata":{"team":"payments"}}`)
+	if rec.Code != http.StatusCreated || !strings.Contains(rec.Body.String(), `"metadata":{"team":"payments"}`) {
+		t.Errorf("Expected metadata stored and returned, got %d %s", rec.Code, rec.Body.String())
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestMetadataFilter(t *testing.T) {
+	s, mock := newTestServer(t)
+	now := time.Now()
+	mock.ExpectQuery(regexp.QuoteMeta("e.metadata ->> $1 = $2 AND e.metadata ->> $3 = $4")).
+		WithArgs("region", "eu", "team", "payments").
+		WillReturnRows(sqlmock.NewRows(expressionRowColumns).
+			AddRow(1, "Nightly", "0 0 * * *", "", nil, true, "", `{"team":"payments","region":"eu"}`, "dev", now, now, now))
+
+	rec := serve(s, "GET", "/api/expressions?meta.team=payments&meta.region=eu", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status %d but got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	var expressions []CronExpression
+	json.Unmarshal(rec.Body.Bytes(), &expressions)
+	if len(expressions) != 1 || expressions[0].Name != "Nightly" {
+		t.Errorf("Unexpected expressions: %+v", expressions)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+
+	// A bare "meta." prefix isn't a filter
+	s, mock = newTestServer(t)
+	mock.ExpectQuery(regexp.QuoteMeta("WHERE e.deleted_at IS NULL ORDER")).
+		WillReturnRows(sqlmock.NewRows(expressionRowColumns))
+	if rec := serve(s, "GET", "/api/expressions?meta.=x", ""); rec.Code != http.StatusOK {
+		t.Errorf("Expected status %d but got %d", http.StatusOK, rec.Code)
+	}
+}
+
+func TestNearestWeekday(t *testing.T) {
+	tests := []struct {
+		name  string
+		year  int
+		month time.Month
+		day   int
+		want  string
+	}{
+		{"weekday unchanged", 2024, time.January, 15, "2024-01-15"},
+		{"saturday moves to friday", 2024, time.June, 15, "2024-06-14"},
+		{"sunday moves to monday", 2024, time.September, 15, "2024-09-16"},
+		{"saturday first stays in month", 2024, time.June, 1, "2024-06-03"},
+		{"sunday last day stays in month", 2024, time.March, 31, "2024-03-29"},
+		{"saturday last day moves back", 2024, time.August, 31, "2024-08-30"},
+		{"leap day", 2024, time.February, 29, "2024-02-29"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := nearestWeekday(tt.year, tt.month, tt.day, time.UTC)
+			if !ok {
+				t.Fatalf("nearestWeekday(%d, %s, %d) not ok", tt.year, tt.month, tt.day)
+			}
+			if got.Format("2006-01-02") != tt.want {
+				t.Errorf("nearestWeekday(%d, %s, %d) = %s, want %s", tt.year, tt.month, tt.day, got.Format("2006-01-02"), tt.want)
+			}
+		})
+	}
+
+	if _, ok := nearestWeekday(2023, time.February, 30, time.UTC); ok {
+		t.Error("expected day 30 to be skipped in February")
+	}
+}
+
+func TestWeekdayScheduleNext(t *testing.T) {
+	schedule, err := parseExpression("0 9 1W * *")
+	if err != nil {
+		t.Fatalf("parseExpression: %v", err)
+	}
+
+	// 2024-06-01 is a Saturday, so June's run is Monday the 3rd
+	next := schedule.Next(time.Date(2024, time.May, 1, 12, 0, 0, 0, time.UTC))
+	want := []string{
+		"2024-06-03 09:00",
+		"2024-07-01 09:00",
+		"2024-08-01 09:00",
+		"2024-09-02 09:00", // Sunday the 1st moves forward
+	}
+	for i, w := range want {
+		if got := next.Format("2006-01-02 15:04"); got != w {
+			t.Errorf("run %d = %s, want %s", i, got, w)
+		}
+		next = schedule.Next(next)
+	}
+}
+
+func TestWeekdayScheduleSkipsShortMonths(t *testing.T) {
+	schedule, err := parseExpression("0 0 31W * *")
+	if err != nil {
+		t.Fatalf("parseExpression: %v", err)
+	}
+
+	// April has no 31st; May 31 2024 is a Friday
+	got := schedule.Next(time.Date(2024, time.April, 1, 0, 0, 0, 0, time.UTC))
+	if want := time.Date(2024, time.May, 31, 0, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("Next = %s, want %s", got, want)
+	}
+}
+
+func TestWeekdayScheduleSameDay(t *testing.T) {
+	schedule, err := parseExpression("0 */6 15W * *")
+	if err != nil {
+		t.Fatalf("parseExpression: %v", err)
+	}
+
+	// Already on the target day, the next run is later that day
+	got := schedule.Next(time.Date(2024, time.January, 15, 7, 0, 0, 0, time.UTC))
+	if want := time.Date(2024, time.January, 15, 12, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("Next = %s, want %s", got, want)
+	}
+
+	// Once the target day has passed, it moves to the next month
+	got = schedule.Next(time.Date(2024, time.January, 15, 18, 0, 0, 0, time.UTC))
+	if want := time.Date(2024, time.February, 15, 0, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("Next = %s, want %s", got, want)
+	}
+}
+
+func TestParseWeekdayExpressionErrors(t *testing.T) {
+	for _, expression := range []string{"0 0 32W * *", "0 0 W * *", "0 0 15W * MON"} {
+		if _, err := parseExpression(expression); err == nil {
+			t.Errorf("parseExpression(%q) expected error", expression)
+		}
+	}
+}
+
+func TestDescribeNearestWeekday(t *testing.T) {
+	got := generateDescription("0 9 15W * *")
+	want := "on the weekday nearest the 15th of the month"
+	if !strings.Contains(got, want) {
+		t.Errorf("generateDescription = %q, want it to contain %q", got, want)
+	}
+}
+
+func TestToSystemdCalendar(t *testing.T) {
+	tests := []struct {
+		expression string
+		want       string
+	}{
+		{"0 9 * * *", "*-*-* 09:00:00"},
+		{"*/15 * * * *", "*-*-* *:00/15:00"},
+		{"30 8 * * 1-5", "Mon..Fri *-*-* 08:30:00"},
+		{"0 0 1,15 * *", "*-*-01,15 00:00:00"},
+		{"0 12 * JAN-MAR SAT,SUN", "Sat,Sun *-01..03-* 12:00:00"},
+		{"0 0-12/6 * * *", "*-*-* 00,06,12:00:00"},
+		{"0 0 * * 7", "Sun *-*-* 00:00:00"},
+		{"@weekly", "Sun *-*-* 00:00:00"},
+		{"0 22-2 * * *", "*-*-* 22..23,00..02:00:00"},
+		{"0 0 * * FRI-MON", "Fri..Sat,Sun..Mon *-*-* 00:00:00"},
+		{"0 0 * 11-2 *", "*-11..12,01..02-* 00:00:00"},
+	}
+	for _, tt := range tests {
+		got, err := toSystemdCalendar(tt.expression)
+		if err != nil {
+			t.Errorf("toSystemdCalendar(%q) error: %v", tt.expression, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("toSystemdCalendar(%q) = %q, want %q", tt.expression, got, tt.want)
+		}
+	}
+}
+
+func TestToSystemdCalendarUnsupported(t *testing.T) {
+	if _, err := toSystemdCalendar("0 0 1 * MON"); err == nil {
+		t.Error("expected an error when both day fields are restricted")
+	}
+	if _, err := toSystemdCalendar("0 22-2/2 * * *"); err == nil {
+		t.Error("expected an error for a stepped range that wraps")
+	}
+}
+
+func TestFirstDivergence(t *testing.T) {
+	from := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	to := from.Add(equivalenceWindow)
+
+	tests := []struct {
+		a, b       string
+		equivalent bool
+		which      string
+	}{
+		{"0,30 * * * *", "*/30 * * * *", true, ""},
+		{"0 0 * * 0", "0 0 * * SUN", true, ""},
+		{"0 9 * * 1-5", "0 9 * * MON,TUE,WED,THU,FRI", true, ""},
+		{"0 * * * *", "0 */2 * * *", false, "a"},
+		{"0 0 29 2 *", "0 0 28 2 *", false, "b"},
+	}
+	for _, tt := range tests {
+		a, _ := parseExpression(tt.a)
+		b, _ := parseExpression(tt.b)
+		divergence, which, _, truncated := firstDivergence(a, b, from, to)
+		if truncated {
+			t.Errorf("%q vs %q: unexpectedly truncated", tt.a, tt.b)
+		}
+		if divergence.IsZero() != tt.equivalent || which != tt.which {
+			t.Errorf("%q vs %q: divergence %v (%q), want equivalent=%v which=%q",
+				tt.a, tt.b, divergence, which, tt.equivalent, tt.which)
+		}
+	}
+}
+
+func TestFirstDivergenceTime(t *testing.T) {
+	from := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	a, _ := parseExpression("0 * * * *")
+	b, _ := parseExpression("0 */2 * * *")
+
+	divergence, _, runs, _ := firstDivergence(a, b, from, from.Add(equivalenceWindow))
+	if want := from.Add(time.Hour); !divergence.Equal(want) {
+		t.Errorf("divergence = %v, want %v", divergence, want)
+	}
+	if runs != 0 {
+		t.Errorf("runs = %d, want 0", runs)
+	}
+}
+
+func TestCompareSchedulesTruncated(t *testing.T) {
+	// Both run every minute until December, far beyond the run cap
+	from := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	a, _ := parseExpression("* * * * *")
+	b, _ := parseExpression("* * * 1-11 *")
+
+	response := compareSchedules(a, b, from)
+	if response.Equivalent || !response.Truncated || response.ComparedRuns != maxEquivalenceRuns {
+		t.Errorf("Expected a truncated, non-equivalent comparison, got %+v", response)
+	}
+}
+
+func TestEquivalentHandler(t *testing.T) {
+	s, _ := newTestServer(t)
+
+	rec := serve(s, "POST", "/api/equivalent", `{"a":"0 9 * * 1-5","b":"0 9 * * MON-FRI"}`)
+	var response EquivalentResponse
+	json.NewDecoder(rec.Body).Decode(&response)
+	if rec.Code != http.StatusOK || !response.Equivalent || response.Truncated {
+		t.Errorf("Expected equivalent expressions, got %d %+v", rec.Code, response)
+	}
+
+	rec = serve(s, "POST", "/api/equivalent", `{"a":"* * * * *","b":"*/1 * * * *"}`)
+	response = EquivalentResponse{}
+	json.NewDecoder(rec.Body).Decode(&response)
+	if response.Equivalent || !response.Truncated {
+		t.Errorf("Expected a truncated comparison not to be reported as equivalent, got %+v", response)
+	}
+
+	if rec := serve(s, "POST", "/api/equivalent", `{"a":"0 9 * * *","b":"0 25 * * *"}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d for an invalid expression but got %d", http.StatusBadRequest, rec.Code)
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	s := &Server{config: Config{TrustedProxies: parseTrustedProxies("10.0.0.0/8, 192.168.1.1")}}
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		forwarded  string
+		expected   string
+	}{
+		{"Direct client", "203.0.113.5:4000", "", "203.0.113.5"},
+		{"Untrusted peer spoofing header", "203.0.113.5:4000", "1.2.3.4", "203.0.113.5"},
+		{"Trusted proxy", "10.1.2.3:4000", "198.51.100.7", "198.51.100.7"},
+		{"Chain of trusted proxies", "10.1.2.3:4000", "198.51.100.7, 192.168.1.1, 10.9.9.9", "198.51.100.7"},
+		{"Spoofed leftmost hop", "10.1.2.3:4000", "1.2.3.4, 198.51.100.7", "198.51.100.7"},
+		{"Trusted proxy without header", "10.1.2.3:4000", "", "10.1.2.3"},
+		{"Bare IP proxy entry", "192.168.1.1:4000", "198.51.100.7", "198.51.100.7"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.forwarded != "" {
+				req.Header.Set("X-Forwarded-For", tt.forwarded)
+			}
+			if got := s.clientIP(req); got != tt.expected {
+				t.Errorf("clientIP() = %q, expected %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestGroupExecutionsByDate(t *testing.T) {
+	schedule, err := parseExpression("30 11,23 * * *")
+	if err != nil {
+		t.Fatalf("parseExpression: %v", err)
+	}
+	from := time.Date(2024, time.January, 15, 12, 0, 0, 0, time.UTC)
+	times := nextExecutionTimes(schedule, from, 3)
+
+	t.Run("UTC boundaries", func(t *testing.T) {
+		got := groupExecutionsByDate(times, time.UTC)
+		expected := map[string][]string{
+			"2024-01-15": {"23:30"},
+			"2024-01-16": {"11:30", "23:30"},
+		}
+		if !reflect.DeepEqual(got, expected) {
+			t.Errorf("groupExecutionsByDate() = %v, expected %v", got, expected)
+		}
+	})
+
+	t.Run("Requested zone boundaries", func(t *testing.T) {
+		// 23:30 UTC is 18:30 in New York, so the runs either side of UTC
+		// midnight land on the same local date
+		loc, err := time.LoadLocation("America/New_York")
+		if err != nil {
+			t.Skipf("timezone data unavailable: %v", err)
+		}
+		got := groupExecutionsByDate(times, loc)
+		expected := map[string][]string{
+			"2024-01-15": {"18:30"},
+			"2024-01-16": {"06:30", "18:30"},
+		}
+		if !reflect.DeepEqual(got, expected) {
+			t.Errorf("groupExecutionsByDate() = %v, expected %v", got, expected)
+		}
+	})
+}
+
+func TestConvertGroupByDate(t *testing.T) {
+	s, _ := newTestServer(t)
+
+	rec := serve(s, "POST", "/api/convert?groupByDate=true", `{"expression":"0 */6 * * *","timezone":"UTC"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status %d but got %d", http.StatusOK, rec.Code)
+	}
+
+	var response ConvertResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	total := 0
+	for date, times := range response.ExecutionsByDate {
+		if _, err := time.Parse("2006-01-02", date); err != nil {
+			t.Errorf("Unexpected date key %q", date)
+		}
+		total += len(times)
+	}
+	if total != len(response.NextExecutions) {
+		t.Errorf("Expected %d grouped runs but got %d", len(response.NextExecutions), total)
+	}
+
+	// Without a timezone, runs are evaluated and grouped in the configured zone
+	s.config.DefaultTimezone, _ = time.LoadLocation("Asia/Tokyo")
+	rec = serve(s, "POST", "/api/convert?groupByDate=true", `{"expression":"0 23 * * *"}`)
+	response = ConvertResponse{}
+	json.Unmarshal(rec.Body.Bytes(), &response)
+	if len(response.NextExecutions) == 0 || !strings.HasSuffix(response.NextExecutions[0], "at 23:00:00") {
+		t.Errorf("Expected runs in Asia/Tokyo, got %v", response.NextExecutions)
+	}
+	for date, times := range response.ExecutionsByDate {
+		if !reflect.DeepEqual(times, []string{"23:00"}) {
+			t.Errorf("Expected %s to hold 23:00, got %v", date, times)
+		}
+	}
+
+	// The flat list stays the default
+	rec = serve(s, "POST", "/api/convert", `{"expression":"0 */6 * * *"}`)
+	if strings.Contains(rec.Body.String(), "executionsByDate") {
+		t.Errorf("Expected no grouped executions by default, got %s", rec.Body.String())
+	}
+}
+
+func TestLocateFieldError(t *testing.T) {
+	tests := []struct {
+		expression string
+		field      int
+		token      string
+		reason     string
+	}{
+		{"0 9 40 * *", 2, "40", "day-of-month out of range"},
+		{"61 * * * *", 0, "61", "minute out of range"},
+		{"0 0,25 * * *", 1, "25", "hour out of range"},
+		{"*/0 * * * *", 0, "*/0", "minute has an invalid step"},
+		{"0 0 * FOO *", 3, "FOO", "month has an invalid value"},
+		{"0 0 5-1 * *", 2, "5-1", "day-of-month range starts after it ends"},
+	}
+	for _, tt := range tests {
+		got := locateFieldError(tt.expression)
+		if got == nil {
+			t.Errorf("locateFieldError(%q) = nil, expected field %d", tt.expression, tt.field)
+			continue
+		}
+		if got.Field != tt.field || got.Token != tt.token || got.Reason != tt.reason {
+			t.Errorf("locateFieldError(%q) = %+v, expected field %d token %q reason %q",
+				tt.expression, got, tt.field, tt.token, tt.reason)
+		}
+	}
+
+	for _, valid := range []string{"*/5 9-17 * * MON-FRI", "0 0 15W * ?", "0 0 1,15 JAN,JUL *", "@daily", "0 22-2 * * FRI-MON"} {
+		if got := locateFieldError(valid); got != nil {
+			t.Errorf("locateFieldError(%q) = %+v, expected nil", valid, got)
+		}
+	}
+}
+
+func TestRecentConversions(t *testing.T) {
+	rc := newRecentConversions(3)
+	at := time.Date(2024, time.January, 15, 9, 0, 0, 0, time.UTC)
+
+	if got := rc.list(); len(got) != 0 {
+		t.Fatalf("Expected an empty history, got %v", got)
+	}
+
+	for i := 0; i < 5; i++ {
+		rc.add(fmt.Sprintf("%d * * * *", i), "", at.Add(time.Duration(i)*time.Minute))
+	}
+	got := rc.list()
+	if len(got) != 3 || got[0].Expression != "4 * * * *" || got[2].Expression != "2 * * * *" {
+		t.Fatalf("Expected the 3 newest entries newest first, got %v", got)
+	}
+
+	// A repeat of the newest expression updates it in place
+	rc.add("4 * * * *", "", at.Add(time.Hour))
+	got = rc.list()
+	if len(got) != 3 || got[0].Count != 2 || !got[0].ConvertedAt.Equal(at.Add(time.Hour)) {
+		t.Errorf("Expected the repeat to be folded into the newest entry, got %v", got)
+	}
+}
+
+func TestConvertFrom(t *testing.T) {
+	s, _ := newTestServer(t)
+
+	tests := []struct {
+		name     string
+		body     string
+		expected []string
+	}{
+		{
+			name:     "Pinned in UTC",
+			body:     `{"expression":"0 9 * * 1","timezone":"UTC","from":"2024-01-15T09:00:00Z"}`,
+			expected: []string{"Mon Jan 22 2024 at 09:00:00", "Mon Jan 29 2024 at 09:00:00"},
+		},
+		{
+			name:     "Pinned instant read in the requested zone",
+			body:     `{"expression":"0 9 * * *","timezone":"America/New_York","from":"2024-01-15T13:00:00Z"}`,
+			expected: []string{"Mon Jan 15 2024 at 09:00:00", "Tue Jan 16 2024 at 09:00:00"},
+		},
+		{
+			name:     "Offset from",
+			body:     `{"expression":"30 * * * *","timezone":"UTC","from":"2024-02-29T23:45:00+01:00"}`,
+			expected: []string{"Thu Feb 29 2024 at 23:30:00", "Fri Mar 1 2024 at 00:30:00"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serve(s, "POST", "/api/convert", tt.body)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("Expected status %d but got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+			}
+			var response ConvertResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if !reflect.DeepEqual(response.NextExecutions[:len(tt.expected)], tt.expected) {
+				t.Errorf("Expected executions to start %v, got %v", tt.expected, response.NextExecutions)
+			}
+		})
+	}
+
+	rec := serve(s, "POST", "/api/convert", `{"expression":"0 9 * * *","from":"next monday"}`)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d for an invalid from but got %d", http.StatusBadRequest, rec.Code)
+	}
+}
+
+func TestLastWeekdaySchedule(t *testing.T) {
+	schedule, err := parseExpression("0 18 LW * *")
+	if err != nil {
+		t.Fatalf("parseExpression: %v", err)
+	}
+
+	next := schedule.Next(time.Date(2024, time.February, 1, 0, 0, 0, 0, time.UTC))
+	expected := []string{
+		"2024-02-29 18:00", // leap day, a Thursday
+		"2024-03-29 18:00", // the 31st is a Sunday
+		"2024-04-30 18:00", // a Tuesday
+		"2024-05-31 18:00", // a Friday
+		"2024-06-28 18:00", // the 30th is a Sunday
+		"2024-07-31 18:00",
+		"2024-08-30 18:00", // the 31st is a Saturday
+	}
+	for i, want := range expected {
+		if got := next.Format("2006-01-02 15:04"); got != want {
+			t.Errorf("run %d = %s, want %s", i, got, want)
+		}
+		next = schedule.Next(next)
+	}
+}
+
+func TestDescribeLastWeekday(t *testing.T) {
+	got := generateDescription("0 18 LW * *")
+	if want := "on the last weekday of the month"; !strings.Contains(got, want) {
+		t.Errorf("generateDescription = %q, want it to contain %q", got, want)
+	}
+}
+
+func TestDayTriggers(t *testing.T) {
+	// The 13th of the month or any Friday
+	schedule, err := parseExpression("0 9 13 * 5")
+	if err != nil {
+		t.Fatalf("parseExpression: %v", err)
+	}
+	from := time.Date(2024, time.September, 1, 0, 0, 0, 0, time.UTC)
+	times := nextExecutionTimes(schedule, from, 4)
+
+	expected := []RunTrigger{
+		{"Fri Sep 6 2024 at 09:00:00", TriggerDayOfWeek},
+		{"Fri Sep 13 2024 at 09:00:00", TriggerBoth},
+		{"Fri Sep 20 2024 at 09:00:00", TriggerDayOfWeek},
+		{"Fri Sep 27 2024 at 09:00:00", TriggerDayOfWeek},
+	}
+	got := dayTriggers(schedule, times)
+	if len(got) != len(expected) {
+		t.Fatalf("dayTriggers() = %v, expected %v", got, expected)
+	}
+	for i := range expected {
+		if got[i] != expected[i] {
+			t.Errorf("trigger %d = %+v, expected %+v", i, got[i], expected[i])
+		}
+	}
+
+	times = nextExecutionTimes(schedule, time.Date(2024, time.October, 12, 0, 0, 0, 0, time.UTC), 1)
+	if got := dayTriggers(schedule, times); got[0].Trigger != TriggerDayOfMonth {
+		t.Errorf("Expected Sunday the 13th to be triggered by day-of-month, got %+v", got[0])
+	}
+
+	for _, expression := range []string{"0 9 * * 5", "0 9 13 * *"} {
+		schedule, _ := parseExpression(expression)
+		if got := dayTriggers(schedule, times); got != nil {
+			t.Errorf("dayTriggers(%q) = %v, expected nil", expression, got)
+		}
+	}
+}
+
+func TestWeeklyRuns(t *testing.T) {
+	schedule, err := parseExpression("0 0,12 * * 1-5")
+	if err != nil {
+		t.Fatalf("parseExpression: %v", err)
+	}
+
+	// Friday 2024-01-19 at 06:00: midnight has passed, noon hasn't
+	days := weeklyRuns(schedule, time.Date(2024, time.January, 19, 6, 0, 0, 0, time.UTC))
+	expected := []WeeklyDay{
+		{Date: "2024-01-19", Runs: []string{"12:00"}},
+		{Date: "2024-01-20", Runs: []string{}},
+		{Date: "2024-01-21", Runs: []string{}},
+		{Date: "2024-01-22", Runs: []string{"00:00", "12:00"}},
+		{Date: "2024-01-23", Runs: []string{"00:00", "12:00"}},
+		{Date: "2024-01-24", Runs: []string{"00:00", "12:00"}},
+		{Date: "2024-01-25", Runs: []string{"00:00", "12:00"}},
+	}
+	if !reflect.DeepEqual(days, expected) {
+		t.Errorf("weeklyRuns() = %v, expected %v", days, expected)
+	}
+
+	every, _ := parseExpression("* * * * *")
+	days = weeklyRuns(every, time.Date(2024, time.January, 19, 0, 0, 0, 0, time.UTC))
+	if len(days[0].Runs) != maxWeeklyRunsPerDay || !days[0].Truncated {
+		t.Errorf("Expected every-minute days capped at %d runs, got %d", maxWeeklyRunsPerDay, len(days[0].Runs))
+	}
+}
+
+func TestWeeklyHandlerNotFound(t *testing.T) {
+	s, mock := newTestServer(t)
+	mock.ExpectQuery("SELECT .* FROM cron_expressions e").WillReturnError(sql.ErrNoRows)
+
+	rec := serve(s, "GET", "/api/expressions/42/weekly", "")
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d but got %d", http.StatusNotFound, rec.Code)
+	}
+}
+
+func TestDowDays(t *testing.T) {
+	tests := []struct {
+		item       string
+		cron       []string
+		oneIndexed []string
+	}{
+		{"7", []string{"Sunday"}, []string{"Saturday"}},
+		{"0", []string{"Sunday"}, nil},
+		{"1-5", []string{"Monday", "Tuesday", "Wednesday", "Thursday", "Friday"},
+			[]string{"Sunday", "Monday", "Tuesday", "Wednesday", "Thursday"}},
+		{"MON-FRI", []string{"Monday", "Tuesday", "Wednesday", "Thursday", "Friday"},
+			[]string{"Monday", "Tuesday", "Wednesday", "Thursday", "Friday"}},
+		{"*/3", []string{"Sunday", "Wednesday", "Saturday"}, []string{"Sunday", "Wednesday", "Saturday"}},
+	}
+	for _, tt := range tests {
+		cronDays, ok := dowDays(tt.item, 0)
+		if !ok || !reflect.DeepEqual(dayNames(cronDays), tt.cron) {
+			t.Errorf("dowDays(%q, 0) = %v, expected %v", tt.item, dayNames(cronDays), tt.cron)
+		}
+		oneDays, ok := dowDays(tt.item, 1)
+		if tt.oneIndexed == nil {
+			if ok {
+				t.Errorf("dowDays(%q, 1) expected out of range, got %v", tt.item, dayNames(oneDays))
+			}
+		} else if !ok || !reflect.DeepEqual(dayNames(oneDays), tt.oneIndexed) {
+			t.Errorf("dowDays(%q, 1) = %v, expected %v", tt.item, dayNames(oneDays), tt.oneIndexed)
+		}
+	}
+
+	if _, ok := dowDays("8", 0); ok {
+		t.Error("expected 8 to be out of range")
+	}
+}
+
+func TestExpressionListCache(t *testing.T) {
+	s, mock := newTestServer(t)
+	s.lists = newListCache(time.Minute)
+	now := time.Now()
+	list := regexp.QuoteMeta("FROM cron_expressions e")
+	rows := func() *sqlmock.Rows {
+		return sqlmock.NewRows(expressionRowColumns).AddRow(1, "Nightly", "0 0 * * *", "", nil, true, "", "{}", "dev", now, now, nil)
+	}
+
+	mock.ExpectQuery(list).WillReturnRows(rows())
+	for i := 0; i < 2; i++ {
+		if rec := serve(s, "GET", "/api/expressions", ""); rec.Code != http.StatusOK {
+			t.Fatalf("Expected status %d but got %d", http.StatusOK, rec.Code)
+		}
+	}
+
+	// A delete clears the cache, so the next list goes back to the database
+	mock.ExpectExec(regexp.QuoteMeta("SET deleted_at")).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(list).WillReturnRows(sqlmock.NewRows(expressionRowColumns))
+	serve(s, "DELETE", "/api/expressions/1", "")
+	rec := serve(s, "GET", "/api/expressions", "")
+	if strings.TrimSpace(rec.Body.String()) != "[]" {
+		t.Errorf("Expected an empty list after delete, got %s", rec.Body.String())
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestCompactExpression(t *testing.T) {
+	tests := []struct {
+		expression string
+		expected   string
+	}{
+		{"0,15,30,45 * * * *", "*/15 * * * *"},
+		{"0 0,6,12,18 * * *", "0 */6 * * *"},
+		{"0 9 * * 1,2,3,4,5", "0 9 * * 1-5"},
+		{"0 9 * * MON,TUE,WED", "0 9 * * MON-WED"},
+		{"1,2,3,4,10,20,21 * * * *", "1-4,10,20,21 * * * *"},
+		{"0,1,2,3,4,5,6,7,8,9,10,11,12,13,14,15,16,17,18,19,20,21,22,23 * * * *", "0-23 * * * *"},
+		{"0 0 * 1,2,3,4,5,6,7,8,9,10,11,12 *", "0 0 * * *"},
+		// Evenly spaced but not covering the whole range
+		{"0,15,30 * * * *", "0,15,30 * * * *"},
+		{"5,20,35,50 * * * *", "5,20,35,50 * * * *"},
+		{"0-10,20 * * * *", "0-10,20 * * * *"},
+	}
+	for _, tt := range tests {
+		if got := compactExpression(tt.expression); got != tt.expected {
+			t.Errorf("compactExpression(%q) = %q, expected %q", tt.expression, got, tt.expected)
+		}
+	}
+}
+
+func TestNormalizeHandlerCompacts(t *testing.T) {
+	tests := []struct {
+		expression string
+		expected   string
+	}{
+		{"30,0,45,15 * * * *", "*/15 * * * *"},
+		{"0 9 * * 5,1,3,2,4", "0 9 * * 1-5"},
+		// Compacting day-of-month to "*" would stop it firing on every day
+		// alongside the day-of-week, so the list is kept
+		{"0 0 1,2,3,4,5,6,7,8,9,10,11,12,13,14,15,16,17,18,19,20,21,22,23,24,25,26,27,28,29,30,31 * MON", "0 0 1,2,3,4,5,6,7,8,9,10,11,12,13,14,15,16,17,18,19,20,21,22,23,24,25,26,27,28,29,30,31 * MON"},
+	}
+	for _, tt := range tests {
+		s, _ := newTestServer(t)
+		body, _ := json.Marshal(NormalizeRequest{Expression: tt.expression})
+		rec := serve(s, "POST", "/api/normalize", string(body))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%q: expected status %d but got %d", tt.expression, http.StatusOK, rec.Code)
+		}
+		var response NormalizeResponse
+		json.NewDecoder(rec.Body).Decode(&response)
+		if response.Normalized != tt.expected {
+			t.Errorf("%q: normalized to %q, expected %q", tt.expression, response.Normalized, tt.expected)
+		}
+	}
+}
+
+func TestCanonicalExpressionUnparseable(t *testing.T) {
+	// The normalized form fails to parse (7 is out of range) while its
+	// compaction would, so compaction must be skipped rather than compared
+	expression := "* * * * 0,1,2,3,4,5,6,7"
+	if got := canonicalExpression(expression); got != expression {
+		t.Errorf("canonicalExpression(%q) = %q, expected it unchanged", expression, got)
+	}
+}
+
+func TestLongestGap(t *testing.T) {
+	from := time.Date(2024, time.January, 19, 6, 0, 0, 0, time.UTC) // Friday
+	weekdays, _ := parseExpression("0 9 * * 1-5")
+	start, end, runs, truncated := longestGap(weekdays, from, from.Add(14*24*time.Hour))
+	if truncated || runs != 10 {
+		t.Errorf("Expected 10 runs untruncated, got %d (truncated %v)", runs, truncated)
+	}
+	if !start.Equal(time.Date(2024, time.January, 19, 9, 0, 0, 0, time.UTC)) || end.Sub(start) != 72*time.Hour {
+		t.Errorf("Expected the weekend gap from Friday 09:00, got %s to %s", start, end)
+	}
+
+	yearly, _ := parseExpression("0 0 1 1 *")
+	if start, _, runs, _ := longestGap(yearly, from, from.Add(defaultGapWindow)); !start.IsZero() || runs != 0 {
+		t.Errorf("Expected no gap for a yearly schedule in 30 days, got %s after %d runs", start, runs)
+	}
+}
+
+func TestMaxGapHandlerWindow(t *testing.T) {
+	for _, window := range []string{"abc", "0d", "400d", "-5h"} {
+		s, _ := newTestServer(t)
+		rec := serve(s, "GET", "/api/expressions/1/max-gap?window="+window, "")
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("window=%s: expected status %d but got %d", window, http.StatusBadRequest, rec.Code)
+		}
+	}
+
+	s, mock := newTestServer(t)
+	now := time.Now()
+	mock.ExpectQuery("SELECT .* FROM cron_expressions e").
+		WillReturnRows(sqlmock.NewRows(expressionRowColumns).AddRow(1, "Heartbeat", "*/10 * * * *", "", nil, true, "", "{}", "dev", now, now, nil))
+	rec := serve(s, "GET", "/api/expressions/1/max-gap?window=2d", "")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"maxGapSeconds":600`) {
+		t.Errorf("Expected a 10 minute gap, got %d %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestHourlyDistribution(t *testing.T) {
+	london, _ := time.LoadLocation("Europe/London")
+	from := time.Date(2024, time.January, 15, 0, 0, 0, 0, london) // Monday
+	schedule, _ := parseExpression("30 9,17 * * 1-5")
+	hours, total, truncated := hourlyDistribution(schedule, from, from.Add(7*24*time.Hour))
+	if truncated || total != 10 || hours[9] != 5 || hours[17] != 5 {
+		t.Errorf("Expected 5 runs at 09:00 and 17:00, got %v (total %d, truncated %v)", hours, total, truncated)
+	}
+
+	yearly, _ := parseExpression("0 0 1 1 *")
+	if hours, total, _ := hourlyDistribution(yearly, from, from.Add(defaultDistributionWindow)); total != 0 || hours != [24]int{} {
+		t.Errorf("Expected no runs for a yearly schedule in a week, got %v", hours)
+	}
+}
+
+func TestHourlyDistributionHandler(t *testing.T) {
+	for _, window := range []string{"abc", "0d", "91d", "-5h"} {
+		s, _ := newTestServer(t)
+		rec := serve(s, "GET", "/api/expressions/1/hourly-distribution?window="+window, "")
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("window=%s: expected status %d but got %d", window, http.StatusBadRequest, rec.Code)
+		}
+	}
+
+	s, mock := newTestServer(t)
+	now := time.Now()
+	mock.ExpectQuery("SELECT .* FROM cron_expressions e").
+		WillReturnRows(sqlmock.NewRows(expressionRowColumns).AddRow(1, "Every six", "0 */6 * * *", "", nil, true, "Asia/Tokyo", "{}", "dev", now, now, nil))
+	rec := serve(s, "GET", "/api/expressions/1/hourly-distribution?window=2d", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status %d but got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	var response HourlyDistributionResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	for hour, count := range response.Hours {
+		if (hour%6 == 0) != (count == 2) || (hour%6 != 0 && count != 0) {
+			t.Errorf("Expected 2 runs at hours divisible by 6 only, got %v", response.Hours)
+			break
+		}
+	}
+	if response.Total != 8 || response.Timezone != "Asia/Tokyo" {
+		t.Errorf("Expected 8 runs in Asia/Tokyo, got %+v", response)
+	}
+}
+
+func TestEnvironmentValidation(t *testing.T) {
+	s, _ := newTestServer(t)
+	rec := serve(s, "POST", "/api/expressions", `{"name":"Nightly","expression":"0 0 * * *","environment":"qa"}`)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d for an unknown environment but got %d", http.StatusBadRequest, rec.Code)
+	}
+
+	rec = serve(s, "GET", "/api/expressions?environment=qa", "")
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d for an unknown environment filter but got %d", http.StatusBadRequest, rec.Code)
+	}
+
+	s, mock := newTestServer(t)
+	mock.ExpectQuery(regexp.QuoteMeta("e.environment = $1")).WithArgs("prod").
+		WillReturnRows(sqlmock.NewRows(expressionRowColumns))
+	if rec := serve(s, "GET", "/api/expressions?environment=prod", ""); rec.Code != http.StatusOK {
+		t.Errorf("Expected status %d but got %d", http.StatusOK, rec.Code)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestStaggerHandler(t *testing.T) {
+	tests := []struct {
+		base     string
+		count    int
+		expected []string
+	}{
+		{"3 * * * *", 3, []string{"3 * * * *", "23 * * * *", "43 * * * *"}},
+		{"@hourly", 4, []string{"0 * * * *", "15 * * * *", "30 * * * *", "45 * * * *"}},
+		{"*/15 * * * *", 3, []string{"0-59/15 * * * *", "5-59/15 * * * *", "10-59/15 * * * *"}},
+		{"30 */6 * * *", 2, []string{"30 0-23/6 * * *", "30 3-23/6 * * *"}},
+		{"0 22 * * 1-5", 3, []string{"0 22 * * 1-5", "0 6 * * 1-5", "0 14 * * 1-5"}},
+	}
+	for _, tt := range tests {
+		s, _ := newTestServer(t)
+		body, _ := json.Marshal(StaggerRequest{Base: tt.base, Count: tt.count})
+		rec := serve(s, "POST", "/api/stagger", string(body))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%q: expected status %d but got %d: %s", tt.base, http.StatusOK, rec.Code, rec.Body.String())
+		}
+		var response StaggerResponse
+		json.NewDecoder(rec.Body).Decode(&response)
+		got := []string{}
+		for _, exp := range response.Expressions {
+			if exp.Description == "" {
+				t.Errorf("%q: missing description for %q", tt.base, exp.Expression)
+			}
+			got = append(got, exp.Expression)
+		}
+		if !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("%q x%d = %v, expected %v", tt.base, tt.count, got, tt.expected)
+		}
+	}
+}
+
+func TestStaggerHandlerRejects(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"invalid expression", `{"base":"61 * * * *","count":2}`},
+		{"irregular cadence", `{"base":"0,7 * * * *","count":2}`},
+		{"uneven step", `{"base":"*/7 * * * *","count":2}`},
+		{"zero count", `{"base":"0 * * * *","count":0}`},
+		{"count above period", `{"base":"*/5 * * * *","count":6}`},
+	}
+	for _, tt := range tests {
+		s, _ := newTestServer(t)
+		if rec := serve(s, "POST", "/api/stagger", tt.body); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status %d but got %d", tt.name, http.StatusBadRequest, rec.Code)
+		}
+	}
+}
+
+func explainStandards(t *testing.T, expression string) ExplainStandardsResponse {
+	t.Helper()
+	s, _ := newTestServer(t)
+	body, _ := json.Marshal(ExplainStandardsRequest{Expression: expression})
+	rec := serve(s, "POST", "/api/explain/standards", string(body))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("%q: expected status %d but got %d: %s", expression, http.StatusOK, rec.Code, rec.Body.String())
+	}
+	var response ExplainStandardsResponse
+	json.NewDecoder(rec.Body).Decode(&response)
+	return response
+}
+
+func TestExplainStandardsWeekdays(t *testing.T) {
+	response := explainStandards(t, "0 9 * * 1-5")
+	if response.Agree {
+		t.Fatal("Expected Quartz's 1-7 day numbering to diverge from Unix")
+	}
+
+	equivalents := map[string]string{}
+	for _, reading := range response.Readings {
+		if !reading.Valid {
+			t.Fatalf("Expected %s to accept the expression: %s", reading.Standard, reading.Error)
+		}
+		equivalents[reading.Standard] = reading.Equivalent
+	}
+	expected := map[string]string{
+		StandardUnix:    "0 9 * * 1-5",
+		StandardJenkins: "0 9 * * 1-5",
+		StandardQuartz:  "0 9 * * 0-4",
+	}
+	for standard, equivalent := range expected {
+		if equivalents[standard] != equivalent {
+			t.Errorf("%s equivalent = %q, expected %q", standard, equivalents[standard], equivalent)
+		}
+	}
+
+	for _, comparison := range response.Comparisons {
+		diverges := comparison.A == StandardQuartz || comparison.B == StandardQuartz
+		if comparison.Equivalent == diverges {
+			t.Errorf("%s vs %s: equivalent = %v", comparison.A, comparison.B, comparison.Equivalent)
+		}
+		if diverges && comparison.FirstDivergence == nil {
+			t.Errorf("%s vs %s: missing first divergence", comparison.A, comparison.B)
+		}
+	}
+}
+
+func TestExplainStandardsReadings(t *testing.T) {
+	tests := []struct {
+		expression string
+		valid      map[string]bool
+	}{
+		{"0 12 * * *", map[string]bool{StandardUnix: true, StandardJenkins: true, StandardQuartz: true}},
+		{"H/15 * * * 7", map[string]bool{StandardUnix: false, StandardJenkins: true, StandardQuartz: false}},
+		{"0 0 LW * *", map[string]bool{StandardUnix: true, StandardJenkins: false, StandardQuartz: false}},
+		{"0 0 12 ? * MON", map[string]bool{StandardUnix: false, StandardJenkins: false, StandardQuartz: true}},
+	}
+	for _, tt := range tests {
+		response := explainStandards(t, tt.expression)
+		for _, reading := range response.Readings {
+			if reading.Valid != tt.valid[reading.Standard] {
+				t.Errorf("%q under %s: valid = %v (%s), expected %v", tt.expression, reading.Standard, reading.Valid, reading.Error, tt.valid[reading.Standard])
+			}
+		}
+	}
+}
+
+func TestDBQueryTimeout(t *testing.T) {
+	s, mock := newTestServer(t)
+	s.config.DBQueryTimeout = 20 * time.Millisecond
+	mock.ExpectQuery("SELECT .* FROM cron_expressions e").
+		WillDelayFor(time.Second).
+		WillReturnRows(sqlmock.NewRows(expressionRowColumns))
+
+	rec := serve(s, "GET", "/api/expressions/1", "")
+	if rec.Code != http.StatusGatewayTimeout {
+		t.Errorf("Expected status %d but got %d: %s", http.StatusGatewayTimeout, rec.Code, rec.Body.String())
+	}
+}
+
+func TestReadyzTimeout(t *testing.T) {
+	mockDB, mock, err := sqlmock.New(sqlmock.MonitorPingsOption(true))
+	if err != nil {
+		t.Fatalf("Failed to create sqlmock: %v", err)
+	}
+	defer mockDB.Close()
+	s := NewServer(newStore(mockDB, nil), loadConfig())
+	s.config.ReadyzTimeout = 20 * time.Millisecond
+	mock.ExpectPing().WillDelayFor(time.Second)
+
+	start := time.Now()
+	rec := serve(s, "GET", "/readyz", "")
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status %d but got %d: %s", http.StatusServiceUnavailable, rec.Code, rec.Body.String())
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Expected /readyz to give up promptly, took %s", elapsed)
+	}
+}
+
+func TestTimelineHandler(t *testing.T) {
+	s, mock := newTestServer(t)
+	now := time.Now()
+	snapshot := func(expression string, enabled bool) []byte {
+		b, _ := json.Marshal(CronExpression{ID: 5, Name: "Nightly", Expression: expression, Enabled: enabled, Environment: "dev"})
+		return b
+	}
+	mock.ExpectQuery(regexp.QuoteMeta("FROM expression_audit")).WithArgs("5").
+		WillReturnRows(sqlmock.NewRows([]string{"action", "actor", "claimed_actor", "snapshot", "created_at"}).
+			AddRow(AuditCreate, "10.0.0.1", "alice", snapshot("0 0 * * *", true), now).
+			AddRow(AuditUpdate, "10.0.0.2", "bob", snapshot("0 3 * * *", true), now.Add(time.Hour)).
+			AddRow(AuditPause, "10.0.0.2", "", snapshot("0 3 * * *", false), now.Add(2*time.Hour)).
+			AddRow(AuditDelete, "10.0.0.1", "alice", nil, now.Add(3*time.Hour)))
+
+	rec := serve(s, "GET", "/api/expressions/5/timeline", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status %d but got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	var timeline []TimelineEntry
+	json.NewDecoder(rec.Body).Decode(&timeline)
+
+	expected := [][]string{
+		{`created as "Nightly" with expression "0 0 * * *"`},
+		{`expression changed from "0 0 * * *" to "0 3 * * *"`},
+		{"paused"},
+		{"deleted"},
+	}
+	if len(timeline) != len(expected) {
+		t.Fatalf("Expected %d entries, got %d", len(expected), len(timeline))
+	}
+	for i, entry := range timeline {
+		if !reflect.DeepEqual(entry.Changes, expected[i]) {
+			t.Errorf("Entry %d (%s by %s): changes = %q, expected %q", i, entry.Action, entry.Actor, entry.Changes, expected[i])
+		}
+	}
+	if timeline[1].Actor != "10.0.0.2" || timeline[1].ClaimedActor != "bob" {
+		t.Errorf("Expected actor 10.0.0.2 claiming bob, got %+v", timeline[1])
+	}
+}
+
+func TestAuditActor(t *testing.T) {
+	s, mock := newTestServer(t)
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO expression_audit")).
+		WithArgs(5, AuditDelete, "192.0.2.1", "alice", nil, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	// The header is recorded, but never as the actor
+	req := httptest.NewRequest("DELETE", "/api/expressions/5", nil)
+	req.RemoteAddr = "192.0.2.1:4000"
+	req.Header.Set("X-Actor", "alice")
+	if actor := s.auditActor(req); actor != "192.0.2.1" {
+		t.Errorf("auditActor() = %q, expected the client address", actor)
+	}
+	s.recordAudit(context.Background(), req, 5, AuditDelete, nil)
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unmet expectations: %v", err)
+	}
+
+	req.Header.Set("X-Actor", strings.Repeat("a", 300))
+	if claimed := claimedActor(req); len(claimed) != maxClaimedActor {
+		t.Errorf("Expected the claimed actor cut to %d bytes, got %d", maxClaimedActor, len(claimed))
+	}
+}
+
+func TestTimelineHandlerNotFound(t *testing.T) {
+	s, mock := newTestServer(t)
+	mock.ExpectQuery(regexp.QuoteMeta("FROM expression_audit")).
+		WillReturnRows(sqlmock.NewRows([]string{"action", "actor", "claimed_actor", "snapshot", "created_at"}))
+	mock.ExpectQuery("SELECT .* FROM cron_expressions e").WillReturnError(sql.ErrNoRows)
+
+	if rec := serve(s, "GET", "/api/expressions/9/timeline", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d but got %d", http.StatusNotFound, rec.Code)
+	}
+}
+
+func TestFieldFilterMatches(t *testing.T) {
+	tests := []struct {
+		field, value string
+		expression   string
+		expected     bool
+	}{
+		{"hour", "9", "0 9 * * *", true},
+		{"hour", "9", "0 8-17 * * *", true},
+		{"hour", "9", "0 */2 * * *", false},
+		{"hour", "9", "0 * * * *", true},
+		{"hour", "9", "0 1,9,17 * * *", true},
+		{"minute", "30", "*/15 * * * *", true},
+		{"dow", "MON", "0 9 * * 1-5", true},
+		{"day-of-week", "0", "0 9 * * MON-FRI", false},
+		{"month", "feb", "0 0 1 NOV-FEB *", true},
+		{"dom", "15", "0 9 15W * *", true},
+		{"dom", "31", "0 9 LW * *", false},
+		{"hour", "9", "not an expression", false},
+	}
+	for _, tt := range tests {
+		filter, err := parseFieldFilter(url.Values{"field": {tt.field}, "value": {tt.value}})
+		if err != nil {
+			t.Fatalf("parseFieldFilter(%s=%s): %v", tt.field, tt.value, err)
+		}
+		if got := filter.matches(tt.expression); got != tt.expected {
+			t.Errorf("%s=%s on %q = %v, expected %v", tt.field, tt.value, tt.expression, got, tt.expected)
+		}
+	}
+
+	for _, query := range []url.Values{{"field": {"hour"}}, {"field": {"second"}, "value": {"1"}}, {"field": {"hour"}, "value": {"24"}}} {
+		if _, err := parseFieldFilter(query); err == nil {
+			t.Errorf("Expected an error for %v", query)
+		}
+	}
+}
+
+func TestGetExpressionsFieldFilter(t *testing.T) {
+	s, mock := newTestServer(t)
+	now := time.Now()
+	mock.ExpectQuery("SELECT .* FROM cron_expressions e").
+		WillReturnRows(sqlmock.NewRows(expressionRowColumns).
+			AddRow(1, "Morning", "0 9 * * *", "", nil, true, "", "{}", "dev", now, now, nil).
+			AddRow(2, "Evening", "0 18 * * *", "", nil, true, "", "{}", "dev", now, now, nil).
+			AddRow(3, "Office hours", "*/30 8-17 * * 1-5", "", nil, true, "", "{}", "dev", now, now, nil))
+
+	rec := serve(s, "GET", "/api/expressions?field=hour&value=9&limit=1&offset=1", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status %d but got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	var expressions []CronExpression
+	json.NewDecoder(rec.Body).Decode(&expressions)
+	if len(expressions) != 1 || expressions[0].ID != 3 {
+		t.Errorf("Expected only expression 3 on the second page, got %+v", expressions)
+	}
+
+	if rec := serve(s, "GET", "/api/expressions?limit=0", ""); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d for limit=0 but got %d", http.StatusBadRequest, rec.Code)
+	}
+}
+
+// serveAdmin sends an authenticated request to an admin endpoint
+func serveAdmin(s *Server, method, path, body string) *httptest.ResponseRecorder {
+	s.config.APIToken = "secret"
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer secret")
+	rec := httptest.NewRecorder()
+	s.handler().ServeHTTP(rec, req)
+	return rec
+}
+
+func TestRestoreHandlerValidation(t *testing.T) {
+	s, _ := newTestServer(t)
+	body := `{"mode":"replace","snapshot":{"version":1,"categories":[],"expressions":[
+		{"name":"Good","expression":"0 0 * * *"},
+		{"name":"Bad","expression":"0 25 * * *"},
+		{"name":"Orphan","expression":"0 1 * * *","category_id":4}
+	]}}`
+	rec := serveAdmin(s, "POST", "/api/admin/restore", body)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status %d but got %d", http.StatusBadRequest, rec.Code)
+	}
+	var response ErrorResponse
+	json.NewDecoder(rec.Body).Decode(&response)
+	if len(response.Error.Problems) != 2 {
+		t.Errorf("Expected 2 problems, got %q", response.Error.Problems)
+	}
+
+	if rec := serveAdmin(s, "POST", "/api/admin/restore", `{"mode":"upsert","snapshot":{"version":1}}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d for an unknown mode but got %d", http.StatusBadRequest, rec.Code)
+	}
+
+	// Names are unique among live expressions, so a snapshot can't repeat one
+	rec = serveAdmin(s, "POST", "/api/admin/restore", `{"mode":"merge","snapshot":{"version":1,"expressions":[
+		{"name":"Nightly","expression":"0 0 * * *"},
+		{"name":"Nightly","expression":"0 1 * * *"}
+	]}}`)
+	response = ErrorResponse{}
+	json.NewDecoder(rec.Body).Decode(&response)
+	if rec.Code != http.StatusBadRequest || len(response.Error.Problems) != 1 || !strings.Contains(response.Error.Problems[0], "duplicates expressions[0]") {
+		t.Errorf("Expected a duplicate name problem, got %d %q", rec.Code, response.Error.Problems)
+	}
+
+	// MIN_INTERVAL_SECONDS applies to restored expressions too
+	s.config.MinInterval = 5 * time.Minute
+	rec = serveAdmin(s, "POST", "/api/admin/restore", `{"mode":"merge","snapshot":{"version":1,"expressions":[
+		{"name":"Frequent","expression":"* * * * *"}
+	]}}`)
+	response = ErrorResponse{}
+	json.NewDecoder(rec.Body).Decode(&response)
+	if rec.Code != http.StatusBadRequest || len(response.Error.Problems) != 1 || !strings.Contains(response.Error.Problems[0], "runs too often") {
+		t.Errorf("Expected a min interval problem, got %d %q", rec.Code, response.Error.Problems)
+	}
+}
+
+func TestRestoreHandlerMerge(t *testing.T) {
+	s, mock := newTestServer(t)
+	now := time.Now()
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta("INSERT INTO categories")).WithArgs("Backups", "", sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(12))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT id, name FROM cron_expressions")).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(3, "Nightly"))
+	mock.ExpectQuery(regexp.QuoteMeta("UPDATE cron_expressions")).
+		WithArgs("0 2 * * *", "", 12, true, sqlmock.AnyArg(), sqlmock.AnyArg(), "dev", sqlmock.AnyArg(), 3).
+		WillReturnRows(sqlmock.NewRows(expressionRowColumns).
+			AddRow(3, "Nightly", "0 2 * * *", "", 12, true, "", "{}", "dev", now, now, now))
+	mock.ExpectQuery(regexp.QuoteMeta("INSERT INTO cron_expressions")).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "updated_at"}).AddRow(20, now, now))
+	mock.ExpectExec(regexp.QuoteMeta("SET enabled = false")).WithArgs(20).WillReturnResult(sqlmock.NewResult(0, 1))
+	// Leaving enabled out restores the expression enabled
+	mock.ExpectQuery(regexp.QuoteMeta("INSERT INTO cron_expressions")).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "updated_at"}).AddRow(21, now, now))
+	mock.ExpectCommit()
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM cron_expressions")).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+	for _, change := range []struct {
+		id     int
+		action string
+	}{{3, AuditUpdate}, {20, AuditCreate}, {21, AuditCreate}} {
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO expression_audit")).
+			WithArgs(change.id, change.action, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+
+	body := `{"mode":"merge","snapshot":{"version":1,"categories":[{"id":1,"name":"Backups"}],"expressions":[
+		{"name":"Nightly","expression":"0 2 * * *","category_id":1,"enabled":true},
+		{"name":"Weekly","expression":"0 3 * * 0","enabled":false},
+		{"name":"Hourly","expression":"0 * * * *"}
+	]}}`
+	rec := serveAdmin(s, "POST", "/api/admin/restore", body)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status %d but got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	var summary RestoreSummary
+	json.NewDecoder(rec.Body).Decode(&summary)
+	expected := RestoreSummary{Mode: RestoreMerge, Categories: 1, Created: 2, Updated: 1}
+	if summary != expected {
+		t.Errorf("Summary = %+v, expected %+v", summary, expected)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestRestoreHandlerReplace(t *testing.T) {
+	s, mock := newTestServer(t)
+	now := time.Now()
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta("SET deleted_at = $1 WHERE deleted_at IS NULL RETURNING id")).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3).AddRow(4))
+	mock.ExpectQuery(regexp.QuoteMeta("INSERT INTO cron_expressions")).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "updated_at"}).AddRow(20, now, now))
+	mock.ExpectCommit()
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM cron_expressions")).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	for _, change := range []struct {
+		id     int
+		action string
+	}{{3, AuditDelete}, {4, AuditDelete}, {20, AuditCreate}} {
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO expression_audit")).
+			WithArgs(change.id, change.action, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+
+	rec := serveAdmin(s, "POST", "/api/admin/restore", `{"mode":"replace","snapshot":{"version":1,"expressions":[
+		{"name":"Nightly","expression":"0 0 * * *"}
+	]}}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status %d but got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	var summary RestoreSummary
+	json.NewDecoder(rec.Body).Decode(&summary)
+	if expected := (RestoreSummary{Mode: RestoreReplace, Created: 1, Deleted: 2}); summary != expected {
+		t.Errorf("Summary = %+v, expected %+v", summary, expected)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestSnapshotHandler(t *testing.T) {
+	s, mock := newTestServer(t)
+	now := time.Now()
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta("FROM categories")).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "description", "created_at", "updated_at"}).
+			AddRow(1, "Backups", "", now, now))
+	mock.ExpectQuery("SELECT .* FROM cron_expressions e").
+		WillReturnRows(sqlmock.NewRows(expressionRowColumns).
+			AddRow(3, "Nightly", "0 0 * * *", "", 1, false, "", "{}", "dev", now, now, now))
+	mock.ExpectRollback()
+
+	rec := serveAdmin(s, "POST", "/api/admin/snapshot", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status %d but got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	var snapshot Snapshot
+	json.NewDecoder(rec.Body).Decode(&snapshot)
+	if len(snapshot.Categories) != 1 || len(snapshot.Expressions) != 1 {
+		t.Fatalf("Unexpected snapshot: %+v", snapshot)
+	}
+	if exp := snapshot.Expressions[0]; exp.Name != "Nightly" || exp.Enabled == nil || *exp.Enabled {
+		t.Errorf("Expected Nightly saved as paused, got %+v", exp)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestFitHandler(t *testing.T) {
+	tests := []struct {
+		name        string
+		req         FitRequest
+		expected    string
+		approximate bool
+		extraRuns   int
+	}{
+		{"weekday mornings",
+			FitRequest{Datetimes: []string{"2026-03-02T09:00:00Z", "2026-03-03T09:00:00Z", "2026-03-04T09:00:00Z", "2026-03-05T09:00:00Z", "2026-03-06T09:00:00Z"}, Timezone: "UTC"},
+			"0 9 * * 1-5", false, 0},
+		{"first of the month",
+			FitRequest{Datetimes: []string{"2026-03-01T00:00:00Z", "2026-01-01T00:00:00Z", "2026-02-01T00:00:00Z"}, Timezone: "UTC"},
+			"0 0 1 1-3 *", false, 0},
+		{"mixed times of day",
+			FitRequest{Datetimes: []string{"2026-03-02T09:00:00Z", "2026-03-02T17:30:00Z"}, Timezone: "UTC"},
+			"*/30 9,17 2 3 *", true, 2},
+		{"converted to timezone",
+			FitRequest{Datetimes: []string{"2026-03-02T14:00:00Z"}, Timezone: "America/New_York"},
+			"0 9 2 3 *", false, 0},
+	}
+	for _, tt := range tests {
+		s, _ := newTestServer(t)
+		body, _ := json.Marshal(tt.req)
+		rec := serve(s, "POST", "/api/fit", string(body))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: expected status %d but got %d: %s", tt.name, http.StatusOK, rec.Code, rec.Body.String())
+		}
+		var response FitResponse
+		json.NewDecoder(rec.Body).Decode(&response)
+		if response.Expression != tt.expected {
+			t.Errorf("%s: expected %q but got %q", tt.name, tt.expected, response.Expression)
+		}
+		if response.Approximate != tt.approximate || response.ExtraRuns != tt.extraRuns {
+			t.Errorf("%s: expected approximate=%v extraRuns=%d but got %v %d", tt.name, tt.approximate, tt.extraRuns, response.Approximate, response.ExtraRuns)
+		}
+		if response.Description == "" {
+			t.Errorf("%s: missing description", tt.name)
+		}
+	}
+}
+
+func TestFitHandlerRejects(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		expected int
+	}{
+		{"no datetimes", `{"datetimes":[]}`, http.StatusBadRequest},
+		{"bad datetime", `{"datetimes":["tomorrow"]}`, http.StatusBadRequest},
+		{"bad timezone", `{"datetimes":["2026-03-02T09:00:00Z"],"timezone":"Mars/Olympus"}`, http.StatusBadRequest},
+		{"seconds", `{"datetimes":["2026-03-02T09:00:30Z"]}`, http.StatusUnprocessableEntity},
+	}
+	for _, tt := range tests {
+		s, _ := newTestServer(t)
+		if rec := serve(s, "POST", "/api/fit", tt.body); rec.Code != tt.expected {
+			t.Errorf("%s: expected status %d but got %d", tt.name, tt.expected, rec.Code)
+		}
+	}
+}
+
+func TestNegotiateEncoding(t *testing.T) {
+	tests := []struct {
+		accept   string
+		expected string
+	}{
+		{"", "application/json"},
+		{"*/*", "application/json"},
+		{"application/msgpack", "application/msgpack"},
+		{"text/html, application/msgpack", "application/msgpack"},
+		{"application/json, application/msgpack", "application/json"},
+		{"application/json;q=0.5, application/msgpack", "application/msgpack"},
+		{"application/msgpack;q=0", "application/json"},
+		{"application/x-protobuf", "application/json"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", "/api/expressions", nil)
+		req.Header.Set("Accept", tt.accept)
+		if got := negotiateEncoding(req).contentType(); got != tt.expected {
+			t.Errorf("Accept %q: expected %s but got %s", tt.accept, tt.expected, got)
+		}
+	}
+}
+
+func TestConvertMsgpack(t *testing.T) {
+	s, _ := newTestServer(t)
+	req := httptest.NewRequest("POST", "/api/convert", strings.NewReader(`{"expression":"0 9 * * 1-5"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/msgpack")
+	rec := httptest.NewRecorder()
+	s.handler().ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status %d but got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	if got := rec.Header().Get("Content-Type"); got != "application/msgpack" {
+		t.Fatalf("Expected msgpack content type but got %q", got)
+	}
+
+	var response map[string]interface{}
+	if err := msgpack.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to decode msgpack: %v", err)
+	}
+	if response["expression"] != "0 9 * * 1-5" || response["description"] == "" {
+		t.Errorf("Unexpected response: %v", response)
+	}
+}
+
+// BenchmarkResponseEncoding compares encoding a large expression list as JSON
+// and as msgpack
+func BenchmarkResponseEncoding(b *testing.B) {
+	now := time.Now()
+	expressions := make([]CronExpression, 1000)
+	for i := range expressions {
+		expressions[i] = CronExpression{
+			ID:          i + 1,
+			Name:        fmt.Sprintf("Job %d", i),
+			Expression:  "*/15 9-17 * * 1-5",
+			Description: "Runs every 15 minutes during office hours",
+			Enabled:     true,
+			Timezone:    "Europe/London",
+			Metadata:    json.RawMessage(`{"team":"payments"}`),
+			Environment: "prod",
+			CreatedAt:   now,
+			UpdatedAt:   now,
+		}
+	}
+	for _, encoding := range responseEncodings {
+		b.Run(encoding.contentType(), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if err := encoding.encode(io.Discard, expressions, false); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestDedupHandler(t *testing.T) {
+	now := time.Now()
+	storedRows := func() *sqlmock.Rows {
+		return sqlmock.NewRows(expressionRowColumns).
+			AddRow(1, "Nightly", "0 0 * * *", "", nil, true, "", "{}", "dev", now, now, nil).
+			AddRow(2, "Nightly copy", "@daily", "", nil, true, "", "{}", "dev", now, now, nil).
+			AddRow(3, "Nightly prod", "0 0 * * *", "", nil, true, "", "{}", "prod", now, now, nil).
+			AddRow(4, "Legacy", "0 25 * * *", "", nil, true, "", "{}", "dev", now, now, nil).
+			AddRow(5, "Every day", "0 0 * * 0-6", "", nil, true, "", "{}", "dev", now, now, nil).
+			AddRow(6, "Hourly", "0 * * * *", "", nil, true, "", "{}", "dev", now, now, nil).
+			AddRow(7, "Quarterly", "*/15 * * * *", "", nil, true, "", "{}", "prod", now, now, nil).
+			AddRow(8, "Quarterly list", "0,15,30,45 * * * *", "", nil, true, "", "{}", "prod", now, now, nil)
+	}
+	expected := DuplicateGroup{
+		Environment: "dev",
+		Kept:        DedupMember{ID: 1, Name: "Nightly", Expression: "0 0 * * *"},
+		Duplicates: []DedupMember{
+			{ID: 2, Name: "Nightly copy", Expression: "@daily"},
+			{ID: 5, Name: "Every day", Expression: "0 0 * * 0-6"},
+		},
+	}
+	checkGroups := func(t *testing.T, report DedupReport) {
+		t.Helper()
+		if report.Checked != 8 || report.Skipped != 1 || len(report.Groups) != 2 {
+			t.Fatalf("Unexpected report: %+v", report)
+		}
+		if quarterly := report.Groups[1]; quarterly.Kept.ID != 7 || len(quarterly.Duplicates) != 1 || quarterly.Duplicates[0].ID != 8 {
+			t.Errorf("Expected 8 grouped with 7, got %+v", quarterly)
+		}
+		group := report.Groups[0]
+		group.Timezone = ""
+		group.Kept.CreatedAt = time.Time{}
+		for i := range group.Duplicates {
+			group.Duplicates[i].CreatedAt = time.Time{}
+		}
+		if !reflect.DeepEqual(group, expected) {
+			t.Errorf("Group = %+v, expected %+v", group, expected)
+		}
+	}
+
+	t.Run("report", func(t *testing.T) {
+		s, mock := newTestServer(t)
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT .* FROM cron_expressions e").WillReturnRows(storedRows())
+		mock.ExpectRollback()
+
+		rec := serveAdmin(s, "POST", "/api/admin/dedup", "")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected status %d but got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+		}
+		var report DedupReport
+		json.NewDecoder(rec.Body).Decode(&report)
+		checkGroups(t, report)
+		if report.Applied || report.Removed != 0 {
+			t.Errorf("Expected nothing applied, got %+v", report)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("Unfulfilled expectations: %v", err)
+		}
+	})
+
+	t.Run("apply", func(t *testing.T) {
+		s, mock := newTestServer(t)
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT .* FROM cron_expressions e .* FOR UPDATE").WillReturnRows(storedRows())
+		mock.ExpectExec(regexp.QuoteMeta("SET deleted_at = $1")).
+			WithArgs(sqlmock.AnyArg(), pq.Array([]int64{2, 5, 8})).
+			WillReturnResult(sqlmock.NewResult(0, 3))
+		mock.ExpectCommit()
+		for _, id := range []int{2, 5, 8} {
+			mock.ExpectExec(regexp.QuoteMeta("INSERT INTO expression_audit")).
+				WithArgs(id, AuditMerge, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+		}
+
+		rec := serveAdmin(s, "POST", "/api/admin/dedup?apply=true", "")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected status %d but got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+		}
+		var report DedupReport
+		json.NewDecoder(rec.Body).Decode(&report)
+		checkGroups(t, report)
+		if !report.Applied || report.Removed != 3 {
+			t.Errorf("Expected 3 removed, got %+v", report)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("Unfulfilled expectations: %v", err)
+		}
+	})
+}
+
+func TestSameSchedule(t *testing.T) {
+	tests := []struct {
+		a, b        string
+		same, known bool
+	}{
+		{"*/15 * * * *", "0,15,30,45 * * * *", true, true},
+		{"0 0 * * *", "0 0 * * 0-6", true, true},
+		{"*/15 * * * *", "*/10 * * * *", false, true},
+		// Equivalent, but the fields differ and the walk runs out first
+		{"* * * * *", "* * 1-31 * *", false, false},
+	}
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, tt := range tests {
+		a, _ := parseExpression(tt.a)
+		b, _ := parseExpression(tt.b)
+		same, known := sameSchedule(a, b, from)
+		if same != tt.same || known != tt.known {
+			t.Errorf("sameSchedule(%q, %q) = %v, %v, expected %v, %v", tt.a, tt.b, same, known, tt.same, tt.known)
+		}
+	}
+}
+
+func TestMinIntervalPolicy(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		expression string
+	}{
+		{"create every minute", "POST", "/api/expressions", `{"name":"Busy","expression":"* * * * *"}`, ""},
+		{"create close pair", "POST", "/api/expressions", `{"name":"Twice","expression":"0,2 9 * * *"}`, ""},
+		{"update every two minutes", "PUT", "/api/expressions/3", `{"name":"Busy","expression":"*/2 * * * *"}`, ""},
+	}
+	for _, tt := range tests {
+		s, mock := newTestServer(t)
+		s.config.MinInterval = 5 * time.Minute
+		rec := serve(s, tt.method, tt.path, tt.body)
+		if rec.Code != http.StatusUnprocessableEntity {
+			t.Errorf("%s: expected status %d but got %d: %s", tt.name, http.StatusUnprocessableEntity, rec.Code, rec.Body.String())
+		}
+		if !strings.Contains(rec.Body.String(), "at least 5m0s") {
+			t.Errorf("%s: expected the limit in the explanation, got %q", tt.name, rec.Body.String())
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+		}
+	}
+
+	s, mock := newTestServer(t)
+	s.config.MinInterval = 5 * time.Minute
+	now := time.Now()
+	mock.ExpectQuery(regexp.QuoteMeta("INSERT INTO cron_expressions")).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "updated_at"}).AddRow(1, now, now))
+	if rec := serve(s, "POST", "/api/expressions", `{"name":"Steady","expression":"*/5 * * * *"}`); rec.Code != http.StatusCreated {
+		t.Errorf("Expected an expression at the limit to be accepted, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestCreateWebhookHandler(t *testing.T) {
+	now := time.Now()
+
+	s, mock := newTestServer(t)
+	mock.ExpectQuery("SELECT .* FROM cron_expressions e").WithArgs("3").
+		WillReturnRows(sqlmock.NewRows(expressionRowColumns).AddRow(3, "Nightly", "0 0 * * *", "", nil, true, "", "{}", "dev", now, now, nil))
+	mock.ExpectQuery(regexp.QuoteMeta("INSERT INTO webhooks")).WithArgs("3", "https://example.com/hook", sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "expression_id", "created_at"}).AddRow(9, 3, now))
+	rec := serve(s, "POST", "/api/expressions/3/webhooks", `{"url":"https://example.com/hook"}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d but got %d: %s", http.StatusCreated, rec.Code, rec.Body.String())
+	}
+	var hook Webhook
+	json.NewDecoder(rec.Body).Decode(&hook)
+	if hook.ID != 9 || hook.ExpressionID != 3 || !hook.Enabled {
+		t.Errorf("Unexpected webhook: %+v", hook)
+	}
+
+	s, mock = newTestServer(t)
+	for _, target := range []string{"ftp://example.com", "http://127.0.0.1:8080/", "http://169.254.169.254/latest/meta-data/", "http://localhost/hook"} {
+		if rec := serve(s, "POST", "/api/expressions/3/webhooks", `{"url":"`+target+`"}`); rec.Code != http.StatusBadRequest {
+			t.Errorf("Expected status %d for %s but got %d", http.StatusBadRequest, target, rec.Code)
+		}
+	}
+
+	mock.ExpectQuery("SELECT .* FROM cron_expressions e").WithArgs("4").
+		WillReturnRows(sqlmock.NewRows(expressionRowColumns))
+	if rec := serve(s, "POST", "/api/expressions/4/webhooks", `{"url":"https://example.com/hook"}`); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d for a missing expression but got %d", http.StatusNotFound, rec.Code)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestWebhookDelivery(t *testing.T) {
+	defer func(delay time.Duration) { webhookRetryDelay = delay }(webhookRetryDelay)
+	webhookRetryDelay = 0
+
+	t.Run("retries until success", func(t *testing.T) {
+		var attempts int32
+		target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if atomic.AddInt32(&attempts, 1) == 1 {
+				w.WriteHeader(http.StatusBadGateway)
+				return
+			}
+			var payload WebhookPayload
+			if err := json.NewDecoder(r.Body).Decode(&payload); err != nil || payload.ExpressionID != 3 {
+				t.Errorf("Unexpected payload %+v: %v", payload, err)
+			}
+		}))
+		defer target.Close()
+
+		s, mock := newTestServer(t)
+		s.config.WebhookAllowPrivate = true
+		mock.ExpectExec(regexp.QuoteMeta("SET consecutive_failures = 0")).WithArgs(sqlmock.AnyArg(), 4).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		payload, _ := json.Marshal(WebhookPayload{ExpressionID: 3, Name: "Nightly", Expression: "0 0 * * *"})
+		newWebhookScheduler(s).deliver(4, target.URL, payload)
+
+		if attempts != 2 {
+			t.Errorf("Expected 2 attempts but got %d", attempts)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("Unfulfilled expectations: %v", err)
+		}
+	})
+
+	t.Run("disables after repeated failures", func(t *testing.T) {
+		var attempts int32
+		target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&attempts, 1)
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer target.Close()
+
+		s, mock := newTestServer(t)
+		s.config.WebhookAllowPrivate = true
+		mock.ExpectQuery(regexp.QuoteMeta("RETURNING enabled")).
+			WithArgs(sqlmock.AnyArg(), "unexpected status 500 Internal Server Error", webhookMaxFailures, 4).
+			WillReturnRows(sqlmock.NewRows([]string{"enabled"}).AddRow(false))
+		before := testutil.ToFloat64(webhooksDisabled)
+		newWebhookScheduler(s).deliver(4, target.URL, []byte(`{}`))
+
+		if attempts != webhookMaxAttempts {
+			t.Errorf("Expected %d attempts but got %d", webhookMaxAttempts, attempts)
+		}
+		if got := testutil.ToFloat64(webhooksDisabled) - before; got != 1 {
+			t.Errorf("Expected one webhook disabled but got %v", got)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("Unfulfilled expectations: %v", err)
+		}
+	})
+}
+
+func TestValidWebhookURL(t *testing.T) {
+	tests := []struct {
+		url          string
+		allowPrivate bool
+		valid        bool
+	}{
+		{"https://example.com/hook", false, true},
+		{"http://203.0.113.7:8080/hook", false, true},
+		{"ftp://example.com", false, false},
+		{"https://", false, false},
+		{"http://127.0.0.1/", false, false},
+		{"http://[::1]/", false, false},
+		{"http://10.1.2.3/", false, false},
+		{"http://192.168.0.10/", false, false},
+		{"http://169.254.169.254/latest/meta-data/", false, false},
+		{"http://100.64.0.1/", false, false},
+		{"http://0.0.0.0/", false, false},
+		{"http://LocalHost./", false, false},
+		{"http://api.localhost/", false, false},
+		{"http://127.0.0.1/", true, true},
+	}
+	for _, tt := range tests {
+		if got := validWebhookURL(tt.url, tt.allowPrivate); got != tt.valid {
+			t.Errorf("validWebhookURL(%q, %v) = %v, expected %v", tt.url, tt.allowPrivate, got, tt.valid)
+		}
+	}
+}
+
+func TestWebhookClientBlocksPrivateAddresses(t *testing.T) {
+	var hits int32
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+	}))
+	defer target.Close()
+
+	// A public-looking name can still resolve to loopback, so the check has
+	// to happen when dialing
+	hostname := strings.Replace(target.URL, "127.0.0.1", "localtest.me", 1)
+	for _, url := range []string{target.URL, hostname} {
+		resp, err := webhookClient(time.Second, false).Post(url, "application/json", strings.NewReader(`{}`))
+		if err == nil {
+			resp.Body.Close()
+			t.Errorf("Expected a delivery to %s to be refused", url)
+		} else if url == target.URL && deliveryError(err) != errWebhookAddress.Error() {
+			t.Errorf("Expected %q recorded for %s, got %q", errWebhookAddress, url, deliveryError(err))
+		}
+	}
+	if hits != 0 {
+		t.Errorf("Expected no request to reach the loopback server, got %d", hits)
+	}
+
+	resp, err := webhookClient(time.Second, true).Post(target.URL, "application/json", strings.NewReader(`{}`))
+	if err != nil {
+		t.Fatalf("Expected private addresses to be allowed when configured: %v", err)
+	}
+	resp.Body.Close()
+}
+
+func TestWebhookSchedulerSync(t *testing.T) {
+	s, mock := newTestServer(t)
+	now := time.Now()
+	query := "SELECT .* FROM cron_expressions e .* FROM webhooks"
+	mock.ExpectQuery(query).WillReturnRows(sqlmock.NewRows(expressionRowColumns).
+		AddRow(3, "Nightly", "0 0 * * *", "", nil, true, "UTC", "{}", "dev", now, now, nil).
+		AddRow(4, "Legacy", "0 25 * * *", "", nil, true, "", "{}", "dev", now, now, nil))
+	mock.ExpectQuery(query).WillReturnRows(sqlmock.NewRows(expressionRowColumns).
+		AddRow(3, "Nightly", "0 0 * * *", "", nil, true, "UTC", "{}", "dev", now, now, nil))
+	mock.ExpectQuery(query).WillReturnRows(sqlmock.NewRows(expressionRowColumns))
+
+	ws := newWebhookScheduler(s)
+	if err := ws.sync(); err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+	if len(ws.entries) != 1 || len(ws.cron.Entries()) != 1 {
+		t.Fatalf("Expected only the valid expression scheduled, got %v", ws.entries)
+	}
+	first := ws.entries[3].id
+	next := ws.cron.Entry(first).Schedule.Next(time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC))
+	if expected := time.Date(2026, 3, 3, 0, 0, 0, 0, time.UTC); !next.Equal(expected) {
+		t.Errorf("Expected next run %s but got %s", expected, next)
+	}
+
+	if err := ws.sync(); err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+	if ws.entries[3].id != first {
+		t.Errorf("Expected an unchanged expression to keep its entry")
+	}
+
+	if err := ws.sync(); err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+	if len(ws.entries) != 0 || len(ws.cron.Entries()) != 0 {
+		t.Errorf("Expected entries removed once no webhooks remain, got %v", ws.entries)
+	}
+}
+
+func TestShiftExpression(t *testing.T) {
+	tests := []struct {
+		expression string
+		offset     int
+		expected   string
+	}{
+		{"50 9 * * *", 15, "5 10 * * *"},
+		{"0 9 * * 1-5", -90, "30 7 * * 1-5"},
+		{"0 23 * * 1-5", 120, "0 1 * * 2-6"},
+		{"0 0 * * 0", -30, "30 23 * * 6"},
+		{"30 0 * * *", -60, "30 23 * * *"},
+		{"*/15 * * * *", 5, "5,20,35,50 * * * *"},
+		{"0 9,17 1 * *", 60, "0 10,18 1 * *"},
+		{"0 */6 * * *", 30, "30 */6 * * *"},
+	}
+	for _, tt := range tests {
+		got, err := shiftExpression(tt.expression, tt.offset)
+		if err != nil {
+			t.Errorf("shiftExpression(%q, %d) failed: %v", tt.expression, tt.offset, err)
+			continue
+		}
+		if got != tt.expected {
+			t.Errorf("shiftExpression(%q, %d) = %q, expected %q", tt.expression, tt.offset, got, tt.expected)
+		}
+	}
+}
+
+func TestShiftExpressionRejects(t *testing.T) {
+	tests := []struct {
+		name       string
+		expression string
+		offset     int
+	}{
+		{"minutes carry differently", "0,50 9 * * *", 15},
+		{"day of month across midnight", "0 23 1 * *", 120},
+		{"some runs cross midnight on set days", "0 12,23 * * 1", 120},
+		{"weekday syntax", "0 9 * * 1#2", 60},
+	}
+	for _, tt := range tests {
+		if got, err := shiftExpression(tt.expression, tt.offset); err == nil {
+			t.Errorf("%s: expected an error, got %q", tt.name, got)
+		}
+	}
+}
+
+func TestShiftHandler(t *testing.T) {
+	s, _ := newTestServer(t)
+	rec := serve(s, "POST", "/api/shift", `{"expression":"@daily","hours":-1,"minutes":-15}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status %d but got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	var response ShiftResponse
+	json.NewDecoder(rec.Body).Decode(&response)
+	if response.Shifted != "45 22 * * *" || response.OffsetMinutes != -75 || response.Description == "" || len(response.NextExecutions) != 5 {
+		t.Errorf("Unexpected response: %+v", response)
+	}
+
+	for _, body := range []string{
+		`{"expression":"61 * * * *","minutes":5}`,
+		`{"expression":"0 9 * * *","hours":24}`,
+	} {
+		if rec := serve(s, "POST", "/api/shift", body); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status %d but got %d", body, http.StatusBadRequest, rec.Code)
+		}
+	}
+	if rec := serve(s, "POST", "/api/shift", `{"expression":"0,50 9 * * *","minutes":15}`); rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected status %d but got %d", http.StatusUnprocessableEntity, rec.Code)
+	}
+}
+
+func TestAlignments(t *testing.T) {
+	from := time.Date(2024, time.January, 31, 14, 37, 20, 0, time.UTC) // a Wednesday
+	tests := []struct {
+		alignment string
+		weekStart time.Weekday
+		expected  time.Time
+	}{
+		{"minute", time.Sunday, time.Date(2024, time.January, 31, 14, 38, 0, 0, time.UTC)},
+		{"quarter-hour", time.Sunday, time.Date(2024, time.January, 31, 14, 45, 0, 0, time.UTC)},
+		{"hour", time.Sunday, time.Date(2024, time.January, 31, 15, 0, 0, 0, time.UTC)},
+		{"day", time.Sunday, time.Date(2024, time.February, 1, 0, 0, 0, 0, time.UTC)},
+		{"week", time.Sunday, time.Date(2024, time.February, 4, 0, 0, 0, 0, time.UTC)},
+		{"week", time.Wednesday, time.Date(2024, time.February, 7, 0, 0, 0, 0, time.UTC)},
+		{"month", time.Sunday, time.Date(2024, time.February, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		if got := alignments[tt.alignment](from, tt.weekStart); !got.Equal(tt.expected) {
+			t.Errorf("%s (week starting %s) = %s, expected %s", tt.alignment, tt.weekStart, got, tt.expected)
+		}
+	}
+}
+
+func TestParseAlignment(t *testing.T) {
+	tests := map[string]string{
+		"hour":               "hour",
+		"next-hour":          "hour",
+		"Start of next hour": "hour",
+		"next quarter hour":  "quarter-hour",
+		"next_day":           "day",
+	}
+	for value, expected := range tests {
+		if got, ok := parseAlignment(value); !ok || got != expected {
+			t.Errorf("parseAlignment(%q) = %q, %v, expected %q", value, got, ok, expected)
+		}
+	}
+	for _, value := range []string{"", "fortnight", "start of hour hour"} {
+		if got, ok := parseAlignment(value); ok {
+			t.Errorf("parseAlignment(%q) = %q, expected it to be rejected", value, got)
+		}
+	}
+}
+
+func TestAlignedScheduleHandler(t *testing.T) {
+	s, _ := newTestServer(t)
+	rec := serve(s, "POST", "/api/schedule/aligned", `{"expression":"0 * * * *","alignment":"start of next hour","from":"2024-01-31T14:37:20Z","timezone":"UTC"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status %d but got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	var response AlignedResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	boundary := time.Date(2024, time.January, 31, 15, 0, 0, 0, time.UTC)
+	if !response.AlignedFrom.Equal(boundary) || response.NextRun == nil || !response.NextRun.Equal(boundary) {
+		t.Errorf("Expected a run on the boundary %s, got %+v", boundary, response)
+	}
+
+	rec = serve(s, "POST", "/api/schedule/aligned", `{"expression":"0 * * * *","alignment":"fortnight"}`)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d for an unknown alignment but got %d", http.StatusBadRequest, rec.Code)
+	}
+}
+
+func TestTokenizeExpression(t *testing.T) {
+	tests := []struct {
+		name       string
+		expression string
+		valid      []bool
+		token      string
+		extra      int
+		ok         bool
+	}{
+		{"valid", "*/15 9-17 * * MON-FRI", []bool{true, true, true, true, true}, "", 0, true},
+		{"extensions", "0 9 15W * *", []bool{true, true, true, true, true}, "", 0, true},
+		{"descriptor", "@daily", []bool{true, true, true, true, true}, "", 0, true},
+		{"bad hour", "0 25 * * *", []bool{true, false, true, true, true}, "25", 0, false},
+		{"bad minute and month", "0,61 9 * 13 *", []bool{false, true, true, false, true}, "61", 0, false},
+		{"too few", "0 9 *", []bool{true, true, true, false, false}, "", 0, false},
+		{"too many", "0 9 * * * 2024", []bool{true, true, true, true, true}, "", 1, false},
+	}
+	for _, tt := range tests {
+		got := tokenizeExpression(tt.expression)
+		if got.Valid != tt.ok || len(got.Extra) != tt.extra || len(got.Fields) != len(cronFields) {
+			t.Errorf("%s: tokenizeExpression(%q) = %+v", tt.name, tt.expression, got)
+			continue
+		}
+		token := ""
+		for i, field := range got.Fields {
+			if field.Valid != tt.valid[i] {
+				t.Errorf("%s: field %s valid = %v, expected %v (%s)", tt.name, field.Name, field.Valid, tt.valid[i], field.Error)
+			}
+			if !field.Valid && token == "" {
+				token = field.Token
+			}
+		}
+		if token != tt.token {
+			t.Errorf("%s: first bad token = %q, expected %q", tt.name, token, tt.token)
+		}
+	}
+}
+
+func TestTokenizeHandler(t *testing.T) {
+	s, _ := newTestServer(t)
+	rec := serve(s, "POST", "/api/tokenize", `{"expression":"0 9"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status %d but got %d", http.StatusOK, rec.Code)
+	}
+	var response TokenizeResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	if response.Valid || len(response.Fields) != 5 || !response.Fields[4].Missing || response.Fields[4].Max != 6 {
+		t.Errorf("Expected padded fields with day-of-week missing, got %+v", response)
+	}
+}
+
+func TestParseScheduleConfig(t *testing.T) {
+	config := `# nightly jobs
+BACKUP_SCHEDULE=0 2 * * *
+export REPORT_SCHEDULE="0 9 ? * MON-FRI"
+
+CLEANUP_SCHEDULE=0 25 * * *
+not a setting
+BACKUP_SCHEDULE=0 3 * * *
+9LIVES=@daily
+`
+	entries, err := parseScheduleConfig(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []struct {
+		line       int
+		key        string
+		valid      bool
+		normalized string
+		error      string
+	}{
+		{2, "BACKUP_SCHEDULE", true, "0 2 * * *", ""},
+		{3, "REPORT_SCHEDULE", true, "0 9 * * MON-FRI", ""},
+		{5, "CLEANUP_SCHEDULE", false, "", "Invalid cron expression"},
+		{6, "not a setting", false, "", "Expected KEY=expression"},
+		{7, "BACKUP_SCHEDULE", false, "", "Duplicate key, first set on line 2"},
+		{8, "9LIVES", false, "", "Invalid key"},
+	}
+	if len(entries) != len(expected) {
+		t.Fatalf("Expected %d entries, got %+v", len(expected), entries)
+	}
+	for i, want := range expected {
+		got := entries[i]
+		if got.Line != want.line || got.Key != want.key || got.Valid != want.valid ||
+			got.Normalized != want.normalized || !strings.HasPrefix(got.Error, want.error) {
+			t.Errorf("entry %d = %+v, expected %+v", i, got, want)
+		}
+	}
+}
+
+func TestValidateConfigHandler(t *testing.T) {
+	s, _ := newTestServer(t)
+	before := testutil.ToFloat64(invalidCronExpressions)
+
+	req := httptest.NewRequest("POST", "/api/config/validate", strings.NewReader("A=*/5 * * * *\nB=61 * * * *\n"))
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	rec := httptest.NewRecorder()
+	s.handler().ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status %d but got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	var response ConfigValidateResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	if response.Valid || response.Total != 2 || response.Invalid != 1 || response.Entries[0].Description == "" {
+		t.Errorf("Expected one valid and one invalid entry, got %+v", response)
+	}
+	if got := testutil.ToFloat64(invalidCronExpressions) - before; got != 1 {
+		t.Errorf("Expected invalid_cron_expressions_total to increase by 1, got %v", got)
+	}
+}
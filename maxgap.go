@@ -0,0 +1,135 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/robfig/cron/v3"
+)
+
+const (
+	defaultGapWindow = 30 * 24 * time.Hour
+	maxGapWindow     = 366 * 24 * time.Hour
+	// maxGapRuns caps the walk; an every-minute schedule over 30 days is
+	// 43200 runs
+	maxGapRuns = 100000
+)
+
+// MaxGapResponse is the longest stretch without a run in the window starting
+// now. GapStart and GapEnd are the runs either side of it; they and MaxGap
+// are omitted, with a Note, when fewer than two runs fall in the window.
+type MaxGapResponse struct {
+	ID            int        `json:"id"`
+	Expression    string     `json:"expression"`
+	Timezone      string     `json:"timezone"`
+	Window        string     `json:"window"`
+	From          time.Time  `json:"from"`
+	To            time.Time  `json:"to"`
+	Runs          int        `json:"runs"`
+	MaxGap        string     `json:"maxGap,omitempty"`
+	MaxGapSeconds int64      `json:"maxGapSeconds,omitempty"`
+	GapStart      *time.Time `json:"gapStart,omitempty"`
+	GapEnd        *time.Time `json:"gapEnd,omitempty"`
+	Truncated     bool       `json:"truncated"`
+	Note          string     `json:"note,omitempty"`
+}
+
+// parseWindow accepts a whole number of days such as "30d" as well as
+// anything time.ParseDuration does
+func parseWindow(value string) (time.Duration, error) {
+	if days := strings.TrimSuffix(value, "d"); days != value {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, err
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(value)
+}
+
+// longestGap walks schedule from from until to and returns the two
+// consecutive runs furthest apart, the number of runs seen and whether
+// maxGapRuns cut the walk short. start and end are zero with fewer than two
+// runs.
+func longestGap(schedule cron.Schedule, from, to time.Time) (start, end time.Time, runs int, truncated bool) {
+	var prev time.Time
+	for next := schedule.Next(from); !next.IsZero() && !next.After(to); next = schedule.Next(next) {
+		if runs == maxGapRuns {
+			return start, end, runs, true
+		}
+		if !prev.IsZero() && next.Sub(prev) > end.Sub(start) {
+			start, end = prev, next
+		}
+		prev = next
+		runs++
+	}
+	return start, end, runs, false
+}
+
+// maxGapHandler reports the longest gap between consecutive runs of a stored
+// expression over ?window= (default 30d), so teams can check a heartbeat job
+// never goes quiet for too long
+func (s *Server) maxGapHandler(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	window := defaultGapWindow
+	if v := r.URL.Query().Get("window"); v != "" {
+		var err error
+		window, err = parseWindow(v)
+		if err != nil || window <= 0 || window > maxGapWindow {
+			writeFieldError(w, http.StatusBadRequest, ErrInvalidRequest, "window", fmt.Sprintf("window must be a duration such as 30d or 12h, at most %dd", maxGapWindow/(24*time.Hour)))
+			return
+		}
+	}
+
+	ctx, cancel := s.dbContext(r)
+	defer cancel()
+
+	exp, err := s.db.expressionByID(ctx, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			writeError(w, http.StatusNotFound, ErrNotFound, "Expression not found")
+		} else {
+			writeDBError(w, ctx, err)
+		}
+		return
+	}
+
+	schedule, err := parseExpression(exp.Expression)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, ErrInternal, "Stored expression is invalid: "+err.Error())
+		return
+	}
+
+	loc := s.expressionLocation(exp)
+	from := time.Now().In(loc)
+	to := from.Add(window)
+	response := MaxGapResponse{
+		ID:         exp.ID,
+		Expression: exp.Expression,
+		Timezone:   loc.String(),
+		Window:     window.String(),
+		From:       from,
+		To:         to,
+	}
+
+	start, end, runs, truncated := longestGap(schedule, from, to)
+	response.Runs = runs
+	response.Truncated = truncated
+	if start.IsZero() {
+		response.Note = fmt.Sprintf("expression runs %d time(s) in the window, so there is no gap between runs", runs)
+	} else {
+		gap := end.Sub(start)
+		response.MaxGap = gap.String()
+		response.MaxGapSeconds = int64(gap / time.Second)
+		response.GapStart = &start
+		response.GapEnd = &end
+	}
+
+	s.writeJSON(w, r, http.StatusOK, response)
+}
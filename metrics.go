@@ -0,0 +1,229 @@
+package main
+
+import (
+	"os"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Prometheus metrics, registered by init. With METRICS_NAMESPACE=cronops the
+// names become cronops_http_requests_total, cronops_cron_expressions_stored
+// and so on; unset, they are unprefixed as listed here:
+//
+//	http_requests_total{endpoint,status}
+//	http_request_duration_seconds{endpoint}
+//	cron_expressions_total
+//	cron_expressions_stored
+//	db_connection_errors_total
+//	invalid_cron_expressions_total
+//	slow_requests_total{endpoint}
+//	panics_total
+//	db_query_retries_total
+//	soft_deleted_purged_total
+//	invalid_stored_expressions
+//	description_generic_fallback_total{field}
+//	description_generation_duration_seconds
+//	cache_hits_total
+//	cache_misses_total
+//	webhook_deliveries_total{result}
+//	webhook_delivery_duration_seconds
+//	webhooks_disabled_total
+//
+// The namespace is read from the process environment, since init runs before
+// main loads .env. Go runtime and go_sql_* pool metrics are never prefixed.
+var (
+	httpRequestsTotal    *prometheus.CounterVec
+	httpRequestDuration  *prometheus.HistogramVec
+	cronExpressionsTotal prometheus.Counter
+
+	// cronExpressionsStored tracks the live row count. Unlike the counter
+	// above it can go down and be resynced from the database.
+	cronExpressionsStored prometheus.Gauge
+
+	dbConnectionErrors     prometheus.Counter
+	invalidCronExpressions prometheus.Counter
+	slowRequestsTotal      *prometheus.CounterVec
+	panicsTotal            prometheus.Counter
+	dbQueryRetries         prometheus.Counter
+	softDeletedPurged      prometheus.Counter
+
+	// invalidStoredExpressions is set by validateStoredExpressions
+	invalidStoredExpressions prometheus.Gauge
+
+	// descriptionFallbacks counts description fields that fell through to a
+	// generic phrase echoing the raw field, to show which ones need work
+	descriptionFallbacks *prometheus.CounterVec
+
+	// descriptionDuration times description generation in the convert
+	// handlers, see timedDescription
+	descriptionDuration prometheus.Histogram
+
+	// cacheHits and cacheMisses count expression list cache lookups; both
+	// stay at zero while LIST_CACHE_TTL_MS is unset
+	cacheHits   prometheus.Counter
+	cacheMisses prometheus.Counter
+
+	// webhookDeliveries counts finished deliveries, after retries, by
+	// result; webhookDeliveryDuration times every attempt
+	webhookDeliveries       *prometheus.CounterVec
+	webhookDeliveryDuration prometheus.Histogram
+	webhooksDisabled        prometheus.Counter
+)
+
+func init() {
+	namespace := os.Getenv("METRICS_NAMESPACE")
+
+	httpRequestsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "Total number of HTTP requests by endpoint and status",
+		},
+		[]string{"endpoint", "status"},
+	)
+
+	httpRequestDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "Duration of HTTP requests in seconds",
+			Buckets:   prometheus.DefBuckets,
+		},
+		[]string{"endpoint"},
+	)
+
+	cronExpressionsTotal = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "cron_expressions_total",
+			Help:      "Total number of cron expressions created; never decreases, see cron_expressions_stored",
+		},
+	)
+
+	cronExpressionsStored = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "cron_expressions_stored",
+			Help:      "Current number of stored cron expressions",
+		},
+	)
+
+	dbConnectionErrors = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "db_connection_errors_total",
+			Help:      "Total number of database connection errors",
+		},
+	)
+
+	invalidCronExpressions = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "invalid_cron_expressions_total",
+			Help:      "Total number of invalid cron expressions submitted",
+		},
+	)
+
+	slowRequestsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "slow_requests_total",
+			Help:      "Total number of requests slower than the configured threshold by endpoint",
+		},
+		[]string{"endpoint"},
+	)
+
+	panicsTotal = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "panics_total",
+			Help:      "Total number of panics recovered from request handlers",
+		},
+	)
+
+	dbQueryRetries = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "db_query_retries_total",
+			Help:      "Total number of read queries retried after a transient database error",
+		},
+	)
+
+	softDeletedPurged = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "soft_deleted_purged_total",
+			Help:      "Total number of soft-deleted expressions permanently purged",
+		},
+	)
+
+	invalidStoredExpressions = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "invalid_stored_expressions",
+			Help:      "Number of stored expressions that failed validation on the last check",
+		},
+	)
+
+	descriptionFallbacks = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "description_generic_fallback_total",
+			Help:      "Total number of description fields rendered by a generic fallback branch by field",
+		},
+		[]string{"field"},
+	)
+
+	descriptionDuration = promauto.NewHistogram(
+		prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "description_generation_duration_seconds",
+			Help:      "Duration of description generation in the convert handlers in seconds",
+			// 1µs to about 32ms; descriptions normally take microseconds
+			Buckets: prometheus.ExponentialBuckets(1e-6, 2, 16),
+		},
+	)
+
+	cacheHits = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "cache_hits_total",
+			Help:      "Total number of expression list requests served from the in-process cache",
+		},
+	)
+
+	cacheMisses = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "cache_misses_total",
+			Help:      "Total number of expression list requests that missed the in-process cache",
+		},
+	)
+
+	webhookDeliveries = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "webhook_deliveries_total",
+			Help:      "Total number of webhook deliveries by result, counted once after retries",
+		},
+		[]string{"result"},
+	)
+
+	webhookDeliveryDuration = promauto.NewHistogram(
+		prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "webhook_delivery_duration_seconds",
+			Help:      "Duration of each webhook delivery attempt in seconds",
+			Buckets:   prometheus.DefBuckets,
+		},
+	)
+
+	webhooksDisabled = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "webhooks_disabled_total",
+			Help:      "Total number of webhooks disabled after repeated failed deliveries",
+		},
+	)
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"log"
+	"mime"
+	"net/http"
+	"runtime/debug"
+	"strings"
+	"time"
+)
+
+// routePolicy declares which cross-cutting middleware a path opts out of
+type routePolicy struct {
+	// public paths never require the API token
+	public bool
+	// unmetered paths aren't recorded in request metrics, so probe traffic
+	// doesn't pollute dashboards
+	unmetered bool
+}
+
+// exemptRoutes is the single place to declare paths that skip auth or
+// metrics. Every middleware consults it instead of special-casing paths.
+var exemptRoutes = map[string]routePolicy{
+	"/healthz":     {public: true, unmetered: true},
+	"/readyz":      {public: true, unmetered: true},
+	"/api/version": {public: true},
+}
+
+// authMiddleware requires "Authorization: Bearer <API_TOKEN>" on /api/ routes
+// when an API token is configured. Static files stay public so the UI loads.
+func (s *Server) authMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.config.APIToken == "" || r.Method == http.MethodOptions ||
+			!strings.HasPrefix(r.URL.Path, "/api/") || exemptRoutes[r.URL.Path].public {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if !validBearerToken(r, s.config.APIToken) {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeError(w, http.StatusUnauthorized, ErrUnauthorized, "Unauthorized")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// validBearerToken reports whether the request carries the expected token in
+// an "Authorization: Bearer" header; a bare token isn't accepted
+func validBearerToken(r *http.Request, token string) bool {
+	provided, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(provided), []byte(token)) == 1
+}
+
+// metricsAuthMiddleware requires "Authorization: Bearer <METRICS_TOKEN>" on
+// operational endpoints when a metrics token is configured
+func (s *Server) metricsAuthMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.config.MetricsToken != "" && !validBearerToken(r, s.config.MetricsToken) {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeError(w, http.StatusUnauthorized, ErrUnauthorized, "Unauthorized")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// corsMiddleware adds CORS headers for origins listed in CORS_ALLOWED_ORIGINS
+// and answers preflight requests. It wraps the router so preflights are
+// handled before method matching.
+func (s *Server) corsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin == "" || !s.originAllowed(origin) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Add("Vary", "Origin")
+
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+			w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type")
+			w.Header().Set("Access-Control-Max-Age", "600")
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) originAllowed(origin string) bool {
+	for _, allowed := range s.config.CORSAllowedOrigins {
+		if allowed == "*" || allowed == origin {
+			return true
+		}
+	}
+	return false
+}
+
+// multipartRoutes accept file uploads instead of JSON bodies
+var multipartRoutes = map[string]bool{
+	"/api/expressions/upload": true,
+}
+
+// plainTextRoutes accept text/plain bodies instead of JSON
+var plainTextRoutes = map[string]bool{
+	"/api/config/validate": true,
+}
+
+// contentTypeMiddleware rejects API request bodies that aren't JSON with 415,
+// rather than letting the handler fail with a confusing decode error.
+// Bodiless requests such as pause/resume are let through, as are bodies with
+// no Content-Type unless STRICT_CONTENT_TYPE is set.
+func (s *Server) contentTypeMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, "/api/") || r.ContentLength == 0 ||
+			(r.Method != http.MethodPost && r.Method != http.MethodPut && r.Method != http.MethodPatch) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		contentType := r.Header.Get("Content-Type")
+		if contentType == "" && !s.config.StrictContentType {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		mediaType, _, err := mime.ParseMediaType(contentType)
+		if err == nil && ((mediaType == "multipart/form-data" && multipartRoutes[r.URL.Path]) ||
+			(mediaType == "text/plain" && plainTextRoutes[r.URL.Path])) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if err != nil || mediaType != "application/json" {
+			writeError(w, http.StatusUnsupportedMediaType, ErrUnsupportedMediaType, "Content-Type must be application/json")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// requestInfoKey is the context key holding a request's requestInfo
+type requestInfoKey struct{}
+
+// requestInfo is per-request data recorded on the way in
+type requestInfo struct {
+	id    string
+	start time.Time
+}
+
+// requestIDMiddleware tags each request with an ID, reusing a client-supplied
+// X-Request-ID so logs can be correlated across services, and records when
+// the request started
+func requestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		info := requestInfo{id: r.Header.Get("X-Request-ID"), start: time.Now()}
+		if info.id == "" {
+			info.id = newRequestID()
+		}
+		w.Header().Set("X-Request-ID", info.id)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestInfoKey{}, info)))
+	})
+}
+
+func newRequestID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// requestID returns the ID assigned by requestIDMiddleware, if any
+func requestID(r *http.Request) string {
+	info, _ := r.Context().Value(requestInfoKey{}).(requestInfo)
+	return info.id
+}
+
+// requestStart returns when requestIDMiddleware first saw the request
+func requestStart(r *http.Request) time.Time {
+	if info, ok := r.Context().Value(requestInfoKey{}).(requestInfo); ok {
+		return info.start
+	}
+	return time.Now()
+}
+
+// recoverMiddleware turns a handler panic into a logged 500 response instead
+// of dropping the connection
+func recoverMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			recovered := recover()
+			if recovered == nil {
+				return
+			}
+			if recovered == http.ErrAbortHandler {
+				// Deliberate abort; let net/http handle it
+				panic(recovered)
+			}
+
+			panicsTotal.Inc()
+			log.Printf("ERROR panic: request_id=%s method=%s path=%s error=%v\n%s",
+				requestID(r), r.Method, r.URL.Path, recovered, debug.Stack())
+
+			writeErrorBody(w, http.StatusInternalServerError, ErrorBody{Code: ErrInternal, Message: "Internal server error", RequestID: requestID(r)})
+		}()
+		next.ServeHTTP(w, r)
+	})
+}
@@ -24,5 +24,148 @@ func RunMigrations(db *sql.DB) {
 		log.Fatalf("Error creating cron_expressions table: %v", err)
 	}
 
+	// Create categories table if it doesn't exist
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS categories (
+			id SERIAL PRIMARY KEY,
+			name VARCHAR(255) NOT NULL UNIQUE,
+			description TEXT,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);
+	`)
+	if err != nil {
+		log.Fatalf("Error creating categories table: %v", err)
+	}
+
+	// Link expressions to an optional category
+	_, err = db.Exec(`
+		ALTER TABLE cron_expressions
+		ADD COLUMN IF NOT EXISTS category_id INTEGER REFERENCES categories(id) ON DELETE SET NULL;
+	`)
+	if err != nil {
+		log.Fatalf("Error adding category_id column: %v", err)
+	}
+
+	// Deleted expressions are kept until purged after the retention period
+	_, err = db.Exec(`
+		ALTER TABLE cron_expressions
+		ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
+	`)
+	if err != nil {
+		log.Fatalf("Error adding deleted_at column: %v", err)
+	}
+
+	// Live expression names are unique so create and update can report a
+	// conflict; deleted rows keep their names until purged. This replaces
+	// the earlier index over every row. Existing duplicates make it fail,
+	// which shouldn't stop the server starting.
+	_, err = db.Exec(`
+		DROP INDEX IF EXISTS idx_cron_expressions_unique_name;
+		CREATE UNIQUE INDEX IF NOT EXISTS idx_cron_expressions_live_name
+		ON cron_expressions (name) WHERE deleted_at IS NULL;
+	`)
+	if err != nil {
+		log.Printf("Warning: can't enforce unique expression names, rename live duplicates and restart: %v", err)
+	}
+
+	// Paused expressions are kept but don't run; existing rows stay enabled
+	_, err = db.Exec(`
+		ALTER TABLE cron_expressions
+		ADD COLUMN IF NOT EXISTS enabled BOOLEAN NOT NULL DEFAULT true;
+	`)
+	if err != nil {
+		log.Fatalf("Error adding enabled column: %v", err)
+	}
+
+	// Zone each expression is meant to run in; NULL uses DEFAULT_TIMEZONE
+	_, err = db.Exec(`
+		ALTER TABLE cron_expressions
+		ADD COLUMN IF NOT EXISTS timezone VARCHAR(64);
+	`)
+	if err != nil {
+		log.Fatalf("Error adding timezone column: %v", err)
+	}
+
+	// Free-form client metadata such as team, runbook URL or SLA
+	_, err = db.Exec(`
+		ALTER TABLE cron_expressions
+		ADD COLUMN IF NOT EXISTS metadata JSONB;
+	`)
+	if err != nil {
+		log.Fatalf("Error adding metadata column: %v", err)
+	}
+
+	// Runtime environment the expression belongs to; existing rows become dev
+	_, err = db.Exec(`
+		ALTER TABLE cron_expressions
+		ADD COLUMN IF NOT EXISTS environment VARCHAR(16) NOT NULL DEFAULT 'dev';
+		CREATE INDEX IF NOT EXISTS idx_cron_expressions_environment ON cron_expressions (environment);
+	`)
+	if err != nil {
+		log.Fatalf("Error adding environment column: %v", err)
+	}
+
+	// When each expression last parsed successfully; NULL until the first
+	// validation sweep
+	_, err = db.Exec(`
+		ALTER TABLE cron_expressions
+		ADD COLUMN IF NOT EXISTS last_validated_at TIMESTAMP;
+	`)
+	if err != nil {
+		log.Fatalf("Error adding last_validated_at column: %v", err)
+	}
+
+	// URLs notified when an expression fires; removed with the expression
+	// when it is purged
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS webhooks (
+			id SERIAL PRIMARY KEY,
+			expression_id INTEGER NOT NULL REFERENCES cron_expressions(id) ON DELETE CASCADE,
+			url TEXT NOT NULL,
+			enabled BOOLEAN NOT NULL DEFAULT true,
+			consecutive_failures INTEGER NOT NULL DEFAULT 0,
+			last_delivery_at TIMESTAMP,
+			last_error TEXT,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE INDEX IF NOT EXISTS idx_webhooks_expression_id ON webhooks (expression_id);
+	`)
+	if err != nil {
+		log.Fatalf("Error creating webhooks table: %v", err)
+	}
+
+	// Shared conversions looked up by their permalink token
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS shared_conversions (
+			token VARCHAR(16) PRIMARY KEY,
+			request JSONB NOT NULL,
+			options JSONB NOT NULL DEFAULT '{}',
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			expires_at TIMESTAMP
+		);
+	`)
+	if err != nil {
+		log.Fatalf("Error creating shared_conversions table: %v", err)
+	}
+
+	// Change history for stored expressions, kept after the expression is
+	// deleted or purged
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS expression_audit (
+			id SERIAL PRIMARY KEY,
+			expression_id INTEGER NOT NULL,
+			action VARCHAR(16) NOT NULL,
+			actor VARCHAR(255) NOT NULL,
+			claimed_actor VARCHAR(255),
+			snapshot JSONB,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE INDEX IF NOT EXISTS idx_expression_audit_expression_id ON expression_audit (expression_id, created_at);
+	`)
+	if err != nil {
+		log.Fatalf("Error creating expression_audit table: %v", err)
+	}
+
 	log.Println("Migrations completed successfully")
 }
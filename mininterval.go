@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// minIntervalWindow is how far ahead the minimum interval policy walks a
+// schedule, long enough to cross a year boundary and both DST changes
+const minIntervalWindow = 366 * 24 * time.Hour
+
+// shortestGap walks schedule from from until to and returns the closest pair
+// of consecutive runs. It stops early at the first gap below limit, since the
+// policy only needs one. ok is false when there are fewer than two runs.
+func shortestGap(schedule cron.Schedule, from, to time.Time, limit time.Duration) (start, end time.Time, ok bool) {
+	prev := schedule.Next(from)
+	for runs := 1; runs < maxGapRuns && !prev.IsZero(); runs++ {
+		next := schedule.Next(prev)
+		if next.IsZero() || next.After(to) {
+			break
+		}
+		if !ok || next.Sub(prev) < end.Sub(start) {
+			start, end, ok = prev, next, true
+			if end.Sub(start) < limit {
+				break
+			}
+		}
+		prev = next
+	}
+	return start, end, ok
+}
+
+// checkMinInterval enforces MIN_INTERVAL_SECONDS on a schedule about to be
+// saved, returning an explanation when two runs come closer than allowed. It
+// always passes when the policy is off.
+func (s *Server) checkMinInterval(schedule cron.Schedule, loc *time.Location) error {
+	limit := s.config.MinInterval
+	if limit <= 0 {
+		return nil
+	}
+	from := time.Now().In(loc)
+	start, end, ok := shortestGap(schedule, from, from.Add(minIntervalWindow), limit)
+	if !ok || end.Sub(start) >= limit {
+		return nil
+	}
+	return fmt.Errorf("runs at %s and again at %s, %s apart; this server requires at least %s between runs",
+		start.Format(executionFormat), end.Format(executionFormat), end.Sub(start), limit)
+}
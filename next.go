@@ -0,0 +1,75 @@
+package main
+
+import (
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// validTimezone reports whether zone is blank (use the default) or a known
+// IANA zone name
+func validTimezone(zone string) bool {
+	if zone == "" {
+		return true
+	}
+	_, err := time.LoadLocation(zone)
+	return err == nil
+}
+
+// expressionLocation returns the zone a stored expression runs in, falling
+// back to DEFAULT_TIMEZONE when it has none
+func (s *Server) expressionLocation(exp CronExpression) *time.Location {
+	if exp.Timezone != "" {
+		if loc, err := time.LoadLocation(exp.Timezone); err == nil {
+			return loc
+		}
+	}
+	return s.config.DefaultTimezone
+}
+
+// NextRunResponse is the next execution of a stored expression. NextRun is
+// null when the expression is paused or never fires again.
+type NextRunResponse struct {
+	ID         int        `json:"id"`
+	Expression string     `json:"expression"`
+	Enabled    bool       `json:"enabled"`
+	Timezone   string     `json:"timezone"`
+	NextRun    *time.Time `json:"nextRun"`
+}
+
+// nextRunHandler returns a stored expression's next execution in the body
+// and, for polling schedulers that only read headers, as an RFC1123
+// X-Next-Run header
+func (s *Server) nextRunHandler(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	ctx, cancel := s.dbContext(r)
+	defer cancel()
+
+	exp, err := s.db.expressionByID(ctx, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			writeError(w, http.StatusNotFound, ErrNotFound, "Expression not found")
+		} else {
+			writeDBError(w, ctx, err)
+		}
+		return
+	}
+
+	schedule, err := parseExpression(exp.Expression)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, ErrInternal, "Stored expression is invalid: "+err.Error())
+		return
+	}
+
+	loc := s.expressionLocation(exp)
+	response := NextRunResponse{ID: exp.ID, Expression: exp.Expression, Enabled: exp.Enabled, Timezone: loc.String()}
+	if next := schedule.Next(time.Now().In(loc)); exp.Enabled && !next.IsZero() {
+		response.NextRun = &next
+		w.Header().Set("X-Next-Run", next.UTC().Format(http.TimeFormat))
+	}
+
+	s.writeJSON(w, r, http.StatusOK, response)
+}
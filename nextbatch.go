@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// NextBatchRequest is the request body for computing several expressions'
+// next runs from the same instant. From is RFC3339 and defaults to now.
+type NextBatchRequest struct {
+	Expressions []string `json:"expressions"`
+	From        string   `json:"from"`
+}
+
+// NextBatchResult is one expression's next run; NextRun is null if it never
+// fires again
+type NextBatchResult struct {
+	Expression    string     `json:"expression"`
+	RawExpression string     `json:"rawExpression"`
+	NextRun       *time.Time `json:"nextRun"`
+}
+
+// NextBatchResponse lists next runs in input order
+type NextBatchResponse struct {
+	From    time.Time         `json:"from"`
+	Results []NextBatchResult `json:"results"`
+}
+
+func (s *Server) nextBatchHandler(w http.ResponseWriter, r *http.Request) {
+	var req NextBatchRequest
+	if !s.decodeJSON(w, r, &req) {
+		return
+	}
+
+	if len(req.Expressions) > maxBatchSize {
+		writeFieldError(w, http.StatusBadRequest, ErrInvalidRequest, "expressions", fmt.Sprintf("Batch exceeds maximum of %d expressions", maxBatchSize))
+		return
+	}
+
+	from := time.Now()
+	if req.From != "" {
+		var err error
+		from, err = time.Parse(time.RFC3339, req.From)
+		if err != nil {
+			writeFieldError(w, http.StatusBadRequest, ErrInvalidRequest, "from", "Invalid from timestamp, expected RFC3339: "+req.From)
+			return
+		}
+	}
+
+	response := NextBatchResponse{From: from, Results: make([]NextBatchResult, 0, len(req.Expressions))}
+	for i, raw := range req.Expressions {
+		expression := cleanExpression(raw)
+		schedule, err := parseExpression(expression)
+		if err != nil {
+			invalidCronExpressions.Inc()
+			writeError(w, http.StatusBadRequest, ErrInvalidCron, fmt.Sprintf("Invalid cron expression at index %d: %s", i, err.Error()))
+			return
+		}
+
+		result := NextBatchResult{Expression: expression, RawExpression: raw}
+		if next := schedule.Next(from); !next.IsZero() {
+			result.NextRun = &next
+		}
+		response.Results = append(response.Results, result)
+	}
+
+	s.writeJSON(w, r, http.StatusOK, response)
+}
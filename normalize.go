@@ -0,0 +1,204 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// NormalizeRequest is the request body for normalizing an expression
+type NormalizeRequest struct {
+	Expression string `json:"expression"`
+}
+
+// NormalizeResponse carries the original and canonical forms of an
+// expression. The canonical form is compacted where that doesn't change when
+// the expression runs, so "0,15,30,45 * * * *" normalizes to "*/15 * * * *".
+type NormalizeResponse struct {
+	Original   string `json:"original"`
+	Normalized string `json:"normalized"`
+	Changed    bool   `json:"changed"`
+}
+
+// normalizeExpression rewrites a valid 5-field expression into a canonical
+// form so semantically identical expressions compare equal as strings.
+// Known descriptors normalize to their canonical name, e.g. @midnight to
+// @daily; any other "@" expression is kept as is.
+func normalizeExpression(expression string) string {
+	if canonical, _, ok := resolveDescriptor(expression); ok {
+		return canonical
+	}
+	if trimmed := strings.TrimSpace(expression); strings.HasPrefix(trimmed, "@") {
+		return trimmed
+	}
+	parts := strings.Fields(expression)
+	for i, part := range parts {
+		if i < len(cronFields) {
+			parts[i] = normalizeField(part, cronFields[i])
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// normalizeField canonicalizes one field: "?" and "*/1" become "*", "a-b/1"
+// becomes "a-b", "5-5" becomes "5", names are uppercased and comma lists are
+// deduplicated and sorted by value
+func normalizeField(field string, f cronField) string {
+	items := []string{}
+	seen := map[string]bool{}
+	for _, item := range strings.Split(field, ",") {
+		item = strings.ToUpper(item)
+		if item == "?" {
+			item = "*"
+		}
+
+		if base := strings.TrimSuffix(item, "/1"); base != item && (base == "*" || strings.Contains(base, "-")) {
+			item = base
+		}
+
+		if bounds := strings.Split(item, "-"); len(bounds) == 2 && !strings.Contains(item, "/") {
+			start, okStart := f.value(bounds[0])
+			end, okEnd := f.value(bounds[1])
+			if okStart && okEnd && start == end {
+				item = bounds[0]
+			}
+		}
+
+		// A wildcard anywhere in the list covers every other item
+		if item == "*" {
+			return "*"
+		}
+
+		if !seen[item] {
+			seen[item] = true
+			items = append(items, item)
+		}
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		return itemStart(items[i], f) < itemStart(items[j], f)
+	})
+	return strings.Join(items, ",")
+}
+
+// compactField collapses a field made only of single values into the
+// shortest equivalent form: every value becomes "*", values evenly spaced
+// from the minimum through the end of the range become "*/n", and runs of
+// three or more consecutive values become "a-b". Fields with ranges, steps
+// or special characters are returned unchanged.
+func compactField(field string, f cronField) string {
+	items := strings.Split(field, ",")
+	values := make([]int, len(items))
+	for i, item := range items {
+		n, ok := f.value(item)
+		if !ok || (i > 0 && n <= values[i-1]) {
+			return field
+		}
+		values[i] = n
+	}
+	if len(values) < 2 {
+		return field
+	}
+
+	step := values[1] - values[0]
+	evenlySpaced := values[0] == f.min && values[len(values)-1]+step > f.max
+	for i := 2; i < len(values) && evenlySpaced; i++ {
+		evenlySpaced = values[i]-values[i-1] == step
+	}
+	if evenlySpaced {
+		if step == 1 {
+			return "*"
+		}
+		return fmt.Sprintf("*/%d", step)
+	}
+
+	compacted := []string{}
+	for start := 0; start < len(values); {
+		end := start
+		for end+1 < len(values) && values[end+1] == values[end]+1 {
+			end++
+		}
+		switch {
+		case end-start >= 2:
+			compacted = append(compacted, items[start]+"-"+items[end])
+		default:
+			compacted = append(compacted, items[start:end+1]...)
+		}
+		start = end + 1
+	}
+	return strings.Join(compacted, ",")
+}
+
+// compactExpression applies compactField to each field of a normalized
+// expression
+func compactExpression(expression string) string {
+	parts := strings.Fields(expression)
+	for i, part := range parts {
+		if i < len(cronFields) {
+			parts[i] = compactField(part, cronFields[i])
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// itemStart returns the first value covered by a list item, for sorting
+func itemStart(item string, f cronField) int {
+	start := strings.FieldsFunc(item, func(r rune) bool { return r == '-' || r == '/' })
+	if len(start) == 0 {
+		return f.min
+	}
+	if n, ok := f.value(start[0]); ok {
+		return n
+	}
+	return f.min
+}
+
+// canonicalExpression normalizes an expression and compacts it where that
+// doesn't change when it runs. Compaction can change meaning where the parser
+// treats "*" specially, such as day-of-month and day-of-week together, so the
+// compacted form is only kept if the two run sequences agree. Frequent
+// expressions are only compared up to maxEquivalenceRuns, which is still far
+// longer than any such difference takes to show up. Expressions that don't
+// parse are returned normalized but not compacted.
+func canonicalExpression(expression string) string {
+	normalized := normalizeExpression(expression)
+	if compacted := compactExpression(normalized); compacted != normalized {
+		original, err := parseExpression(normalized)
+		if err != nil {
+			return normalized
+		}
+		candidate, err := parseExpression(compacted)
+		if err == nil {
+			from := time.Now()
+			if diverged, _, _, _ := firstDivergence(original, candidate, from, from.Add(equivalenceWindow)); diverged.IsZero() {
+				return compacted
+			}
+		}
+	}
+	return normalized
+}
+
+func (s *Server) normalizeHandler(w http.ResponseWriter, r *http.Request) {
+	var req NormalizeRequest
+	if !s.decodeJSON(w, r, &req) {
+		return
+	}
+
+	expression := cleanExpression(req.Expression)
+	if _, err := parseExpression(expression); err != nil {
+		invalidCronExpressions.Inc()
+		writeError(w, http.StatusBadRequest, ErrInvalidCron, "Invalid cron expression: "+err.Error())
+		return
+	}
+
+	normalized := canonicalExpression(expression)
+	response := NormalizeResponse{
+		Original:   req.Expression,
+		Normalized: normalized,
+		Changed:    normalized != expression,
+	}
+
+	s.writeJSON(w, r, http.StatusOK, response)
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// PartialConvertResponse describes a partial expression after its missing
+// trailing fields have been filled with "*"
+type PartialConvertResponse struct {
+	Expression      string   `json:"expression"`
+	RawExpression   string   `json:"rawExpression"`
+	DefaultedFields []string `json:"defaultedFields"`
+	Description     string   `json:"description"`
+	NextExecutions  []string `json:"nextExecutions"`
+}
+
+// completeExpression pads a partial expression such as "0 9 *" to five
+// fields with "*" and returns the names of the fields it filled in
+func completeExpression(partial string) (string, []string, error) {
+	fields := strings.Fields(partial)
+	if len(fields) > len(cronFields) {
+		return "", nil, fmt.Errorf("expected at most %d fields, found %d", len(cronFields), len(fields))
+	}
+
+	defaulted := []string{}
+	for _, field := range cronFields[len(fields):] {
+		fields = append(fields, "*")
+		defaulted = append(defaulted, field.name)
+	}
+	return strings.Join(fields, " "), defaulted, nil
+}
+
+// partialConvertHandler describes an expression that is still being built,
+// for progressive builder UIs
+func (s *Server) partialConvertHandler(w http.ResponseWriter, r *http.Request) {
+	var req ConvertRequest
+	if !s.decodeJSON(w, r, &req) {
+		return
+	}
+
+	expression, defaulted, err := completeExpression(cleanExpression(req.Expression))
+	if fieldErr := locateFieldError(expression); err == nil && fieldErr != nil {
+		writeInvalidExpression(w, fieldErr)
+		return
+	}
+	if err == nil {
+		_, err = parseExpression(expression)
+	}
+	if err != nil {
+		invalidCronExpressions.Inc()
+		writeError(w, http.StatusBadRequest, ErrInvalidCron, "Invalid cron expression: "+err.Error())
+		return
+	}
+
+	response := PartialConvertResponse{
+		Expression:      expression,
+		RawExpression:   req.Expression,
+		DefaultedFields: defaulted,
+		Description:     timedDescription(expression, time.Sunday),
+		NextExecutions:  calculateNextExecutions(expression, 5),
+	}
+
+	s.writeJSON(w, r, http.StatusOK, response)
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+func (s *Server) pauseExpressionHandler(w http.ResponseWriter, r *http.Request) {
+	s.setExpressionEnabled(w, r, false)
+}
+
+func (s *Server) resumeExpressionHandler(w http.ResponseWriter, r *http.Request) {
+	s.setExpressionEnabled(w, r, true)
+}
+
+// setExpressionEnabled pauses or resumes an expression without deleting it
+// and returns the updated expression
+func (s *Server) setExpressionEnabled(w http.ResponseWriter, r *http.Request, enabled bool) {
+	id := mux.Vars(r)["id"]
+
+	ctx, cancel := s.dbContext(r)
+	defer cancel()
+
+	var exp CronExpression
+	err := scanExpression(s.db.writer().QueryRowContext(ctx, `
+		UPDATE cron_expressions e
+		SET enabled = $1, updated_at = $2
+		WHERE e.id = $3 AND e.deleted_at IS NULL
+		RETURNING `+expressionColumns+`
+	`, enabled, time.Now(), id), &exp)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			writeError(w, http.StatusNotFound, ErrNotFound, "Expression not found")
+		} else {
+			writeDBError(w, ctx, err)
+		}
+		return
+	}
+	s.lists.invalidate()
+	action := AuditPause
+	if enabled {
+		action = AuditResume
+	}
+	s.recordAudit(ctx, r, exp.ID, action, &exp)
+
+	s.writeJSON(w, r, http.StatusOK, exp)
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const (
+	defaultPolicySamples = 50
+	maxPolicySamples     = 1000
+)
+
+// TimeWindow is a daily wall-clock window such as 00:00-06:00. Windows whose
+// end is before their start wrap past midnight.
+type TimeWindow struct {
+	Start string `json:"start"`
+	End   string `json:"end"`
+}
+
+// SchedulePolicy describes the scheduling rules an expression must satisfy
+type SchedulePolicy struct {
+	ForbiddenWindows []TimeWindow `json:"forbiddenWindows"`
+	WeekdaysOnly     bool         `json:"weekdaysOnly"`
+	WeekendsOnly     bool         `json:"weekendsOnly"`
+}
+
+// PolicyRequest is the request body for validating an expression against a policy
+type PolicyRequest struct {
+	Expression string         `json:"expression"`
+	Samples    int            `json:"samples"`
+	Policy     SchedulePolicy `json:"policy"`
+}
+
+// PolicyViolation reports a rule broken by one or more sampled runs
+type PolicyViolation struct {
+	Rule           string `json:"rule"`
+	Message        string `json:"message"`
+	Occurrences    int    `json:"occurrences"`
+	FirstViolation string `json:"firstViolation"`
+}
+
+// PolicyResponse is the response for a policy validation
+type PolicyResponse struct {
+	Expression     string            `json:"expression"`
+	RawExpression  string            `json:"rawExpression"`
+	Compliant      bool              `json:"compliant"`
+	SamplesChecked int               `json:"samplesChecked"`
+	Violations     []PolicyViolation `json:"violations"`
+}
+
+// policyRule checks a single run time against one rule of the policy
+type policyRule struct {
+	name    string
+	message string
+	breaks  func(t time.Time) bool
+}
+
+// parseClock parses an HH:MM string into minutes past midnight
+func parseClock(value string) (int, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", value)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// buildPolicyRules turns the request policy into a list of checkable rules
+func buildPolicyRules(policy SchedulePolicy) ([]policyRule, error) {
+	if policy.WeekdaysOnly && policy.WeekendsOnly {
+		return nil, fmt.Errorf("weekdaysOnly and weekendsOnly are mutually exclusive")
+	}
+
+	rules := []policyRule{}
+	for _, window := range policy.ForbiddenWindows {
+		start, err := parseClock(window.Start)
+		if err != nil {
+			return nil, err
+		}
+		end, err := parseClock(window.End)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, policyRule{
+			name:    fmt.Sprintf("forbiddenWindow %s-%s", window.Start, window.End),
+			message: fmt.Sprintf("must not fire between %s and %s", window.Start, window.End),
+			breaks: func(t time.Time) bool {
+				minute := t.Hour()*60 + t.Minute()
+				if start <= end {
+					return minute >= start && minute < end
+				}
+				return minute >= start || minute < end
+			},
+		})
+	}
+
+	if policy.WeekdaysOnly {
+		rules = append(rules, policyRule{
+			name:    "weekdaysOnly",
+			message: "must only fire Monday to Friday",
+			breaks: func(t time.Time) bool {
+				return t.Weekday() == time.Saturday || t.Weekday() == time.Sunday
+			},
+		})
+	}
+
+	if policy.WeekendsOnly {
+		rules = append(rules, policyRule{
+			name:    "weekendsOnly",
+			message: "must only fire on Saturday or Sunday",
+			breaks: func(t time.Time) bool {
+				return t.Weekday() != time.Saturday && t.Weekday() != time.Sunday
+			},
+		})
+	}
+
+	return rules, nil
+}
+
+func (s *Server) validatePolicyHandler(w http.ResponseWriter, r *http.Request) {
+	var req PolicyRequest
+	if !s.decodeJSON(w, r, &req) {
+		return
+	}
+
+	expression := cleanExpression(req.Expression)
+	schedule, err := parseExpression(expression)
+	if err != nil {
+		invalidCronExpressions.Inc()
+		writeError(w, http.StatusBadRequest, ErrInvalidCron, "Invalid cron expression: "+err.Error())
+		return
+	}
+
+	rules, err := buildPolicyRules(req.Policy)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, ErrInvalidRequest, "Invalid policy: "+err.Error())
+		return
+	}
+
+	samples := req.Samples
+	if samples <= 0 {
+		samples = defaultPolicySamples
+	}
+	if samples > maxPolicySamples {
+		samples = maxPolicySamples
+	}
+
+	// Sample the next runs and check each one against every rule
+	violations := make([]*PolicyViolation, len(rules))
+	checked := 0
+	next := time.Now()
+	for checked < samples {
+		next = schedule.Next(next)
+		if next.IsZero() {
+			break
+		}
+		checked++
+		for j, rule := range rules {
+			if !rule.breaks(next) {
+				continue
+			}
+			if violations[j] == nil {
+				violations[j] = &PolicyViolation{
+					Rule:           rule.name,
+					Message:        rule.message,
+					FirstViolation: next.Format(time.RFC3339),
+				}
+			}
+			violations[j].Occurrences++
+		}
+	}
+
+	response := PolicyResponse{
+		Expression:     expression,
+		RawExpression:  req.Expression,
+		SamplesChecked: checked,
+		Violations:     []PolicyViolation{},
+	}
+	for _, v := range violations {
+		if v != nil {
+			response.Violations = append(response.Violations, *v)
+		}
+	}
+	response.Compliant = len(response.Violations) == 0
+
+	s.writeJSON(w, r, http.StatusOK, response)
+}
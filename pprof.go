@@ -0,0 +1,25 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"net/http/pprof"
+
+	"github.com/gorilla/mux"
+)
+
+// registerPprof mounts the net/http/pprof handlers under /debug/pprof/ behind
+// the metrics token. Named profiles such as /debug/pprof/heap are served by
+// pprof.Index. Without a metrics token nothing is mounted, however the
+// config was built, since metricsAuthMiddleware would let every request in.
+func (s *Server) registerPprof(r *mux.Router) {
+	if s.config.MetricsToken == "" {
+		log.Printf("Warning: pprof needs METRICS_TOKEN, not mounting /debug/pprof/")
+		return
+	}
+	r.Handle("/debug/pprof/cmdline", s.metricsAuthMiddleware(http.HandlerFunc(pprof.Cmdline)))
+	r.Handle("/debug/pprof/profile", s.metricsAuthMiddleware(http.HandlerFunc(pprof.Profile)))
+	r.Handle("/debug/pprof/symbol", s.metricsAuthMiddleware(http.HandlerFunc(pprof.Symbol)))
+	r.Handle("/debug/pprof/trace", s.metricsAuthMiddleware(http.HandlerFunc(pprof.Trace)))
+	r.PathPrefix("/debug/pprof/").Handler(s.metricsAuthMiddleware(http.HandlerFunc(pprof.Index)))
+}
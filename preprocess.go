@@ -0,0 +1,45 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+// trailingComment matches a crontab-style "# comment" after the expression.
+// The '#' must follow whitespace so Quartz "nth weekday" fields like 5#3 are
+// left alone.
+var trailingComment = regexp.MustCompile(`\s+#.*$`)
+
+// cleanExpression strips the noise users commonly paste along with an
+// expression: surrounding quotes and a trailing "# comment"
+func cleanExpression(raw string) string {
+	expression := strings.TrimSpace(raw)
+
+	// Keep only what's inside a leading quoted section, dropping anything
+	// after the closing quote
+	if expression != "" && strings.ContainsRune(`"'`+"`", rune(expression[0])) {
+		if end := strings.IndexByte(expression[1:], expression[0]); end >= 0 {
+			expression = expression[1 : end+1]
+		}
+	}
+
+	expression = trailingComment.ReplaceAllString(expression, "")
+	return fillStepBases(strings.TrimSpace(expression))
+}
+
+// fillStepBases rewrites steps missing their base, such as "/5", to "*/5" in
+// every field. The parser rejects a bare "/5" and the description would
+// otherwise split it into an empty base.
+func fillStepBases(expression string) string {
+	fields := strings.Fields(expression)
+	for i, field := range fields {
+		items := strings.Split(field, ",")
+		for j, item := range items {
+			if strings.HasPrefix(item, "/") {
+				items[j] = "*" + item
+			}
+		}
+		fields[i] = strings.Join(items, ",")
+	}
+	return strings.Join(fields, " ")
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"log"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// purgeSchedule is how often soft-deleted rows past retention are purged
+const purgeSchedule = "@hourly"
+
+// purgeSoftDeleted hard-deletes expressions soft-deleted more than the
+// configured retention ago and returns how many rows were removed
+func (s *Server) purgeSoftDeleted() (int64, error) {
+	cutoff := time.Now().Add(-s.config.SoftDeleteRetention)
+	result, err := s.db.writer().Exec(`
+		DELETE FROM cron_expressions
+		WHERE deleted_at IS NOT NULL AND deleted_at < $1
+	`, cutoff)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// startPurgeJob runs purgeSoftDeleted in the background on purgeSchedule
+func (s *Server) startPurgeJob() *cron.Cron {
+	c := cron.New()
+	c.AddFunc(purgeSchedule, func() {
+		purged, err := s.purgeSoftDeleted()
+		if err != nil {
+			log.Printf("Error purging soft-deleted expressions: %v", err)
+			return
+		}
+		softDeletedPurged.Add(float64(purged))
+		log.Printf("Purged %d soft-deleted expressions older than %s", purged, s.config.SoftDeleteRetention)
+	})
+	c.Start()
+	return c
+}
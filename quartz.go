@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Quartz-only constructs: L (last), W (nearest weekday) and # (nth weekday).
+// The patterns avoid matching day names such as WED.
+var (
+	quartzDomSpecial = regexp.MustCompile(`(^|,)L|\dW`)
+	quartzDowSpecial = regexp.MustCompile(`\dL|#|^L$`)
+)
+
+// Cron standards accepted by the convert endpoint
+const (
+	StandardUnix     = "unix"
+	StandardUnixYear = "unix-year"
+	StandardQuartz   = "quartz"
+)
+
+// toStandardExpression converts an expression written for the given standard
+// into a standard 5-field expression. An empty standard means unix.
+func toStandardExpression(standard, expression string) (string, error) {
+	switch strings.ToLower(standard) {
+	case "", StandardUnix:
+		return expression, nil
+	case StandardUnixYear:
+		// The year field is applied separately, see withYears
+		fields := strings.Fields(expression)
+		if len(fields) != 6 {
+			return "", fmt.Errorf("expected 6 fields with a year, found %d", len(fields))
+		}
+		return strings.Join(fields[:5], " "), nil
+	case StandardQuartz:
+		return quartzToStandard(expression)
+	}
+	return "", fmt.Errorf("unsupported standard %q", standard)
+}
+
+// quartzToStandard maps a Quartz expression (seconds minutes hours
+// day-of-month month day-of-week [year]) down to a 5-field expression. '?'
+// becomes '*' and day-of-week numbers are shifted from Quartz's 1-7 (SUN-SAT)
+// to cron's 0-6. Standard cron has no seconds or year, so the seconds field
+// must be 0 and the year, if given, must be '*'; anything else would fire at
+// different times once converted.
+func quartzToStandard(expression string) (string, error) {
+	fields := strings.Fields(expression)
+	if len(fields) != 6 && len(fields) != 7 {
+		return "", fmt.Errorf("expected 6 or 7 Quartz fields, found %d", len(fields))
+	}
+
+	if seconds, err := strconv.Atoi(fields[0]); err != nil || seconds != 0 {
+		return "", fmt.Errorf("Quartz seconds field %q must be 0, standard cron has no seconds", fields[0])
+	}
+	if len(fields) == 7 && fields[6] != "*" {
+		return "", fmt.Errorf("Quartz year field %q must be * or omitted, standard cron has no year", fields[6])
+	}
+
+	minute, hour, dom, month, dow := fields[1], fields[2], fields[3], fields[4], fields[5]
+	if quartzDomSpecial.MatchString(dom) || quartzDowSpecial.MatchString(dow) {
+		return "", fmt.Errorf("Quartz L, W and # constructs have no standard cron equivalent")
+	}
+
+	if dom == "?" {
+		dom = "*"
+	}
+	if dow == "?" {
+		dow = "*"
+	}
+
+	dow, err := shiftQuartzDow(dow)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.Join([]string{minute, hour, dom, month, dow}, " "), nil
+}
+
+// shiftQuartzDow converts numeric Quartz day-of-week values (1-7 = SUN-SAT)
+// to cron's 0-6, leaving names, wildcards and step sizes untouched
+func shiftQuartzDow(field string) (string, error) {
+	parts := strings.Split(field, ",")
+	for i, part := range parts {
+		rangeAndStep := strings.SplitN(part, "/", 2)
+		bounds := strings.Split(rangeAndStep[0], "-")
+		for j, bound := range bounds {
+			n, err := strconv.Atoi(bound)
+			if err != nil {
+				// Wildcard or day name
+				continue
+			}
+			if n < 1 || n > 7 {
+				return "", fmt.Errorf("Quartz day-of-week %d out of range 1-7", n)
+			}
+			bounds[j] = strconv.Itoa(n - 1)
+		}
+		rangeAndStep[0] = strings.Join(bounds, "-")
+		parts[i] = strings.Join(rangeAndStep, "/")
+	}
+	return strings.Join(parts, ","), nil
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// randomCadences are the cadences the random endpoint can generate
+var randomCadences = []string{CadenceSubHourly, CadenceHourly, CadenceDaily, CadenceWeekly, CadenceMonthly, CadenceYearly}
+
+// RandomResponse is the response for a generated random expression
+type RandomResponse struct {
+	Expression  string `json:"expression"`
+	Cadence     string `json:"cadence"`
+	Description string `json:"description"`
+}
+
+// randomExpression builds a concrete expression with the given cadence,
+// picking random values so jobs spread out instead of all firing at :00
+func randomExpression(rng *rand.Rand, cadence string) (string, error) {
+	minute := rng.Intn(60)
+	hour := rng.Intn(24)
+	switch cadence {
+	case CadenceSubHourly:
+		steps := []int{5, 10, 15, 20, 30}
+		step := steps[rng.Intn(len(steps))]
+		return fmt.Sprintf("%d-59/%d * * * *", rng.Intn(step), step), nil
+	case CadenceHourly:
+		return fmt.Sprintf("%d * * * *", minute), nil
+	case CadenceDaily:
+		return fmt.Sprintf("%d %d * * *", minute, hour), nil
+	case CadenceWeekly:
+		return fmt.Sprintf("%d %d * * %d", minute, hour, rng.Intn(7)), nil
+	case CadenceMonthly:
+		// Days 1-28 exist in every month
+		return fmt.Sprintf("%d %d %d * *", minute, hour, rng.Intn(28)+1), nil
+	case CadenceYearly:
+		return fmt.Sprintf("%d %d %d %d *", minute, hour, rng.Intn(28)+1, rng.Intn(12)+1), nil
+	}
+	return "", fmt.Errorf("unsupported cadence %q", cadence)
+}
+
+func (s *Server) randomExpressionHandler(w http.ResponseWriter, r *http.Request) {
+	// Seed per request so repeated calls give different schedules
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+
+	cadence := strings.ToLower(r.URL.Query().Get("cadence"))
+	if cadence == "" {
+		cadence = randomCadences[rng.Intn(len(randomCadences))]
+	}
+
+	expression, err := randomExpression(rng, cadence)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, ErrInvalidRequest, err.Error())
+		return
+	}
+
+	// Double-check the generated expression before handing it out
+	if _, err := parseExpression(expression); err != nil {
+		writeError(w, http.StatusInternalServerError, ErrInternal, "Generated invalid cron expression: "+err.Error())
+		return
+	}
+
+	response := RandomResponse{
+		Expression:  expression,
+		Cadence:     cadence,
+		Description: generateDescription(expression),
+	}
+
+	s.writeJSON(w, r, http.StatusOK, response)
+}
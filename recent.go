@@ -0,0 +1,75 @@
+package main
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+// recentCapacity is how many conversions GET /api/convert/recent remembers
+const recentCapacity = 100
+
+// RecentConversion is one entry of the recent conversions history. Repeated
+// consecutive conversions of the same expression share an entry, with Count
+// and ConvertedAt updated.
+type RecentConversion struct {
+	Expression  string    `json:"expression"`
+	Description string    `json:"description"`
+	ConvertedAt time.Time `json:"convertedAt"`
+	Count       int       `json:"count"`
+}
+
+// recentConversions is a fixed-size ring buffer of successful conversions.
+// It lives in memory only, so it starts empty on every restart.
+type recentConversions struct {
+	mu      sync.Mutex
+	entries []RecentConversion
+	next    int // index the next new entry is written to
+	full    bool
+}
+
+func newRecentConversions(capacity int) *recentConversions {
+	return &recentConversions{entries: make([]RecentConversion, capacity)}
+}
+
+// add records a conversion, folding it into the newest entry when it repeats
+// the same expression
+func (rc *recentConversions) add(expression, description string, at time.Time) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	if rc.next > 0 || rc.full {
+		last := &rc.entries[(rc.next-1+len(rc.entries))%len(rc.entries)]
+		if last.Expression == expression {
+			last.ConvertedAt = at
+			last.Count++
+			return
+		}
+	}
+
+	rc.entries[rc.next] = RecentConversion{Expression: expression, Description: description, ConvertedAt: at, Count: 1}
+	rc.next = (rc.next + 1) % len(rc.entries)
+	if rc.next == 0 {
+		rc.full = true
+	}
+}
+
+// list returns the recorded conversions, newest first
+func (rc *recentConversions) list() []RecentConversion {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	size := rc.next
+	if rc.full {
+		size = len(rc.entries)
+	}
+	list := make([]RecentConversion, 0, size)
+	for i := 1; i <= size; i++ {
+		list = append(list, rc.entries[(rc.next-i+len(rc.entries))%len(rc.entries)])
+	}
+	return list
+}
+
+func (s *Server) recentConversionsHandler(w http.ResponseWriter, r *http.Request) {
+	s.writeJSON(w, r, http.StatusOK, map[string][]RecentConversion{"conversions": s.recent.list()})
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Envelope is the response shape used when ENVELOPE_RESPONSES is enabled
+type Envelope struct {
+	Data  interface{}  `json:"data"`
+	Error *string      `json:"error"`
+	Meta  EnvelopeMeta `json:"meta"`
+}
+
+// EnvelopeMeta describes the request an enveloped response answers
+type EnvelopeMeta struct {
+	RequestID  string  `json:"requestId"`
+	DurationMs float64 `json:"durationMs"`
+}
+
+// writeJSON writes v as a JSON response with the given status. Successful
+// /api/ responses are wrapped in an Envelope when the deployment opts in;
+// otherwise v is written bare, as it always has been.
+func (s *Server) writeJSON(w http.ResponseWriter, r *http.Request, status int, v interface{}) {
+	s.writeEncoded(w, r, status, v, jsonEncoding{})
+}
+
+// writeNegotiated is writeJSON for high-volume responses, encoded in the
+// format the Accept header asks for, see negotiateEncoding
+func (s *Server) writeNegotiated(w http.ResponseWriter, r *http.Request, status int, v interface{}) {
+	w.Header().Add("Vary", "Accept")
+	s.writeEncoded(w, r, status, v, negotiateEncoding(r))
+}
+
+func (s *Server) writeEncoded(w http.ResponseWriter, r *http.Request, status int, v interface{}, encoding responseEncoding) {
+	if s.config.EnvelopeResponses && status < http.StatusBadRequest && strings.HasPrefix(r.URL.Path, "/api/") {
+		v = Envelope{
+			Data: v,
+			Meta: EnvelopeMeta{
+				RequestID:  requestID(r),
+				DurationMs: float64(time.Since(requestStart(r)).Microseconds()) / 1000,
+			},
+		}
+	}
+
+	w.Header().Set("Content-Type", encoding.contentType())
+	w.WriteHeader(status)
+	encoding.encode(w, v, s.config.PrettyJSON || r.URL.Query().Get("pretty") == "true")
+}
+
+// decodeJSON decodes the request body into v. On failure it writes a 400
+// naming the offending field and expected type where it can, and returns
+// false.
+func (s *Server) decodeJSON(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	err := json.NewDecoder(r.Body).Decode(v)
+	if err == nil {
+		return true
+	}
+
+	response := ErrorBody{Code: ErrInvalidJSON, Message: err.Error()}
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.Is(err, io.EOF):
+		response.Message = "Request body is empty"
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		response.Message = "Request body is truncated JSON"
+	case errors.As(err, &syntaxErr):
+		response.Message = "Request body is not valid JSON: " + syntaxErr.Error()
+		response.Offset = syntaxErr.Offset
+	case errors.As(err, &typeErr):
+		response.Field = typeErr.Field
+		response.Expected = typeErr.Type.String()
+		response.Offset = typeErr.Offset
+		response.Message = fmt.Sprintf("Field %q must be %s, got %s", typeErr.Field, typeErr.Type, typeErr.Value)
+	}
+	writeErrorBody(w, http.StatusBadRequest, response)
+	return false
+}
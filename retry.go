@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"syscall"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// readRetryBackoff is the wait before each retry of a failed read; its
+// length is the number of retries
+var readRetryBackoff = []time.Duration{50 * time.Millisecond, 200 * time.Millisecond}
+
+// retryRead runs a read-only query against the reader pool, retrying on
+// transient errors until ctx is done. Only use it for reads: retrying a
+// write that may have been applied is not safe.
+func (s *store) retryRead(ctx context.Context, query func(db *sql.DB) error) error {
+	err := query(s.reader())
+	for _, wait := range readRetryBackoff {
+		if !isTransient(err) {
+			break
+		}
+		dbQueryRetries.Inc()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+		err = query(s.reader())
+	}
+	return err
+}
+
+// queryRead runs a multi-row read query with retryRead
+func (s *store) queryRead(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	var rows *sql.Rows
+	err := s.retryRead(ctx, func(db *sql.DB) (err error) {
+		rows, err = db.QueryContext(ctx, query, args...)
+		return err
+	})
+	return rows, err
+}
+
+// isTransient reports whether err is a database error worth retrying:
+// dropped connections, serialization failures, deadlocks and server restarts
+func isTransient(err error) bool {
+	if err == nil {
+		return false
+	}
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code.Class() {
+		case "08", "40": // connection exception, transaction rollback
+			return true
+		}
+		return pqErr.Code == "57P01" // admin_shutdown
+	}
+	return errors.Is(err, syscall.ECONNRESET) || errors.Is(err, io.ErrUnexpectedEOF)
+}
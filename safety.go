@@ -0,0 +1,138 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// Default thresholds for the safety check
+const (
+	defaultMinStepMinutes = 5
+	defaultMaxRunsPerHour = 12
+)
+
+// Severities of safety findings, most severe first
+const (
+	SeverityCritical = "critical"
+	SeverityHigh     = "high"
+	SeverityMedium   = "medium"
+)
+
+// SafetyRequest is the request body for a safety check. Zero thresholds use
+// the defaults.
+type SafetyRequest struct {
+	Expression     string `json:"expression"`
+	MinStepMinutes int    `json:"minStepMinutes"`
+	MaxRunsPerHour int    `json:"maxRunsPerHour"`
+}
+
+// SafetyFinding is one triggered safety rule
+type SafetyFinding struct {
+	Rule     string `json:"rule"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+}
+
+// SafetyResponse reports whether an expression passes the safety rules, with
+// its run counts for context
+type SafetyResponse struct {
+	Expression    string            `json:"expression"`
+	RawExpression string            `json:"rawExpression"`
+	Pass          bool              `json:"pass"`
+	Findings      []SafetyFinding   `json:"findings"`
+	Frequency     FrequencyResponse `json:"frequency"`
+}
+
+// minMinuteGap returns the shortest gap in minutes between consecutive runs.
+// The gap from the last minute of one hour to the first of the next only
+// counts when both hours are scheduled, so "0,59 9 * * *" is 59 minutes, not
+// 1. Hour 23 is taken to run into hour 0 of the next day.
+func minMinuteGap(minutes, hours []int) int {
+	gap := 60
+	if len(minutes) > 0 && hasConsecutiveHours(hours) {
+		gap = minutes[0] + 60 - minutes[len(minutes)-1]
+	}
+	for i := 1; i < len(minutes); i++ {
+		if d := minutes[i] - minutes[i-1]; d < gap {
+			gap = d
+		}
+	}
+	return gap
+}
+
+// checkSafety applies the safety rules to a parsed schedule
+func checkSafety(schedule cron.Schedule, minStep, maxPerHour int) []SafetyFinding {
+	findings := []SafetyFinding{}
+	spec, ok := schedule.(*cron.SpecSchedule)
+	if !ok {
+		return findings
+	}
+
+	if classifyCadence(schedule) == CadenceEveryMinute {
+		findings = append(findings, SafetyFinding{
+			Rule:     "every-minute",
+			Severity: SeverityCritical,
+			Message:  "Runs every minute of every day",
+		})
+	}
+
+	minutes := bitValues(spec.Minute, 0, 59)
+	if gap := minMinuteGap(minutes, bitValues(spec.Hour, 0, 23)); gap < minStep {
+		findings = append(findings, SafetyFinding{
+			Rule:     "short-interval",
+			Severity: SeverityHigh,
+			Message:  fmt.Sprintf("Runs as little as %d minute(s) apart, below the minimum of %d", gap, minStep),
+		})
+	}
+
+	if len(minutes) > maxPerHour {
+		findings = append(findings, SafetyFinding{
+			Rule:     "runs-per-hour",
+			Severity: SeverityMedium,
+			Message:  fmt.Sprintf("Runs %d times in each hour it fires, above the maximum of %d", len(minutes), maxPerHour),
+		})
+	}
+	return findings
+}
+
+func (s *Server) validateSafetyHandler(w http.ResponseWriter, r *http.Request) {
+	var req SafetyRequest
+	if !s.decodeJSON(w, r, &req) {
+		return
+	}
+
+	minStep, maxPerHour := req.MinStepMinutes, req.MaxRunsPerHour
+	if minStep == 0 {
+		minStep = defaultMinStepMinutes
+	}
+	if maxPerHour == 0 {
+		maxPerHour = defaultMaxRunsPerHour
+	}
+	if minStep < 0 || maxPerHour < 0 {
+		writeError(w, http.StatusBadRequest, ErrInvalidRequest, "Thresholds must not be negative")
+		return
+	}
+
+	expression := cleanExpression(req.Expression)
+	schedule, err := parseExpression(expression)
+	if err != nil {
+		invalidCronExpressions.Inc()
+		writeError(w, http.StatusBadRequest, ErrInvalidCron, "Invalid cron expression: "+err.Error())
+		return
+	}
+
+	findings := checkSafety(schedule, minStep, maxPerHour)
+	response := SafetyResponse{
+		Expression:    expression,
+		RawExpression: req.Expression,
+		Pass:          len(findings) == 0,
+		Findings:      findings,
+		Frequency:     FrequencyResponse{Expression: expression, Enabled: true},
+	}
+	countFrequency(schedule, time.Now().In(s.config.DefaultTimezone), &response.Frequency)
+
+	s.writeJSON(w, r, http.StatusOK, response)
+}
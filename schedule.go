@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const (
+	defaultHeatmapDays = 7
+	maxHeatmapDays     = 28
+	// maxHeatmapRuns caps the schedule walk; an every-minute schedule over
+	// the maximum window stays under it
+	maxHeatmapRuns = 50000
+)
+
+// HeatmapRequest is the request body for a weekly heatmap
+type HeatmapRequest struct {
+	Expression string `json:"expression"`
+	Days       int    `json:"days"`
+}
+
+// HeatmapResponse counts runs per day-of-week (rows, Sunday first) and hour
+// of day (columns) over the sampled window
+type HeatmapResponse struct {
+	Expression string     `json:"expression"`
+	From       time.Time  `json:"from"`
+	To         time.Time  `json:"to"`
+	Days       []string   `json:"days"`
+	Grid       [7][24]int `json:"grid"`
+	Total      int        `json:"total"`
+	Truncated  bool       `json:"truncated"`
+}
+
+func (s *Server) heatmapHandler(w http.ResponseWriter, r *http.Request) {
+	var req HeatmapRequest
+	if !s.decodeJSON(w, r, &req) {
+		return
+	}
+
+	days := req.Days
+	if days == 0 {
+		days = defaultHeatmapDays
+	}
+	if days < 0 || days > maxHeatmapDays {
+		writeFieldError(w, http.StatusBadRequest, ErrInvalidRequest, "days", fmt.Sprintf("days must be between 1 and %d", maxHeatmapDays))
+		return
+	}
+
+	expression := cleanExpression(req.Expression)
+	schedule, err := parseExpression(expression)
+	if err != nil {
+		invalidCronExpressions.Inc()
+		writeError(w, http.StatusBadRequest, ErrInvalidCron, "Invalid cron expression: "+err.Error())
+		return
+	}
+
+	from := time.Now()
+	response := HeatmapResponse{
+		Expression: expression,
+		From:       from,
+		To:         from.AddDate(0, 0, days),
+		Days:       []string{},
+	}
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		response.Days = append(response.Days, d.String())
+	}
+
+	// Walk the schedule across the window and bucket each run
+	for next := schedule.Next(from); !next.IsZero() && next.Before(response.To); next = schedule.Next(next) {
+		if response.Total == maxHeatmapRuns {
+			response.Truncated = true
+			break
+		}
+		response.Grid[next.Weekday()][next.Hour()]++
+		response.Total++
+	}
+
+	s.writeJSON(w, r, http.StatusOK, response)
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// secondsParser parses robfig-style 6-field expressions with a leading seconds field
+var secondsParser = cron.NewParser(cron.Second | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow)
+
+// describeWithSeconds describes a 6-field expression with a leading seconds
+// field by folding a seconds phrase into the 5-field description
+func describeWithSeconds(expression string, weekStart time.Weekday) string {
+	fields := strings.Fields(expression)
+	if len(fields) != 6 {
+		return "Invalid cron expression"
+	}
+
+	const prefix = "This cron expression will run "
+	description := describeExpression(strings.Join(fields[1:], " "), weekStart)
+	if !strings.HasPrefix(description, prefix) {
+		return description
+	}
+	rest := strings.TrimPrefix(description, prefix)
+	second, minute := describeSeconds(fields[0]), fields[1]
+	repeating := strings.HasPrefix(second, "every")
+
+	switch {
+	case minute == "*" && strings.HasPrefix(rest, "every minute"):
+		rest = strings.TrimPrefix(rest, "every minute")
+		if repeating {
+			// "every 30 seconds" already implies every minute
+			rest = second + rest
+		} else {
+			rest = second + " of every minute" + rest
+		}
+	case strings.HasPrefix(rest, "every"):
+		// "at second 15 of every 5 minutes"
+		rest = second + " of " + rest
+	case repeating:
+		// "every second during the minute at 9:00"
+		rest = second + " during the minute " + rest
+	default:
+		rest = second + ", " + rest
+	}
+	return prefix + rest
+}
+
+// describeSeconds describes a seconds field, mirroring the minute phrasing
+func describeSeconds(second string) string {
+	switch second {
+	case "*", "*/1":
+		return "every second"
+	}
+
+	switch {
+	case strings.Contains(second, ","):
+		return fmt.Sprintf("at seconds %s", joinNatural(strings.Split(second, ",")))
+	case strings.Contains(second, "/"):
+		parts := strings.SplitN(second, "/", 2)
+		if bounds := strings.Split(parts[0], "-"); len(bounds) == 2 {
+			return fmt.Sprintf("every %s seconds from second %s to %s", parts[1], bounds[0], bounds[1])
+		}
+		if parts[0] != "*" {
+			return fmt.Sprintf("every %s seconds starting at second %s", parts[1], parts[0])
+		}
+		return fmt.Sprintf("every %s seconds", parts[1])
+	case strings.Contains(second, "-"):
+		bounds := strings.SplitN(second, "-", 2)
+		return fmt.Sprintf("every second from second %s to %s", bounds[0], bounds[1])
+	}
+	return fmt.Sprintf("at second %s", second)
+}
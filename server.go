@@ -0,0 +1,309 @@
+package main
+
+import (
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Config holds the runtime configuration for the server
+type Config struct {
+	Port      string
+	StaticDir string
+
+	// SlowRequestThreshold is the duration above which a request is logged
+	// and counted as slow
+	SlowRequestThreshold time.Duration
+
+	// APIToken, when set, is required as a bearer token on /api/ routes
+	APIToken string
+
+	// MetricsToken, when set, is required as a bearer token on /metrics and
+	// the pprof routes
+	MetricsToken string
+
+	// EnablePprof mounts net/http/pprof under /debug/pprof/. It needs
+	// MetricsToken so profiles are never exposed unauthenticated.
+	EnablePprof bool
+
+	// CORSAllowedOrigins lists origins allowed to call the API; "*" allows any
+	CORSAllowedOrigins []string
+
+	// DefaultTimezone is the zone stored expressions are evaluated in
+	DefaultTimezone *time.Location
+
+	// SoftDeleteRetention is how long deleted expressions are kept before
+	// being purged
+	SoftDeleteRetention time.Duration
+
+	// EnvelopeResponses wraps successful /api/ JSON responses in
+	// {data, error, meta} instead of returning them bare
+	EnvelopeResponses bool
+
+	// DBQueryTimeout bounds the database calls of a single request; requests
+	// that exceed it get 504
+	DBQueryTimeout time.Duration
+
+	// ReadyzTimeout bounds the database ping made by /readyz, so a wedged
+	// database fails the probe instead of hanging it
+	ReadyzTimeout time.Duration
+
+	// EnableWebhooks runs the webhook scheduler in this process. Enable it on
+	// a single instance, or every instance will deliver each run.
+	EnableWebhooks bool
+
+	// WebhookTimeout bounds each webhook delivery attempt
+	WebhookTimeout time.Duration
+
+	// WebhookAllowPrivate lets webhooks reach loopback, private and
+	// link-local addresses. Only for local development: otherwise webhooks
+	// can be pointed at internal services.
+	WebhookAllowPrivate bool
+
+	// MinInterval is the shortest gap allowed between runs of a saved
+	// expression; zero turns the policy off
+	MinInterval time.Duration
+
+	// ListCacheTTL is how long GET /api/expressions results are cached;
+	// zero disables the cache
+	ListCacheTTL time.Duration
+
+	// PrettyJSON indents every JSON response, for development. Otherwise
+	// ?pretty=true indents a single response.
+	PrettyJSON bool
+
+	// StrictContentType rejects API request bodies sent without a
+	// Content-Type instead of assuming JSON
+	StrictContentType bool
+
+	// EnabledConverters is the set of converters this deployment exposes
+	EnabledConverters map[string]bool
+
+	// TrustedProxies are the peer ranges whose X-Forwarded-For header is
+	// believed, see clientIP
+	TrustedProxies []*net.IPNet
+}
+
+// loadConfig reads the server configuration from environment variables
+func loadConfig() Config {
+	config := Config{
+		Port:                 os.Getenv("PORT"),
+		StaticDir:            "./static",
+		SlowRequestThreshold: 250 * time.Millisecond,
+		APIToken:             os.Getenv("API_TOKEN"),
+		MetricsToken:         os.Getenv("METRICS_TOKEN"),
+		EnablePprof:          os.Getenv("ENABLE_PPROF") == "true",
+		CORSAllowedOrigins:   splitList(os.Getenv("CORS_ALLOWED_ORIGINS")),
+		DefaultTimezone:      time.Local,
+		SoftDeleteRetention:  30 * 24 * time.Hour,
+		DBQueryTimeout:       5 * time.Second,
+		ReadyzTimeout:        2 * time.Second,
+		EnableWebhooks:       os.Getenv("ENABLE_WEBHOOKS") == "true",
+		WebhookTimeout:       5 * time.Second,
+		WebhookAllowPrivate:  os.Getenv("WEBHOOK_ALLOW_PRIVATE") == "true",
+		EnabledConverters:    parseEnabledConverters(os.Getenv("ENABLED_CONVERTERS")),
+		StrictContentType:    os.Getenv("STRICT_CONTENT_TYPE") == "true",
+		EnvelopeResponses:    os.Getenv("ENVELOPE_RESPONSES") == "true",
+		PrettyJSON:           os.Getenv("PRETTY_JSON") == "true",
+		TrustedProxies:       parseTrustedProxies(os.Getenv("TRUSTED_PROXIES")),
+	}
+	if config.Port == "" {
+		config.Port = "8080"
+	}
+	if v := os.Getenv("SLOW_REQUEST_MS"); v != "" {
+		if ms, err := strconv.Atoi(v); err == nil && ms > 0 {
+			config.SlowRequestThreshold = time.Duration(ms) * time.Millisecond
+		} else {
+			log.Printf("Warning: invalid SLOW_REQUEST_MS %q, using %s", v, config.SlowRequestThreshold)
+		}
+	}
+	if v := os.Getenv("DB_QUERY_TIMEOUT"); v != "" {
+		if timeout, err := time.ParseDuration(v); err == nil && timeout > 0 {
+			config.DBQueryTimeout = timeout
+		} else {
+			log.Printf("Warning: invalid DB_QUERY_TIMEOUT %q, using %s", v, config.DBQueryTimeout)
+		}
+	}
+	if v := os.Getenv("READYZ_TIMEOUT"); v != "" {
+		if timeout, err := time.ParseDuration(v); err == nil && timeout > 0 {
+			config.ReadyzTimeout = timeout
+		} else {
+			log.Printf("Warning: invalid READYZ_TIMEOUT %q, using %s", v, config.ReadyzTimeout)
+		}
+	}
+	if v := os.Getenv("LIST_CACHE_TTL_MS"); v != "" {
+		if ms, err := strconv.Atoi(v); err == nil && ms >= 0 {
+			config.ListCacheTTL = time.Duration(ms) * time.Millisecond
+		} else {
+			log.Printf("Warning: invalid LIST_CACHE_TTL_MS %q, using %s", v, config.ListCacheTTL)
+		}
+	}
+	if v := os.Getenv("DEFAULT_TIMEZONE"); v != "" {
+		if loc, err := time.LoadLocation(v); err == nil {
+			config.DefaultTimezone = loc
+		} else {
+			log.Printf("Warning: invalid DEFAULT_TIMEZONE %q, using %s", v, config.DefaultTimezone)
+		}
+	}
+	if v := os.Getenv("WEBHOOK_TIMEOUT"); v != "" {
+		if timeout, err := time.ParseDuration(v); err == nil && timeout > 0 {
+			config.WebhookTimeout = timeout
+		} else {
+			log.Printf("Warning: invalid WEBHOOK_TIMEOUT %q, using %s", v, config.WebhookTimeout)
+		}
+	}
+	if v := os.Getenv("MIN_INTERVAL_SECONDS"); v != "" {
+		if seconds, err := strconv.Atoi(v); err == nil && seconds >= 0 {
+			config.MinInterval = time.Duration(seconds) * time.Second
+		} else {
+			log.Printf("Warning: invalid MIN_INTERVAL_SECONDS %q, using %s", v, config.MinInterval)
+		}
+	}
+	if v := os.Getenv("SOFT_DELETE_RETENTION_DAYS"); v != "" {
+		if days, err := strconv.Atoi(v); err == nil && days >= 0 {
+			config.SoftDeleteRetention = time.Duration(days) * 24 * time.Hour
+		} else {
+			log.Printf("Warning: invalid SOFT_DELETE_RETENTION_DAYS %q, using %s", v, config.SoftDeleteRetention)
+		}
+	}
+	if config.EnablePprof && config.MetricsToken == "" {
+		log.Printf("Warning: ENABLE_PPROF requires METRICS_TOKEN, pprof disabled")
+		config.EnablePprof = false
+	}
+	return config
+}
+
+// splitList splits a comma-separated environment value, dropping blanks
+func splitList(value string) []string {
+	items := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// Server holds the dependencies shared by the HTTP handlers
+type Server struct {
+	db           *store
+	config       Config
+	streams      *streamSessions
+	descriptions *descriptionCache
+	recent       *recentConversions
+	lists        *listCache
+}
+
+// NewServer creates a server backed by the given database store
+func NewServer(db *store, config Config) *Server {
+	return &Server{
+		db:           db,
+		config:       config,
+		streams:      newStreamSessions(),
+		descriptions: newDescriptionCache(descriptionCacheCapacity),
+		recent:       newRecentConversions(recentCapacity),
+		lists:        newListCache(config.ListCacheTTL),
+	}
+}
+
+// routes builds the router with all API, metrics and static file routes
+func (s *Server) routes() *mux.Router {
+	r := mux.NewRouter()
+
+	// Probe and discovery routes, see exemptRoutes
+	r.HandleFunc("/healthz", s.metricMiddleware("/healthz", s.healthzHandler)).Methods("GET")
+	r.HandleFunc("/readyz", s.metricMiddleware("/readyz", s.readyzHandler)).Methods("GET")
+	r.HandleFunc("/api/version", s.metricMiddleware("/api/version", s.versionHandler)).Methods("GET")
+
+	// Define routes with metrics middleware
+	r.HandleFunc("/api/convert", s.metricMiddleware("/api/convert", s.convertCronHandler)).Methods("POST")
+	r.HandleFunc("/api/convert/batch", s.metricMiddleware("/api/convert/batch", s.batchConvertHandler)).Methods("POST")
+	s.handleConverter(r, ConverterSystemd, "/api/convert/systemd", s.systemdConvertHandler)
+	r.HandleFunc("/api/convert/partial", s.metricMiddleware("/api/convert/partial", s.partialConvertHandler)).Methods("POST")
+	r.HandleFunc("/api/convert/recent", s.metricMiddleware("/api/convert/recent", s.recentConversionsHandler)).Methods("GET")
+	r.HandleFunc("/api/convert/stream", s.metricMiddleware("/api/convert/stream", s.convertStreamHandler)).Methods("GET")
+	r.HandleFunc("/api/convert/stream/{session}", s.metricMiddleware("/api/convert/stream/{session}", s.convertStreamUpdateHandler)).Methods("POST")
+	r.HandleFunc("/api/detect", s.metricMiddleware("/api/detect", s.detectHandler)).Methods("POST")
+	r.HandleFunc("/api/normalize", s.metricMiddleware("/api/normalize", s.normalizeHandler)).Methods("POST")
+	r.HandleFunc("/api/equivalent", s.metricMiddleware("/api/equivalent", s.equivalentHandler)).Methods("POST")
+	r.HandleFunc("/api/stagger", s.metricMiddleware("/api/stagger", s.staggerHandler)).Methods("POST")
+	r.HandleFunc("/api/fit", s.metricMiddleware("/api/fit", s.fitHandler)).Methods("POST")
+	r.HandleFunc("/api/shift", s.metricMiddleware("/api/shift", s.shiftHandler)).Methods("POST")
+	r.HandleFunc("/api/explain/standards", s.metricMiddleware("/api/explain/standards", s.explainStandardsHandler)).Methods("POST")
+	r.HandleFunc("/api/share", s.metricMiddleware("/api/share", s.createShareHandler)).Methods("POST")
+	r.HandleFunc("/api/share/{token}", s.metricMiddleware("/api/share/{token}", s.getShareHandler)).Methods("GET")
+	r.HandleFunc("/api/lint", s.metricMiddleware("/api/lint", s.lintHandler)).Methods("POST")
+	r.HandleFunc("/api/tokenize", s.metricMiddleware("/api/tokenize", s.tokenizeHandler)).Methods("POST")
+	r.HandleFunc("/api/random", s.metricMiddleware("/api/random", s.randomExpressionHandler)).Methods("GET")
+	r.HandleFunc("/api/validate/cadence", s.metricMiddleware("/api/validate/cadence", s.validateCadenceHandler)).Methods("POST")
+	r.HandleFunc("/api/validate/dow", s.metricMiddleware("/api/validate/dow", s.validateDowHandler)).Methods("POST")
+	r.HandleFunc("/api/validate/policy", s.metricMiddleware("/api/validate/policy", s.validatePolicyHandler)).Methods("POST")
+	r.HandleFunc("/api/validate/safety", s.metricMiddleware("/api/validate/safety", s.validateSafetyHandler)).Methods("POST")
+	r.HandleFunc("/api/schedule/heatmap", s.metricMiddleware("/api/schedule/heatmap", s.heatmapHandler)).Methods("POST")
+	r.HandleFunc("/api/schedule/load", s.metricMiddleware("/api/schedule/load", s.scheduleLoadHandler)).Methods("GET")
+	r.HandleFunc("/api/schedule/common", s.metricMiddleware("/api/schedule/common", s.commonScheduleHandler)).Methods("POST")
+	r.HandleFunc("/api/schedule/frequency", s.metricMiddleware("/api/schedule/frequency", s.frequencyHandler)).Methods("POST")
+	r.HandleFunc("/api/schedule/next-batch", s.metricMiddleware("/api/schedule/next-batch", s.nextBatchHandler)).Methods("POST")
+	r.HandleFunc("/api/schedule/aligned", s.metricMiddleware("/api/schedule/aligned", s.alignedScheduleHandler)).Methods("POST")
+	r.HandleFunc("/api/config/validate", s.metricMiddleware("/api/config/validate", s.validateConfigHandler)).Methods("POST")
+	r.HandleFunc("/api/crontab/parse", s.metricMiddleware("/api/crontab/parse", s.parseCrontabHandler)).Methods("POST")
+	r.HandleFunc("/api/crontab/export", s.metricMiddleware("/api/crontab/export", s.exportCrontabHandler)).Methods("GET")
+	r.HandleFunc("/api/expressions", s.metricMiddleware("/api/expressions", s.getExpressionsHandler)).Methods("GET")
+	r.HandleFunc("/api/expressions", s.metricMiddleware("/api/expressions", s.createExpressionHandler)).Methods("POST")
+	r.HandleFunc("/api/expressions/distinct", s.metricMiddleware("/api/expressions/distinct", s.getDistinctExpressionsHandler)).Methods("GET")
+	r.HandleFunc("/api/expressions/by-expression", s.metricMiddleware("/api/expressions/by-expression", s.getExpressionsByValueHandler)).Methods("GET")
+	r.HandleFunc("/api/expressions/upload", s.metricMiddleware("/api/expressions/upload", s.uploadExpressionsHandler)).Methods("POST")
+	r.HandleFunc("/api/expressions/descriptions", s.metricMiddleware("/api/expressions/descriptions", s.bulkDescriptionsHandler)).Methods("POST")
+	r.HandleFunc("/api/expressions/{id}", s.metricMiddleware("/api/expressions/{id}", s.getExpressionHandler)).Methods("GET")
+	r.HandleFunc("/api/expressions/{id}", s.metricMiddleware("/api/expressions/{id}", s.updateExpressionHandler)).Methods("PUT")
+	r.HandleFunc("/api/expressions/{id}", s.metricMiddleware("/api/expressions/{id}", s.deleteExpressionHandler)).Methods("DELETE")
+	r.HandleFunc("/api/expressions/{id}/next", s.metricMiddleware("/api/expressions/{id}/next", s.nextRunHandler)).Methods("GET")
+	r.HandleFunc("/api/expressions/{id}/worldclock", s.metricMiddleware("/api/expressions/{id}/worldclock", s.worldclockHandler)).Methods("GET")
+	r.HandleFunc("/api/expressions/{id}/weekly", s.metricMiddleware("/api/expressions/{id}/weekly", s.weeklyHandler)).Methods("GET")
+	r.HandleFunc("/api/expressions/{id}/max-gap", s.metricMiddleware("/api/expressions/{id}/max-gap", s.maxGapHandler)).Methods("GET")
+	r.HandleFunc("/api/expressions/{id}/hourly-distribution", s.metricMiddleware("/api/expressions/{id}/hourly-distribution", s.hourlyDistributionHandler)).Methods("GET")
+	r.HandleFunc("/api/expressions/{id}/timeline", s.metricMiddleware("/api/expressions/{id}/timeline", s.timelineHandler)).Methods("GET")
+	r.HandleFunc("/api/expressions/{id}/frequency", s.metricMiddleware("/api/expressions/{id}/frequency", s.expressionFrequencyHandler)).Methods("GET")
+	r.HandleFunc("/api/expressions/{id}/pause", s.metricMiddleware("/api/expressions/{id}/pause", s.pauseExpressionHandler)).Methods("POST")
+	r.HandleFunc("/api/expressions/{id}/resume", s.metricMiddleware("/api/expressions/{id}/resume", s.resumeExpressionHandler)).Methods("POST")
+	r.HandleFunc("/api/expressions/{id}/webhooks", s.metricMiddleware("/api/expressions/{id}/webhooks", s.createWebhookHandler)).Methods("POST")
+	r.HandleFunc("/api/expressions/{id}/webhooks", s.metricMiddleware("/api/expressions/{id}/webhooks", s.listWebhooksHandler)).Methods("GET")
+	r.HandleFunc("/api/expressions/{id}/webhooks/{webhook}", s.metricMiddleware("/api/expressions/{id}/webhooks/{webhook}", s.deleteWebhookHandler)).Methods("DELETE")
+	r.HandleFunc("/api/categories", s.metricMiddleware("/api/categories", s.getCategoriesHandler)).Methods("GET")
+	r.HandleFunc("/api/categories", s.metricMiddleware("/api/categories", s.createCategoryHandler)).Methods("POST")
+	r.HandleFunc("/api/categories/{id}", s.metricMiddleware("/api/categories/{id}", s.getCategoryHandler)).Methods("GET")
+	r.HandleFunc("/api/categories/{id}", s.metricMiddleware("/api/categories/{id}", s.updateCategoryHandler)).Methods("PUT")
+	r.HandleFunc("/api/categories/{id}", s.metricMiddleware("/api/categories/{id}", s.deleteCategoryHandler)).Methods("DELETE")
+	r.HandleFunc("/api/admin/metrics/resync", s.metricMiddleware("/api/admin/metrics/resync", s.adminOnly(s.resyncMetricsHandler))).Methods("POST")
+	r.HandleFunc("/api/admin/validate-all", s.metricMiddleware("/api/admin/validate-all", s.adminOnly(s.validateAllHandler))).Methods("POST")
+	r.HandleFunc("/api/admin/snapshot", s.metricMiddleware("/api/admin/snapshot", s.adminOnly(s.snapshotHandler))).Methods("POST")
+	r.HandleFunc("/api/admin/restore", s.metricMiddleware("/api/admin/restore", s.adminOnly(s.restoreHandler))).Methods("POST")
+	r.HandleFunc("/api/admin/dedup", s.metricMiddleware("/api/admin/dedup", s.adminOnly(s.dedupHandler))).Methods("POST")
+
+	// Add Prometheus metrics endpoint
+	r.Handle("/metrics", s.metricsAuthMiddleware(promhttp.Handler()))
+
+	// Profiling, only when explicitly enabled
+	if s.config.EnablePprof {
+		s.registerPprof(r)
+	}
+
+	// Serve static files
+	r.PathPrefix("/").Handler(http.FileServer(http.Dir(s.config.StaticDir)))
+
+	return r
+}
+
+// handler wraps the router with the middleware that must run before route
+// matching, such as CORS preflight handling. Request IDs and panic recovery
+// are outermost so they cover everything else.
+func (s *Server) handler() http.Handler {
+	return requestIDMiddleware(recoverMiddleware(s.corsMiddleware(s.authMiddleware(s.contentTypeMiddleware(s.routes())))))
+}
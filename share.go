@@ -0,0 +1,168 @@
+package main
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// maxShareHours caps how far in the future a share may expire
+const maxShareHours = 24 * 365
+
+// shareOptions are the convert query options a share may carry
+var shareOptions = map[string]bool{
+	"weekStart":   true,
+	"relative":    true,
+	"withIcons":   true,
+	"groupByDate": true,
+}
+
+// ShareRequest is a conversion to share: the convert body plus its query
+// options. ExpiresInHours of 0 keeps the share forever.
+type ShareRequest struct {
+	ConvertRequest
+	Options        map[string]string `json:"options"`
+	ExpiresInHours int               `json:"expiresInHours"`
+}
+
+// ShareResponse describes a shared conversion. Conversion is re-rendered on
+// every read, so next runs are always current.
+type ShareResponse struct {
+	Token      string            `json:"token"`
+	Request    ConvertRequest    `json:"request"`
+	Options    map[string]string `json:"options"`
+	CreatedAt  time.Time         `json:"createdAt"`
+	ExpiresAt  *time.Time        `json:"expiresAt,omitempty"`
+	Conversion ConvertResponse   `json:"conversion"`
+}
+
+// newShareToken returns a short URL-safe random token
+func newShareToken() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// withOptions returns a copy of r whose query string holds only options
+func withOptions(r *http.Request, options map[string]string) *http.Request {
+	query := url.Values{}
+	for key, value := range options {
+		query.Set(key, value)
+	}
+	shared := r.Clone(r.Context())
+	shared.URL.RawQuery = query.Encode()
+	return shared
+}
+
+// createShareHandler stores a conversion and returns a token to share it by
+func (s *Server) createShareHandler(w http.ResponseWriter, r *http.Request) {
+	var req ShareRequest
+	if !s.decodeJSON(w, r, &req) {
+		return
+	}
+	if req.Options == nil {
+		req.Options = map[string]string{}
+	}
+	for key := range req.Options {
+		if !shareOptions[key] {
+			writeError(w, http.StatusBadRequest, ErrInvalidRequest, fmt.Sprintf("Unsupported share option %q", key))
+			return
+		}
+	}
+	if req.ExpiresInHours < 0 || req.ExpiresInHours > maxShareHours {
+		writeFieldError(w, http.StatusBadRequest, ErrInvalidRequest, "expiresInHours", fmt.Sprintf("expiresInHours must be between 0 and %d", maxShareHours))
+		return
+	}
+
+	// Only shareable if it converts now
+	conversion, ok := s.convert(w, withOptions(r, req.Options), req.ConvertRequest)
+	if !ok {
+		return
+	}
+
+	request, _ := json.Marshal(req.ConvertRequest)
+	options, _ := json.Marshal(req.Options)
+	response := ShareResponse{
+		Token:      newShareToken(),
+		Request:    req.ConvertRequest,
+		Options:    req.Options,
+		CreatedAt:  time.Now(),
+		Conversion: conversion,
+	}
+	if req.ExpiresInHours > 0 {
+		expiresAt := response.CreatedAt.Add(time.Duration(req.ExpiresInHours) * time.Hour)
+		response.ExpiresAt = &expiresAt
+	}
+
+	ctx, cancel := s.dbContext(r)
+	defer cancel()
+
+	_, err := s.db.writer().ExecContext(ctx, `
+		INSERT INTO shared_conversions (token, request, options, created_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+	`, response.Token, request, options, response.CreatedAt, response.ExpiresAt)
+	if err != nil {
+		writeDBError(w, ctx, err)
+		return
+	}
+
+	s.writeJSON(w, r, http.StatusCreated, response)
+}
+
+// getShareHandler loads a shared conversion and renders it afresh
+func (s *Server) getShareHandler(w http.ResponseWriter, r *http.Request) {
+	token := mux.Vars(r)["token"]
+
+	var request, options []byte
+	var expiresAt sql.NullTime
+	response := ShareResponse{Token: token}
+	ctx, cancel := s.dbContext(r)
+	defer cancel()
+
+	err := s.db.retryRead(ctx, func(db *sql.DB) error {
+		return db.QueryRowContext(ctx, `
+			SELECT request, options, created_at, expires_at
+			FROM shared_conversions
+			WHERE token = $1
+		`, token).Scan(&request, &options, &response.CreatedAt, &expiresAt)
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			writeError(w, http.StatusNotFound, ErrNotFound, "Share not found")
+		} else {
+			writeDBError(w, ctx, err)
+		}
+		return
+	}
+	if expiresAt.Valid {
+		if time.Now().After(expiresAt.Time) {
+			writeError(w, http.StatusGone, ErrExpired, "Share has expired")
+			return
+		}
+		response.ExpiresAt = &expiresAt.Time
+	}
+
+	if err := json.Unmarshal(request, &response.Request); err != nil {
+		writeError(w, http.StatusInternalServerError, ErrInternal, err.Error())
+		return
+	}
+	if err := json.Unmarshal(options, &response.Options); err != nil {
+		writeError(w, http.StatusInternalServerError, ErrInternal, err.Error())
+		return
+	}
+
+	conversion, ok := s.convert(w, withOptions(r, response.Options), response.Request)
+	if !ok {
+		return
+	}
+	response.Conversion = conversion
+
+	s.writeJSON(w, r, http.StatusOK, response)
+}
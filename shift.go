@@ -0,0 +1,152 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// minutesPerDay bounds shift offsets; larger shifts would move runs by days
+const minutesPerDay = 24 * 60
+
+// ShiftRequest is the request body for shifting an expression's run times.
+// The offset is Hours*60 + Minutes and may be negative.
+type ShiftRequest struct {
+	Expression string `json:"expression"`
+	Minutes    int    `json:"minutes"`
+	Hours      int    `json:"hours"`
+}
+
+// ShiftResponse is the shifted expression with its description and next runs
+type ShiftResponse struct {
+	Expression     string   `json:"expression"`
+	OffsetMinutes  int      `json:"offsetMinutes"`
+	Shifted        string   `json:"shifted"`
+	Description    string   `json:"description"`
+	NextExecutions []string `json:"nextExecutions"`
+}
+
+// shiftExpression moves every run of a 5-field expression by offset minutes.
+// Runs pushed past midnight move to the next or previous weekday, which is
+// only possible when day-of-month and month are wildcards. The shifted times
+// must still form a grid of minutes and hours, so shifting "0,50 9 * * *" by
+// 15 minutes (09:15 and 10:05) fails.
+func shiftExpression(expression string, offset int) (string, error) {
+	fields := strings.Fields(expression)
+	schedule, err := parseExpression(expression)
+	if err != nil {
+		return "", err
+	}
+	spec, ok := schedule.(*cron.SpecSchedule)
+	if !ok || len(fields) != len(cronFields) {
+		return "", fmt.Errorf("only standard 5-field expressions can be shifted")
+	}
+
+	minutes := map[int]bool{}
+	hours := map[int]bool{}
+	times := map[int]bool{}
+	carries := map[int]bool{}
+	for _, h := range bitValues(spec.Hour, 0, 23) {
+		for _, m := range bitValues(spec.Minute, 0, 59) {
+			shifted := h*60 + m + offset
+			carry := 0
+			switch {
+			case shifted < 0:
+				carry = -1
+			case shifted >= minutesPerDay:
+				carry = 1
+			}
+			shifted -= carry * minutesPerDay
+			minutes[shifted%60] = true
+			hours[shifted/60] = true
+			times[shifted] = true
+			carries[carry] = true
+		}
+	}
+	if len(minutes)*len(hours) != len(times) {
+		return "", fmt.Errorf("the shifted run times don't use the same minutes in every hour, so no single expression covers them")
+	}
+
+	dow := fields[4]
+	if len(carries) > 1 || !carries[0] {
+		if fields[2] != "*" || fields[3] != "*" {
+			return "", fmt.Errorf("the shift moves runs across midnight, which would change their day of the month")
+		}
+		if len(carries) > 1 && dow != "*" {
+			return "", fmt.Errorf("the shift moves only some runs across midnight, so their days of the week differ")
+		}
+		if dow != "*" {
+			carry := 1
+			if carries[-1] {
+				carry = -1
+			}
+			days := []int{}
+			for _, d := range bitValues(spec.Dow, 0, 6) {
+				days = append(days, (d+carry+7)%7)
+			}
+			dow = fitField(days, cronFields[4])
+		}
+	}
+
+	return strings.Join([]string{
+		fitField(keys(minutes), cronFields[0]),
+		fitField(keys(hours), cronFields[1]),
+		fields[2], fields[3], dow,
+	}, " "), nil
+}
+
+// keys returns the keys of a set of ints, in no particular order
+func keys(set map[int]bool) []int {
+	values := make([]int, 0, len(set))
+	for v := range set {
+		values = append(values, v)
+	}
+	return values
+}
+
+// shiftHandler previews moving an expression earlier or later by a minute
+// and hour offset, returning the shifted expression and its next runs
+func (s *Server) shiftHandler(w http.ResponseWriter, r *http.Request) {
+	var req ShiftRequest
+	if !s.decodeJSON(w, r, &req) {
+		return
+	}
+
+	expression := cleanExpression(req.Expression)
+	if _, err := parseExpression(expression); err != nil {
+		invalidCronExpressions.Inc()
+		writeError(w, http.StatusBadRequest, ErrInvalidCron, "Invalid cron expression: "+err.Error())
+		return
+	}
+	if _, equivalent, ok := resolveDescriptor(expression); ok {
+		expression = equivalent
+	}
+
+	offset := req.Hours*60 + req.Minutes
+	if offset <= -minutesPerDay || offset >= minutesPerDay {
+		writeError(w, http.StatusBadRequest, ErrInvalidRequest, "offset must be less than 24 hours either way")
+		return
+	}
+
+	shifted, err := shiftExpression(expression, offset)
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, ErrUnprocessable, "Can't shift expression: "+err.Error())
+		return
+	}
+	schedule, err := parseExpression(shifted)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, ErrInternal, "Shifted expression is invalid: "+err.Error())
+		return
+	}
+
+	s.writeJSON(w, r, http.StatusOK, ShiftResponse{
+		Expression:     expression,
+		OffsetMinutes:  offset,
+		Shifted:        shifted,
+		Description:    generateDescription(shifted),
+		NextExecutions: formatExecutions(nextExecutionTimes(schedule, time.Now().In(s.config.DefaultTimezone), 5)),
+	})
+}
@@ -0,0 +1,339 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// snapshotVersion is bumped whenever the snapshot format changes
+const snapshotVersion = 1
+
+// Restore modes
+const (
+	RestoreMerge   = "merge"
+	RestoreReplace = "replace"
+)
+
+// Snapshot is a complete dump of the stored expressions and the categories
+// they refer to, for moving a set between environments
+type Snapshot struct {
+	Version     int                  `json:"version"`
+	TakenAt     time.Time            `json:"taken_at"`
+	Categories  []Category           `json:"categories"`
+	Expressions []SnapshotExpression `json:"expressions"`
+}
+
+// SnapshotExpression is an expression in a snapshot. Enabled is a pointer
+// so an expression that leaves it out restores enabled, as create would.
+type SnapshotExpression struct {
+	CronExpression
+	Enabled *bool `json:"enabled"`
+}
+
+// enabled reports whether the expression should be restored enabled
+func (exp SnapshotExpression) enabled() bool {
+	return exp.Enabled == nil || *exp.Enabled
+}
+
+// restoreChange is an expression written by a restore, audited once the
+// transaction commits. exp is nil for a delete.
+type restoreChange struct {
+	id     int
+	action string
+	exp    *CronExpression
+}
+
+// RestoreRequest is the request body for restoring a snapshot. In merge mode
+// expressions whose name matches a live expression update it and the rest
+// are added; in replace mode every live expression is deleted first.
+type RestoreRequest struct {
+	Mode     string   `json:"mode"`
+	Snapshot Snapshot `json:"snapshot"`
+}
+
+// RestoreSummary reports what a restore changed
+type RestoreSummary struct {
+	Mode       string `json:"mode"`
+	Categories int    `json:"categories"`
+	Created    int    `json:"created"`
+	Updated    int    `json:"updated"`
+	Deleted    int    `json:"deleted"`
+}
+
+// snapshotHandler dumps every live expression and every category. Both are
+// read in one read-only transaction so the expressions can't refer to a
+// category created or deleted in between.
+func (s *Server) snapshotHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.dbContext(r)
+	defer cancel()
+
+	snapshot := Snapshot{Version: snapshotVersion, TakenAt: time.Now(), Categories: []Category{}, Expressions: []SnapshotExpression{}}
+
+	tx, err := s.db.reader().BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		writeDBError(w, ctx, err)
+		return
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT id, name, COALESCE(description, ''), created_at, updated_at
+		FROM categories
+		ORDER BY id
+	`)
+	if err != nil {
+		writeDBError(w, ctx, err)
+		return
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var c Category
+		if err := rows.Scan(&c.ID, &c.Name, &c.Description, &c.CreatedAt, &c.UpdatedAt); err != nil {
+			writeDBError(w, ctx, err)
+			return
+		}
+		snapshot.Categories = append(snapshot.Categories, c)
+	}
+	if err := rows.Err(); err != nil {
+		writeDBError(w, ctx, err)
+		return
+	}
+	rows.Close()
+
+	rows, err = tx.QueryContext(ctx, `
+		SELECT `+expressionColumns+`
+		FROM cron_expressions e
+		WHERE e.deleted_at IS NULL
+		ORDER BY e.id
+	`)
+	if err != nil {
+		writeDBError(w, ctx, err)
+		return
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var exp SnapshotExpression
+		if err := scanExpression(rows, &exp.CronExpression); err != nil {
+			writeDBError(w, ctx, err)
+			return
+		}
+		exp.Enabled = &exp.CronExpression.Enabled
+		snapshot.Expressions = append(snapshot.Expressions, exp)
+	}
+	if err := rows.Err(); err != nil {
+		writeDBError(w, ctx, err)
+		return
+	}
+
+	s.writeJSON(w, r, http.StatusOK, snapshot)
+}
+
+// snapshotProblems validates a snapshot as the create endpoint would,
+// including MIN_INTERVAL_SECONDS, defaulting empty environments, and returns
+// every problem found. Names must be unique within the snapshot, as they are
+// among live expressions.
+func (s *Server) snapshotProblems(snapshot *Snapshot) []string {
+	problems := []string{}
+	if snapshot.Version != snapshotVersion {
+		return append(problems, fmt.Sprintf("unsupported snapshot version %d, expected %d", snapshot.Version, snapshotVersion))
+	}
+
+	categories := map[int]bool{}
+	for i, c := range snapshot.Categories {
+		if c.Name == "" {
+			problems = append(problems, fmt.Sprintf("categories[%d]: name is required", i))
+		}
+		categories[c.ID] = true
+	}
+
+	names := map[string]int{}
+	for i := range snapshot.Expressions {
+		exp := &snapshot.Expressions[i].CronExpression
+		exp.Expression = cleanExpression(exp.Expression)
+		if exp.Environment == "" {
+			exp.Environment = defaultEnvironment
+		}
+		prefix := fmt.Sprintf("expressions[%d] (%q)", i, exp.Name)
+		if first, ok := names[exp.Name]; ok {
+			problems = append(problems, fmt.Sprintf("%s: name duplicates expressions[%d]", prefix, first))
+		} else {
+			names[exp.Name] = i
+		}
+		schedule, err := parseExpression(exp.Expression)
+		if err != nil {
+			problems = append(problems, prefix+": invalid cron expression: "+err.Error())
+		}
+		if !validTimezone(exp.Timezone) {
+			problems = append(problems, prefix+": invalid timezone: "+exp.Timezone)
+		} else if err == nil {
+			if err := s.checkMinInterval(schedule, s.expressionLocation(*exp)); err != nil {
+				problems = append(problems, prefix+": expression runs too often: "+err.Error())
+			}
+		}
+		if !validMetadata(exp.Metadata) {
+			problems = append(problems, prefix+": metadata must be a JSON object")
+		}
+		if !validEnvironment(exp.Environment) {
+			problems = append(problems, prefix+": invalid environment: "+exp.Environment)
+		}
+		if exp.CategoryID != nil && !categories[*exp.CategoryID] {
+			problems = append(problems, fmt.Sprintf("%s: category_id %d is not in the snapshot", prefix, *exp.CategoryID))
+		}
+	}
+	return problems
+}
+
+// restoreHandler applies a snapshot in a single transaction after
+// validating all of it. Categories are matched by name and expressions get
+// new ids, so category references are remapped on the way in.
+func (s *Server) restoreHandler(w http.ResponseWriter, r *http.Request) {
+	var req RestoreRequest
+	if !s.decodeJSON(w, r, &req) {
+		return
+	}
+	req.Mode = strings.ToLower(req.Mode)
+	if req.Mode != RestoreMerge && req.Mode != RestoreReplace {
+		writeFieldError(w, http.StatusBadRequest, ErrInvalidRequest, "mode", "mode must be merge or replace")
+		return
+	}
+	if problems := s.snapshotProblems(&req.Snapshot); len(problems) > 0 {
+		writeErrorBody(w, http.StatusBadRequest, ErrorBody{Code: ErrInvalidRequest, Message: "Snapshot failed validation", Problems: problems})
+		return
+	}
+
+	ctx, cancel := s.dbContext(r)
+	defer cancel()
+
+	tx, err := s.db.writer().BeginTx(ctx, nil)
+	if err != nil {
+		writeDBError(w, ctx, err)
+		return
+	}
+	defer tx.Rollback()
+
+	summary, changes, err := restoreSnapshot(ctx, tx, req.Mode, req.Snapshot)
+	if err != nil {
+		writeDBError(w, ctx, err)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		writeDBError(w, ctx, err)
+		return
+	}
+
+	s.lists.invalidate()
+	cronExpressionsTotal.Add(float64(summary.Created))
+	if count, err := s.db.countExpressions(ctx); err == nil {
+		cronExpressionsStored.Set(float64(count))
+	}
+	for _, change := range changes {
+		s.recordAudit(ctx, r, change.id, change.action, change.exp)
+	}
+
+	s.writeJSON(w, r, http.StatusOK, summary)
+}
+
+// restoreSnapshot writes a validated snapshot within tx, returning the
+// changes to audit once it commits
+func restoreSnapshot(ctx context.Context, tx *sql.Tx, mode string, snapshot Snapshot) (RestoreSummary, []restoreChange, error) {
+	summary := RestoreSummary{Mode: mode}
+	changes := []restoreChange{}
+	now := time.Now()
+
+	categoryIDs := map[int]int{}
+	for _, c := range snapshot.Categories {
+		var id int
+		err := tx.QueryRowContext(ctx, `
+			INSERT INTO categories (name, description, created_at, updated_at)
+			VALUES ($1, $2, $3, $3)
+			ON CONFLICT (name) DO UPDATE SET description = EXCLUDED.description, updated_at = EXCLUDED.updated_at
+			RETURNING id
+		`, c.Name, c.Description, now).Scan(&id)
+		if err != nil {
+			return summary, nil, err
+		}
+		categoryIDs[c.ID] = id
+		summary.Categories++
+	}
+
+	existing := map[string]int{}
+	if mode == RestoreReplace {
+		rows, err := tx.QueryContext(ctx, "UPDATE cron_expressions SET deleted_at = $1 WHERE deleted_at IS NULL RETURNING id", now)
+		if err != nil {
+			return summary, nil, err
+		}
+		for rows.Next() {
+			var id int
+			if err := rows.Scan(&id); err != nil {
+				rows.Close()
+				return summary, nil, err
+			}
+			changes = append(changes, restoreChange{id: id, action: AuditDelete})
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return summary, nil, err
+		}
+		summary.Deleted = len(changes)
+	} else {
+		rows, err := tx.QueryContext(ctx, "SELECT id, name FROM cron_expressions WHERE deleted_at IS NULL ORDER BY id DESC")
+		if err != nil {
+			return summary, nil, err
+		}
+		for rows.Next() {
+			var id int
+			var name string
+			if err := rows.Scan(&id, &name); err != nil {
+				rows.Close()
+				return summary, nil, err
+			}
+			// Descending order leaves the oldest row for duplicate names
+			existing[name] = id
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return summary, nil, err
+		}
+	}
+
+	for _, item := range snapshot.Expressions {
+		exp := item.CronExpression
+		if exp.CategoryID != nil {
+			id := categoryIDs[*exp.CategoryID]
+			exp.CategoryID = &id
+		}
+
+		if id, ok := existing[exp.Name]; ok {
+			var updated CronExpression
+			err := scanExpression(tx.QueryRowContext(ctx, `
+				UPDATE cron_expressions e
+				SET expression = $1, description = $2, category_id = $3, enabled = $4, timezone = $5, metadata = $6, environment = $7, updated_at = $8, last_validated_at = $8
+				WHERE e.id = $9
+				RETURNING `+expressionColumns,
+				exp.Expression, exp.Description, exp.CategoryID, item.enabled(), nullIfEmpty(exp.Timezone), metadataValue(exp.Metadata), exp.Environment, now, id), &updated)
+			if err != nil {
+				return summary, nil, err
+			}
+			changes = append(changes, restoreChange{id: id, action: AuditUpdate, exp: &updated})
+			summary.Updated++
+			continue
+		}
+
+		if err := insertExpression(ctx, tx, &exp); err != nil {
+			return summary, nil, err
+		}
+		if !item.enabled() {
+			if _, err := tx.ExecContext(ctx, "UPDATE cron_expressions SET enabled = false WHERE id = $1", exp.ID); err != nil {
+				return summary, nil, err
+			}
+			exp.Enabled = false
+		}
+		changes = append(changes, restoreChange{id: exp.ID, action: AuditCreate, exp: &exp})
+		summary.Created++
+	}
+	return summary, changes, nil
+}
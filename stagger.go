@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// maxStaggerCount bounds how many expressions one stagger request returns
+const maxStaggerCount = 100
+
+// StaggerRequest is the request body for spreading copies of a base cadence
+type StaggerRequest struct {
+	Base  string `json:"base"`
+	Count int    `json:"count"`
+}
+
+// StaggeredExpression is one of the generated expressions
+type StaggeredExpression struct {
+	Expression  string `json:"expression"`
+	Description string `json:"description"`
+}
+
+// StaggerResponse lists Count expressions with the base's cadence, starting
+// at the base and spaced evenly across one period
+type StaggerResponse struct {
+	Base           string                `json:"base"`
+	Count          int                   `json:"count"`
+	PeriodMinutes  int                   `json:"periodMinutes"`
+	SpacingMinutes int                   `json:"spacingMinutes"`
+	Expressions    []StaggeredExpression `json:"expressions"`
+}
+
+// staggerCadence describes a base that can be staggered: it repeats every
+// period minutes starting start minutes into the period, and format renders
+// the expression for a given offset into the period
+type staggerCadence struct {
+	period int
+	start  int
+	format func(offset int) string
+}
+
+// parseStaggerCadence recognizes the cadences staggering makes sense for:
+// hourly ("M * * * *"), every S minutes ("*/S * * * *"), every S hours
+// ("M */S * * *") and daily ("M H * * *", with any day, month and weekday
+// restriction kept). S must divide the hour or day evenly.
+func parseStaggerCadence(base string) (staggerCadence, error) {
+	fields := strings.Fields(base)
+	if len(fields) != len(cronFields) {
+		return staggerCadence{}, fmt.Errorf("expected %d fields, found %d", len(cronFields), len(fields))
+	}
+	days := strings.Join(fields[2:], " ")
+	minute, minuteErr := strconv.Atoi(fields[0])
+	hour, hourErr := strconv.Atoi(fields[1])
+	minuteStep, minuteStepOK := stepOf(fields[0])
+	hourStep, hourStepOK := stepOf(fields[1])
+
+	switch {
+	case minuteErr == nil && fields[1] == "*" && days == "* * *":
+		return staggerCadence{period: 60, start: minute, format: func(offset int) string {
+			return fmt.Sprintf("%d * * * *", offset)
+		}}, nil
+	case minuteStepOK && 60%minuteStep == 0 && fields[1] == "*" && days == "* * *":
+		return staggerCadence{period: minuteStep, format: func(offset int) string {
+			return fmt.Sprintf("%d-59/%d * * * *", offset, minuteStep)
+		}}, nil
+	case minuteErr == nil && hourStepOK && 24%hourStep == 0 && days == "* * *":
+		return staggerCadence{period: hourStep * 60, start: minute, format: func(offset int) string {
+			return fmt.Sprintf("%d %d-23/%d * * *", offset%60, offset/60, hourStep)
+		}}, nil
+	case minuteErr == nil && hourErr == nil:
+		return staggerCadence{period: 24 * 60, start: hour*60 + minute, format: func(offset int) string {
+			return fmt.Sprintf("%d %d %s", offset%60, offset/60, days)
+		}}, nil
+	}
+	return staggerCadence{}, fmt.Errorf("base must run hourly, daily, every N minutes or every N hours")
+}
+
+// stepOf returns n for a field of the form "*/n"
+func stepOf(field string) (int, bool) {
+	if !strings.HasPrefix(field, "*/") {
+		return 0, false
+	}
+	n, err := strconv.Atoi(strings.TrimPrefix(field, "*/"))
+	return n, err == nil && n > 0
+}
+
+// staggerHandler spreads count copies of a base cadence evenly across its
+// period so jobs sharing a cadence don't all start in the same minute
+func (s *Server) staggerHandler(w http.ResponseWriter, r *http.Request) {
+	var req StaggerRequest
+	if !s.decodeJSON(w, r, &req) {
+		return
+	}
+
+	base := cleanExpression(req.Base)
+	if _, err := parseExpression(base); err != nil {
+		invalidCronExpressions.Inc()
+		writeError(w, http.StatusBadRequest, ErrInvalidCron, "Invalid cron expression: "+err.Error())
+		return
+	}
+	if _, equivalent, ok := resolveDescriptor(base); ok {
+		base = equivalent
+	}
+
+	cadence, err := parseStaggerCadence(base)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, ErrInvalidRequest, "Base can't be staggered: "+err.Error())
+		return
+	}
+	if req.Count < 1 || req.Count > maxStaggerCount || req.Count > cadence.period {
+		writeFieldError(w, http.StatusBadRequest, ErrInvalidRequest, "count", fmt.Sprintf("count must be between 1 and %d for this base", min(maxStaggerCount, cadence.period)))
+		return
+	}
+
+	response := StaggerResponse{
+		Base:           req.Base,
+		Count:          req.Count,
+		PeriodMinutes:  cadence.period,
+		SpacingMinutes: cadence.period / req.Count,
+		Expressions:    make([]StaggeredExpression, 0, req.Count),
+	}
+	for i := 0; i < req.Count; i++ {
+		offset := (cadence.start + i*cadence.period/req.Count) % cadence.period
+		expression := cadence.format(offset)
+		response.Expressions = append(response.Expressions, StaggeredExpression{
+			Expression:  expression,
+			Description: generateDescription(expression),
+		})
+	}
+
+	s.writeJSON(w, r, http.StatusOK, response)
+}
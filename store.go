@@ -0,0 +1,175 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Postgres error codes the handlers map to client errors
+const (
+	pqUniqueViolation     = "23505"
+	pqForeignKeyViolation = "23503"
+)
+
+// expressionColumns lists the cron_expressions columns read by scanExpression,
+// qualified with the "e" table alias so queries can join other tables
+const expressionColumns = "e.id, e.name, e.expression, e.description, e.category_id, e.enabled, COALESCE(e.timezone, ''), COALESCE(e.metadata, '{}'), e.environment, e.created_at, e.updated_at, e.last_validated_at"
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanExpression scans a row selected with expressionColumns into exp, followed
+// by any extra destinations for joined columns
+func scanExpression(row rowScanner, exp *CronExpression, extra ...interface{}) error {
+	var categoryID sql.NullInt64
+	var metadata []byte
+	var lastValidated sql.NullTime
+	dest := append([]interface{}{
+		&exp.ID, &exp.Name, &exp.Expression, &exp.Description, &categoryID, &exp.Enabled, &exp.Timezone, &metadata, &exp.Environment, &exp.CreatedAt, &exp.UpdatedAt, &lastValidated,
+	}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		return err
+	}
+	exp.Metadata = json.RawMessage(metadata)
+	if categoryID.Valid {
+		id := int(categoryID.Int64)
+		exp.CategoryID = &id
+	}
+	if lastValidated.Valid {
+		exp.LastValidatedAt = &lastValidated.Time
+	}
+	return nil
+}
+
+// expressionByID loads a live (not soft-deleted) expression, returning
+// sql.ErrNoRows when there is none
+func (s *store) expressionByID(ctx context.Context, id string) (CronExpression, error) {
+	var exp CronExpression
+	err := s.retryRead(ctx, func(db *sql.DB) error {
+		return scanExpression(db.QueryRowContext(ctx, `
+			SELECT `+expressionColumns+`
+			FROM cron_expressions e
+			WHERE e.id = $1 AND e.deleted_at IS NULL
+		`, id), &exp)
+	})
+	return exp, err
+}
+
+// store holds the database pools used by the handlers. Writes always go to the
+// primary; SELECT-only handlers read from the replica when one is configured.
+type store struct {
+	primary *sql.DB
+	replica *sql.DB
+}
+
+// newStore wraps the given pools. A nil replica means all reads fall back to
+// the primary.
+func newStore(primary, replica *sql.DB) *store {
+	return &store{primary: primary, replica: replica}
+}
+
+// reader returns the pool to use for read-only queries
+func (s *store) reader() *sql.DB {
+	if s.replica != nil {
+		return s.replica
+	}
+	return s.primary
+}
+
+// writer returns the pool to use for queries that modify data
+func (s *store) writer() *sql.DB {
+	return s.primary
+}
+
+// nullIfEmpty stores an empty string as NULL
+func nullIfEmpty(value string) sql.NullString {
+	return sql.NullString{String: value, Valid: value != ""}
+}
+
+// rowQuerier is satisfied by both *sql.DB and *sql.Tx
+type rowQuerier interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// insertExpression inserts exp and fills in its id and timestamps. New
+// expressions start enabled, matching the column default, and count as
+// validated since callers parse them first.
+func insertExpression(ctx context.Context, q rowQuerier, exp *CronExpression) error {
+	now := time.Now()
+	err := q.QueryRowContext(ctx, `
+		INSERT INTO cron_expressions (name, expression, description, category_id, timezone, metadata, environment, created_at, updated_at, last_validated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $9)
+		RETURNING id, created_at, updated_at
+	`, exp.Name, exp.Expression, exp.Description, exp.CategoryID, nullIfEmpty(exp.Timezone), metadataValue(exp.Metadata), exp.Environment, now, now).Scan(&exp.ID, &exp.CreatedAt, &exp.UpdatedAt)
+	if err != nil {
+		return err
+	}
+	// New expressions start enabled, matching the column default, and reads
+	// coalesce missing metadata to {}, so report it the same way
+	exp.Enabled = true
+	if len(exp.Metadata) == 0 || string(exp.Metadata) == "null" {
+		exp.Metadata = json.RawMessage("{}")
+	}
+	exp.LastValidatedAt = &exp.UpdatedAt
+	return nil
+}
+
+// validMetadata reports whether metadata is absent or a JSON object
+func validMetadata(metadata json.RawMessage) bool {
+	if len(metadata) == 0 || string(metadata) == "null" {
+		return true
+	}
+	var object map[string]interface{}
+	return json.Unmarshal(metadata, &object) == nil
+}
+
+// environments are the runtime environments an expression can be tagged with
+var environments = []string{"dev", "staging", "prod"}
+
+// defaultEnvironment is used when an expression doesn't name one
+const defaultEnvironment = "dev"
+
+// validEnvironment reports whether env is one of environments
+func validEnvironment(env string) bool {
+	for _, allowed := range environments {
+		if env == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// metadataValue converts metadata to a JSONB parameter, storing absent
+// metadata as NULL
+func metadataValue(metadata json.RawMessage) sql.NullString {
+	if string(metadata) == "null" {
+		return sql.NullString{}
+	}
+	return nullIfEmpty(string(metadata))
+}
+
+// isUniqueViolation reports whether err is a Postgres unique constraint violation
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == pqUniqueViolation
+}
+
+// isForeignKeyViolation reports whether err is a Postgres foreign key violation
+func isForeignKeyViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == pqForeignKeyViolation
+}
+
+// countExpressions returns the number of live (not soft-deleted) expressions
+func (s *store) countExpressions(ctx context.Context) (int, error) {
+	var count int
+	err := s.writer().QueryRowContext(ctx, "SELECT COUNT(*) FROM cron_expressions WHERE deleted_at IS NULL").Scan(&count)
+	return count, err
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// streamKeepAlive is how often an idle convert stream sends a comment line
+// so proxies don't close the connection
+const streamKeepAlive = 15 * time.Second
+
+// StreamResult is a single validity/description update sent over the convert stream
+type StreamResult struct {
+	Expression     string   `json:"expression"`
+	RawExpression  string   `json:"rawExpression"`
+	Valid          bool     `json:"valid"`
+	Error          string   `json:"error,omitempty"`
+	Description    string   `json:"description,omitempty"`
+	NextExecutions []string `json:"nextExecutions,omitempty"`
+}
+
+// streamSessions maps open stream sessions to the channel feeding them, so the
+// companion POST endpoint can push new expressions to a connected editor
+type streamSessions struct {
+	sync.Mutex
+	m map[string]chan string
+}
+
+func newStreamSessions() *streamSessions {
+	return &streamSessions{m: make(map[string]chan string)}
+}
+
+// newSessionID returns a random 128-bit session ID. Only the server chooses
+// them, since knowing an ID is enough to push to its stream.
+func newSessionID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// evaluateExpression validates and describes an expression for the stream
+func evaluateExpression(raw string) StreamResult {
+	expression := cleanExpression(raw)
+	result := StreamResult{Expression: expression, RawExpression: raw}
+	if _, err := parseExpression(expression); err != nil {
+		result.Error = "Invalid cron expression: " + err.Error()
+		return result
+	}
+	result.Valid = true
+	result.Description = timedDescription(expression, time.Sunday)
+	result.NextExecutions = calculateNextExecutions(expression, 5)
+	return result
+}
+
+func writeEvent(w http.ResponseWriter, event string, v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data)
+}
+
+// convertStreamHandler opens a server-sent events stream that emits a result
+// event for the initial ?expression= and for every expression later posted to
+// the session via convertStreamUpdateHandler. The session ID is generated
+// here and returned in the X-Stream-Session header and the first event.
+func (s *Server) convertStreamHandler(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, ErrInternal, "Streaming unsupported")
+		return
+	}
+
+	session, err := newSessionID()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, ErrInternal, "Generating session ID: "+err.Error())
+		return
+	}
+
+	updates := make(chan string, 8)
+	s.streams.Lock()
+	if _, exists := s.streams.m[session]; exists {
+		s.streams.Unlock()
+		writeError(w, http.StatusConflict, ErrConflict, "Stream session already open")
+		return
+	}
+	s.streams.m[session] = updates
+	s.streams.Unlock()
+
+	defer func() {
+		s.streams.Lock()
+		delete(s.streams.m, session)
+		s.streams.Unlock()
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.Header().Set("X-Stream-Session", session)
+
+	writeEvent(w, "session", map[string]string{"session": session})
+	if expression := r.URL.Query().Get("expression"); expression != "" {
+		writeEvent(w, "result", evaluateExpression(expression))
+	}
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(streamKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			// Client disconnected
+			return
+		case expression := <-updates:
+			writeEvent(w, "result", evaluateExpression(expression))
+			flusher.Flush()
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+			flusher.Flush()
+		}
+	}
+}
+
+// convertStreamUpdateHandler pushes a new expression to an open stream session
+func (s *Server) convertStreamUpdateHandler(w http.ResponseWriter, r *http.Request) {
+	session := mux.Vars(r)["session"]
+
+	var req ConvertRequest
+	if !s.decodeJSON(w, r, &req) {
+		return
+	}
+
+	s.streams.Lock()
+	updates, ok := s.streams.m[session]
+	s.streams.Unlock()
+	if !ok {
+		writeError(w, http.StatusNotFound, ErrNotFound, "Stream session not found")
+		return
+	}
+
+	select {
+	case updates <- req.Expression:
+	default:
+		writeError(w, http.StatusServiceUnavailable, ErrUnavailable, "Stream session is busy")
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// systemdDayNames are systemd's weekday names indexed by cron's 0-6
+var systemdDayNames = []string{"Sun", "Mon", "Tue", "Wed", "Thu", "Fri", "Sat"}
+
+// SystemdResponse carries a cron expression and its OnCalendar= equivalent
+type SystemdResponse struct {
+	Expression string `json:"expression"`
+	OnCalendar string `json:"onCalendar"`
+}
+
+// toSystemdCalendar translates a 5-field expression into a systemd
+// OnCalendar= specification such as "Mon..Fri *-*-* 09:00:00"
+func toSystemdCalendar(expression string) (string, error) {
+	if _, equivalent, ok := resolveDescriptor(expression); ok {
+		expression = equivalent
+	}
+	// systemd ranges can't wrap, so hours 22-2 become 22-23,0-2
+	fields := strings.Fields(expandWrapRanges(expression))
+	if len(fields) != len(cronFields) {
+		return "", fmt.Errorf("expected %d fields, found %d", len(cronFields), len(fields))
+	}
+
+	// Cron fires when either restricted day field matches, but systemd
+	// requires both, so the combination has no equivalent
+	dom, dow := fields[2], fields[4]
+	if dom != "*" && dom != "?" && dow != "*" && dow != "?" {
+		return "", fmt.Errorf("systemd can't combine day-of-month and day-of-week; cron treats them as either/or")
+	}
+
+	components := make([]string, len(cronFields))
+	for i, field := range fields {
+		component, err := systemdComponent(field, cronFields[i])
+		if err != nil {
+			return "", fmt.Errorf("%s: %v", cronFields[i].name, err)
+		}
+		components[i] = component
+	}
+
+	calendar := fmt.Sprintf("*-%s-%s %s:%s:00", components[3], components[2], components[1], components[0])
+	if components[4] != "*" {
+		calendar = components[4] + " " + calendar
+	}
+	return calendar, nil
+}
+
+// systemdComponent converts one cron field into systemd syntax: ranges use
+// "..", "*/n" becomes "<min>/n", stepped ranges are expanded into lists and
+// day-of-week values become names
+func systemdComponent(field string, f cronField) (string, error) {
+	if field == "*" || field == "?" {
+		return "*", nil
+	}
+
+	items := []string{}
+	for _, item := range strings.Split(field, ",") {
+		base, step := item, ""
+		if i := strings.Index(item, "/"); i >= 0 {
+			base, step = item[:i], item[i+1:]
+		}
+
+		start, end := f.min, f.max
+		if base != "*" {
+			bounds := strings.Split(base, "-")
+			if len(bounds) > 2 {
+				return "", fmt.Errorf("invalid range %q", base)
+			}
+			var ok bool
+			if start, ok = f.value(bounds[0]); !ok {
+				return "", fmt.Errorf("unsupported value %q", bounds[0])
+			}
+			end = start
+			if len(bounds) == 2 {
+				if end, ok = f.value(bounds[1]); !ok {
+					return "", fmt.Errorf("unsupported value %q", bounds[1])
+				}
+			} else if step != "" {
+				end = f.max
+			}
+			if start > end {
+				return "", fmt.Errorf("range %q wraps past the end of the field", base)
+			}
+		}
+
+		switch {
+		case step == "":
+			items = append(items, systemdRange(start, end, f))
+		case end == f.max && f.name != "day-of-week":
+			// "a/n" repeats up to the end of the field, as in cron
+			items = append(items, systemdValue(start, f)+"/"+step)
+		default:
+			n, err := strconv.Atoi(step)
+			if err != nil || n < 1 {
+				return "", fmt.Errorf("invalid step %q", step)
+			}
+			for v := start; v <= end; v += n {
+				items = append(items, systemdValue(v, f))
+			}
+		}
+	}
+	return strings.Join(items, ","), nil
+}
+
+// systemdRange renders start..end, or a single value when they're equal
+func systemdRange(start, end int, f cronField) string {
+	if start == end {
+		return systemdValue(start, f)
+	}
+	return systemdValue(start, f) + ".." + systemdValue(end, f)
+}
+
+// systemdValue renders a value as a two-digit number, or a day name for the
+// day-of-week field where cron's 7 is also Sunday
+func systemdValue(v int, f cronField) string {
+	if f.name == "day-of-week" {
+		return systemdDayNames[v%7]
+	}
+	return fmt.Sprintf("%02d", v)
+}
+
+// systemdConvertHandler translates an expression for users moving from
+// crontab to systemd timers
+func (s *Server) systemdConvertHandler(w http.ResponseWriter, r *http.Request) {
+	var req ConvertRequest
+	if !s.decodeJSON(w, r, &req) {
+		return
+	}
+
+	expression := cleanExpression(req.Expression)
+	if _, err := parseExpression(expression); err != nil {
+		invalidCronExpressions.Inc()
+		writeError(w, http.StatusBadRequest, ErrInvalidCron, "Invalid cron expression: "+err.Error())
+		return
+	}
+
+	calendar, err := toSystemdCalendar(expression)
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, ErrUnprocessable, "Cannot convert to systemd: "+err.Error())
+		return
+	}
+
+	s.writeJSON(w, r, http.StatusOK, SystemdResponse{Expression: expression, OnCalendar: calendar})
+}
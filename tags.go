@@ -0,0 +1,150 @@
+package main
+
+import (
+	"github.com/robfig/cron/v3"
+)
+
+// Semantic tags returned by the convert endpoint with ?withIcons=true. The
+// frontend maps each tag to an icon, so these values are a stable API and must
+// not be renamed.
+//
+//	frequent   fires more than once an hour, hour after hour
+//	hourly     fires once an hour
+//	daily      fires at fixed times every day
+//	weekly     fires on a single day of the week
+//	monthly    fires on specific days of the month
+//	yearly     fires in specific months only
+//	weekday    only fires Monday to Friday
+//	weekend    only fires Saturday and Sunday
+//	night      only fires between 22:00 and 05:59
+//	morning    only fires between 06:00 and 11:59
+//	afternoon  only fires between 12:00 and 17:59
+//	evening    only fires between 18:00 and 21:59
+const (
+	TagFrequent  = "frequent"
+	TagHourly    = "hourly"
+	TagDaily     = "daily"
+	TagWeekly    = "weekly"
+	TagMonthly   = "monthly"
+	TagYearly    = "yearly"
+	TagWeekday   = "weekday"
+	TagWeekend   = "weekend"
+	TagNight     = "night"
+	TagMorning   = "morning"
+	TagAfternoon = "afternoon"
+	TagEvening   = "evening"
+)
+
+// starBit mirrors robfig/cron's marker bit for fields written as "*"
+const starBit = 1 << 63
+
+// bitValues lists the values set in a SpecSchedule field between min and max
+func bitValues(bits uint64, min, max int) []int {
+	values := []int{}
+	for i := min; i <= max; i++ {
+		if bits&(1<<uint(i)) != 0 {
+			values = append(values, i)
+		}
+	}
+	return values
+}
+
+// allWithin reports whether every value lies in [low, high]
+func allWithin(values []int, low, high int) bool {
+	for _, v := range values {
+		if v < low || v > high {
+			return false
+		}
+	}
+	return len(values) > 0
+}
+
+// hasConsecutiveHours reports whether any scheduled hour is followed by
+// another scheduled hour
+func hasConsecutiveHours(hours []int) bool {
+	scheduled := map[int]bool{}
+	for _, h := range hours {
+		scheduled[h] = true
+	}
+	for _, h := range hours {
+		if scheduled[(h+1)%24] {
+			return true
+		}
+	}
+	return false
+}
+
+// scheduleTags derives the semantic tags for a parsed schedule
+func scheduleTags(schedule cron.Schedule) []string {
+	spec, ok := schedule.(*cron.SpecSchedule)
+	if !ok {
+		return []string{}
+	}
+
+	tags := []string{}
+	minutes := bitValues(spec.Minute, 0, 59)
+	hours := bitValues(spec.Hour, 0, 23)
+	days := bitValues(spec.Dow, 0, 6)
+	domStar := spec.Dom&starBit != 0
+	dowStar := spec.Dow&starBit != 0
+	monthStar := spec.Month&starBit != 0
+
+	// Cadence. Several runs within one hour, as in "0,30 9 1 * *", are not
+	// frequent on their own; the runs must keep coming less than an hour
+	// apart into the next hour.
+	switch {
+	case len(minutes) > 1 && hasConsecutiveHours(hours):
+		tags = append(tags, TagFrequent)
+	case len(hours) == 24:
+		tags = append(tags, TagHourly)
+	case !monthStar:
+		tags = append(tags, TagYearly)
+	case !domStar:
+		tags = append(tags, TagMonthly)
+	case !dowStar && len(days) == 1:
+		tags = append(tags, TagWeekly)
+	default:
+		tags = append(tags, TagDaily)
+	}
+
+	// Days of the week, only meaningful when day of month doesn't also match
+	if !dowStar && domStar {
+		weekend := true
+		weekday := true
+		for _, d := range days {
+			if d == 0 || d == 6 {
+				weekday = false
+			} else {
+				weekend = false
+			}
+		}
+		if weekday {
+			tags = append(tags, TagWeekday)
+		}
+		if weekend {
+			tags = append(tags, TagWeekend)
+		}
+	}
+
+	// Time of day
+	if len(hours) < 24 {
+		night := true
+		for _, h := range hours {
+			if h > 5 && h < 22 {
+				night = false
+			}
+		}
+		switch {
+		case night:
+			tags = append(tags, TagNight)
+		case allWithin(hours, 6, 11):
+			tags = append(tags, TagMorning)
+		case allWithin(hours, 12, 17):
+			tags = append(tags, TagAfternoon)
+		case allWithin(hours, 18, 21):
+			tags = append(tags, TagEvening)
+		}
+	}
+
+	return tags
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// FieldToken is one field of an expression as an editor input. Min and Max
+// are the field's allowed range; Token and Error locate the first bad item
+// when the field is invalid. Missing fields are padded with an empty value.
+type FieldToken struct {
+	Index   int    `json:"index"`
+	Name    string `json:"name"`
+	Value   string `json:"value"`
+	Valid   bool   `json:"valid"`
+	Missing bool   `json:"missing,omitempty"`
+	Min     int    `json:"min"`
+	Max     int    `json:"max"`
+	Token   string `json:"token,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// TokenizeResponse is an expression split into its five fields. Extra holds
+// anything past the fifth field; Valid is only set when every field is valid
+// and the expression parses as a whole.
+type TokenizeResponse struct {
+	Expression string       `json:"expression"`
+	Fields     []FieldToken `json:"fields"`
+	Extra      []string     `json:"extra,omitempty"`
+	Valid      bool         `json:"valid"`
+	Error      string       `json:"error,omitempty"`
+}
+
+// tokenizeField validates value as field i on its own, by parsing it with
+// every other field a wildcard, so syntax the parser supports such as "L" or
+// "1#2" is accepted. checkFieldItem names the offending item where it can.
+func tokenizeField(i int, value string) FieldToken {
+	f := cronFields[i]
+	token := FieldToken{Index: i, Name: f.name, Value: value, Min: f.min, Max: f.max}
+	if value == "" {
+		token.Missing = true
+		token.Error = f.name + " is missing"
+		return token
+	}
+
+	probe := []string{"*", "*", "*", "*", "*"}
+	probe[i] = value
+	_, err := parseExpression(strings.Join(probe, " "))
+	if err == nil {
+		token.Valid = true
+		return token
+	}
+	token.Error = err.Error()
+	for _, item := range strings.Split(value, ",") {
+		if reason := checkFieldItem(item, f); reason != "" {
+			token.Token = item
+			token.Error = reason
+			break
+		}
+	}
+	return token
+}
+
+// tokenizeExpression splits a 5-field expression and validates each field
+// independently. Descriptors such as @daily are tokenized as their
+// equivalent expression.
+func tokenizeExpression(expression string) TokenizeResponse {
+	response := TokenizeResponse{Expression: expression, Fields: make([]FieldToken, len(cronFields))}
+	if _, equivalent, ok := resolveDescriptor(expression); ok {
+		expression = equivalent
+	}
+
+	values := strings.Fields(expression)
+	if len(values) > len(cronFields) {
+		response.Extra = values[len(cronFields):]
+	}
+	valid := len(values) == len(cronFields)
+	for i := range cronFields {
+		value := ""
+		if i < len(values) {
+			value = values[i]
+		}
+		response.Fields[i] = tokenizeField(i, value)
+		valid = valid && response.Fields[i].Valid
+	}
+
+	switch {
+	case len(values) != len(cronFields):
+		response.Error = fmt.Sprintf("expected %d fields, found %d", len(cronFields), len(values))
+	case valid:
+		if _, err := parseExpression(expression); err != nil {
+			response.Error = err.Error()
+		} else {
+			response.Valid = true
+		}
+	}
+	return response
+}
+
+// tokenizeHandler splits an expression into per-field tokens for a
+// structured editor, validating each field on its own so errors point at the
+// field that caused them
+func (s *Server) tokenizeHandler(w http.ResponseWriter, r *http.Request) {
+	var req ConvertRequest
+	if !s.decodeJSON(w, r, &req) {
+		return
+	}
+
+	s.writeJSON(w, r, http.StatusOK, tokenizeExpression(cleanExpression(req.Expression)))
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+const (
+	// maxUploadBytes caps the size of an uploaded expressions file
+	maxUploadBytes = 1 << 20
+	// maxUploadItems caps how many expressions one upload may contain
+	maxUploadItems = 500
+)
+
+// UploadItemResult reports what happened to one uploaded expression
+type UploadItemResult struct {
+	Index      int    `json:"index"`
+	Name       string `json:"name"`
+	Expression string `json:"expression"`
+	Created    bool   `json:"created"`
+	ID         int    `json:"id,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// UploadResponse summarises an expressions file upload
+type UploadResponse struct {
+	Created int                `json:"created"`
+	Failed  int                `json:"failed"`
+	Results []UploadItemResult `json:"results"`
+}
+
+// uploadExpressionsHandler imports a JSON array of expressions sent as the
+// "file" field of a multipart form. Valid items are inserted in a single
+// transaction; invalid or conflicting ones are reported and skipped.
+func (s *Server) uploadExpressionsHandler(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxUploadBytes)
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		if _, tooLarge := err.(*http.MaxBytesError); tooLarge {
+			writeError(w, http.StatusRequestEntityTooLarge, ErrPayloadTooLarge, fmt.Sprintf("Upload exceeds %d bytes", maxUploadBytes))
+		} else {
+			writeFieldError(w, http.StatusBadRequest, ErrInvalidRequest, "file", "Expected a multipart upload with a \"file\" field: "+err.Error())
+		}
+		return
+	}
+	defer file.Close()
+
+	var items []CronExpression
+	if err := json.NewDecoder(file).Decode(&items); err != nil {
+		writeFieldError(w, http.StatusBadRequest, ErrInvalidRequest, "file", "File must contain a JSON array of expressions: "+err.Error())
+		return
+	}
+	if len(items) == 0 || len(items) > maxUploadItems {
+		writeFieldError(w, http.StatusBadRequest, ErrInvalidRequest, "file", fmt.Sprintf("Upload must contain between 1 and %d expressions", maxUploadItems))
+		return
+	}
+
+	ctx, cancel := s.dbContext(r)
+	defer cancel()
+
+	tx, err := s.db.writer().BeginTx(ctx, nil)
+	if err != nil {
+		writeDBError(w, ctx, err)
+		return
+	}
+	defer tx.Rollback()
+
+	response := UploadResponse{Results: make([]UploadItemResult, 0, len(items))}
+	for i := range items {
+		exp := &items[i]
+		exp.Expression = cleanExpression(exp.Expression)
+		result := UploadItemResult{Index: i, Name: exp.Name, Expression: exp.Expression}
+
+		schedule, err := parseExpression(exp.Expression)
+		if err != nil {
+			invalidCronExpressions.Inc()
+			result.Error = "Invalid cron expression: " + err.Error()
+		} else if !validTimezone(exp.Timezone) {
+			result.Error = "Invalid timezone: " + exp.Timezone
+		} else if !validMetadata(exp.Metadata) {
+			result.Error = "metadata must be a JSON object"
+		} else if exp.Environment != "" && !validEnvironment(exp.Environment) {
+			result.Error = "Invalid environment: " + exp.Environment
+		} else if err := s.checkMinInterval(schedule, s.expressionLocation(*exp)); err != nil {
+			result.Error = "Expression runs too often: " + err.Error()
+		} else {
+			if exp.Environment == "" {
+				exp.Environment = defaultEnvironment
+			}
+			// A savepoint keeps one failed insert from aborting the rest
+			if _, err := tx.ExecContext(ctx, "SAVEPOINT upload_item"); err != nil {
+				writeDBError(w, ctx, err)
+				return
+			}
+			if err := insertExpression(ctx, tx, exp); err != nil {
+				if _, rbErr := tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT upload_item"); rbErr != nil {
+					writeDBError(w, ctx, rbErr)
+					return
+				}
+				switch {
+				case isUniqueViolation(err):
+					result.Error = "Expression already exists"
+				case isForeignKeyViolation(err):
+					result.Error = "Category not found"
+				default:
+					result.Error = err.Error()
+				}
+			} else {
+				result.Created = true
+				result.ID = exp.ID
+			}
+		}
+
+		if result.Created {
+			response.Created++
+		} else {
+			response.Failed++
+		}
+		response.Results = append(response.Results, result)
+	}
+
+	if err := tx.Commit(); err != nil {
+		writeDBError(w, ctx, err)
+		return
+	}
+	cronExpressionsTotal.Add(float64(response.Created))
+	cronExpressionsStored.Add(float64(response.Created))
+	s.lists.invalidate()
+	for i, result := range response.Results {
+		if result.Created {
+			s.recordAudit(ctx, r, result.ID, AuditCreate, &items[i])
+		}
+	}
+
+	s.writeJSON(w, r, http.StatusOK, response)
+}
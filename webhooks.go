@@ -0,0 +1,487 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/robfig/cron/v3"
+)
+
+const (
+	// webhookSyncSchedule is how often the scheduler reloads which
+	// expressions have webhooks, so new registrations start firing within
+	// a minute
+	webhookSyncSchedule = "@every 1m"
+	// webhookMaxAttempts is how many times one delivery is tried
+	webhookMaxAttempts = 3
+	// webhookMaxFailures is how many failed deliveries in a row disable a
+	// webhook
+	webhookMaxFailures = 5
+)
+
+// webhookRetryDelay is the wait before the first retry, doubling after each
+// attempt; a variable so tests can shorten it
+var webhookRetryDelay = time.Second
+
+// Webhook is a URL notified each time an expression fires. It is disabled
+// after webhookMaxFailures failed deliveries in a row.
+type Webhook struct {
+	ID                  int        `json:"id"`
+	ExpressionID        int        `json:"expression_id"`
+	URL                 string     `json:"url"`
+	Enabled             bool       `json:"enabled"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	LastDeliveryAt      *time.Time `json:"last_delivery_at"`
+	LastError           string     `json:"last_error,omitempty"`
+	CreatedAt           time.Time  `json:"created_at"`
+}
+
+// WebhookRequest is the request body for registering a webhook
+type WebhookRequest struct {
+	URL string `json:"url"`
+}
+
+// WebhookPayload is POSTed as JSON to each webhook when its expression fires
+type WebhookPayload struct {
+	ExpressionID int       `json:"expression_id"`
+	Name         string    `json:"name"`
+	Expression   string    `json:"expression"`
+	Timezone     string    `json:"timezone"`
+	FiredAt      time.Time `json:"fired_at"`
+}
+
+// errWebhookAddress is returned when a webhook resolves to an address it
+// may not reach
+var errWebhookAddress = errors.New("destination address not allowed")
+
+// sharedAddressSpace is the carrier-grade NAT range, which like RFC 1918
+// addresses can reach infrastructure that isn't public
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// blockedWebhookIP reports whether ip is loopback, private, link-local (which
+// includes the 169.254.169.254 cloud metadata service), multicast or
+// unspecified
+func blockedWebhookIP(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() || sharedAddressSpace.Contains(ip)
+}
+
+// validWebhookURL reports whether target is an absolute http or https URL.
+// Unless allowPrivate is set, hosts that are blocked addresses or localhost
+// are rejected up front; names resolving to blocked addresses are caught
+// when dialing, see webhookClient.
+func validWebhookURL(target string, allowPrivate bool) bool {
+	u, err := url.Parse(target)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return false
+	}
+	if allowPrivate {
+		return true
+	}
+	host := strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return false
+	}
+	ip := net.ParseIP(host)
+	return ip == nil || !blockedWebhookIP(ip)
+}
+
+// webhookClient makes deliveries. Unless allowPrivate is set, every
+// connection is checked after DNS resolution, so a name that resolves (or
+// is later rebound) to a blocked address, or a redirect to one, can't be
+// used to reach internal services. Proxies are ignored, since the check
+// would otherwise only see the proxy's address.
+func webhookClient(timeout time.Duration, allowPrivate bool) *http.Client {
+	dialer := &net.Dialer{Timeout: timeout}
+	if !allowPrivate {
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || blockedWebhookIP(ip) {
+				return errWebhookAddress
+			}
+			return nil
+		}
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
+
+// deliveryError summarizes a failed attempt for last_error, keeping the
+// status of a response but not the details of connection failures
+func deliveryError(err error) string {
+	var status webhookStatusError
+	var netErr net.Error
+	switch {
+	case errors.As(err, &status):
+		return status.Error()
+	case errors.Is(err, errWebhookAddress):
+		return errWebhookAddress.Error()
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return "request timed out"
+	}
+	return "request failed"
+}
+
+// webhookStatusError is a delivery answered with a non-2xx status
+type webhookStatusError struct {
+	status string
+}
+
+func (e webhookStatusError) Error() string {
+	return "unexpected status " + e.status
+}
+
+// createWebhookHandler registers a URL to be notified when a stored
+// expression fires. Deliveries only happen on the instance running the
+// scheduler, see ENABLE_WEBHOOKS.
+func (s *Server) createWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	var req WebhookRequest
+	if !s.decodeJSON(w, r, &req) {
+		return
+	}
+	if !validWebhookURL(req.URL, s.config.WebhookAllowPrivate) {
+		writeFieldError(w, http.StatusBadRequest, ErrInvalidRequest, "url", "url must be an absolute http or https URL to a public host")
+		return
+	}
+
+	ctx, cancel := s.dbContext(r)
+	defer cancel()
+
+	if _, err := s.db.expressionByID(ctx, id); err != nil {
+		if err == sql.ErrNoRows {
+			writeError(w, http.StatusNotFound, ErrNotFound, "Expression not found")
+		} else {
+			writeDBError(w, ctx, err)
+		}
+		return
+	}
+
+	hook := Webhook{URL: req.URL, Enabled: true}
+	err := s.db.writer().QueryRowContext(ctx, `
+		INSERT INTO webhooks (expression_id, url, created_at)
+		VALUES ($1, $2, $3)
+		RETURNING id, expression_id, created_at
+	`, id, req.URL, time.Now()).Scan(&hook.ID, &hook.ExpressionID, &hook.CreatedAt)
+	if err != nil {
+		if isForeignKeyViolation(err) {
+			writeError(w, http.StatusNotFound, ErrNotFound, "Expression not found")
+		} else {
+			writeDBError(w, ctx, err)
+		}
+		return
+	}
+
+	s.writeJSON(w, r, http.StatusCreated, hook)
+}
+
+// listWebhooksHandler lists the webhooks registered on an expression, with
+// their delivery state
+func (s *Server) listWebhooksHandler(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	ctx, cancel := s.dbContext(r)
+	defer cancel()
+
+	rows, err := s.db.queryRead(ctx, `
+		SELECT id, expression_id, url, enabled, consecutive_failures, last_delivery_at, COALESCE(last_error, ''), created_at
+		FROM webhooks
+		WHERE expression_id = $1
+		ORDER BY id
+	`, id)
+	if err != nil {
+		writeDBError(w, ctx, err)
+		return
+	}
+	defer rows.Close()
+
+	hooks := []Webhook{}
+	for rows.Next() {
+		var hook Webhook
+		var lastDelivery sql.NullTime
+		err := rows.Scan(&hook.ID, &hook.ExpressionID, &hook.URL, &hook.Enabled, &hook.ConsecutiveFailures, &lastDelivery, &hook.LastError, &hook.CreatedAt)
+		if err != nil {
+			writeDBError(w, ctx, err)
+			return
+		}
+		if lastDelivery.Valid {
+			hook.LastDeliveryAt = &lastDelivery.Time
+		}
+		hooks = append(hooks, hook)
+	}
+	if err := rows.Err(); err != nil {
+		writeDBError(w, ctx, err)
+		return
+	}
+
+	s.writeJSON(w, r, http.StatusOK, hooks)
+}
+
+func (s *Server) deleteWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	ctx, cancel := s.dbContext(r)
+	defer cancel()
+
+	result, err := s.db.writer().ExecContext(ctx, `
+		DELETE FROM webhooks WHERE id = $1 AND expression_id = $2
+	`, vars["webhook"], vars["id"])
+	if err != nil {
+		writeDBError(w, ctx, err)
+		return
+	}
+	if n, err := result.RowsAffected(); err != nil || n == 0 {
+		writeError(w, http.StatusNotFound, ErrNotFound, "Webhook not found")
+		return
+	}
+
+	s.writeJSON(w, r, http.StatusOK, map[string]string{"message": "Webhook deleted successfully"})
+}
+
+// locatedSchedule evaluates a schedule in the expression's own timezone,
+// whatever zone the cron runner uses
+type locatedSchedule struct {
+	schedule cron.Schedule
+	loc      *time.Location
+}
+
+func (l locatedSchedule) Next(t time.Time) time.Time {
+	return l.schedule.Next(t.In(l.loc))
+}
+
+// webhookEntry is an expression scheduled for delivery. key changes whenever
+// anything in the payload or schedule does, so the entry is replaced.
+type webhookEntry struct {
+	id  cron.EntryID
+	key string
+}
+
+// webhookScheduler fires webhooks for every live, enabled expression that
+// has at least one enabled webhook
+type webhookScheduler struct {
+	server *Server
+	cron   *cron.Cron
+	client *http.Client
+
+	mu      sync.Mutex
+	entries map[int]webhookEntry
+}
+
+func newWebhookScheduler(s *Server) *webhookScheduler {
+	return &webhookScheduler{
+		server:  s,
+		cron:    cron.New(),
+		client:  webhookClient(s.config.WebhookTimeout, s.config.WebhookAllowPrivate),
+		entries: map[int]webhookEntry{},
+	}
+}
+
+// startWebhookScheduler loads the expressions with webhooks and starts
+// firing them, resyncing on webhookSyncSchedule
+func (s *Server) startWebhookScheduler() *webhookScheduler {
+	ws := newWebhookScheduler(s)
+	resync := func() {
+		if err := ws.sync(); err != nil {
+			log.Printf("Error syncing webhook schedules: %v", err)
+		}
+	}
+	resync()
+	ws.cron.AddFunc(webhookSyncSchedule, resync)
+	ws.cron.Start()
+	return ws
+}
+
+// sync brings the scheduled entries in line with the database, adding,
+// replacing and removing entries as expressions and webhooks change
+func (ws *webhookScheduler) sync() error {
+	ctx, cancel := context.WithTimeout(context.Background(), ws.server.config.DBQueryTimeout)
+	defer cancel()
+
+	rows, err := ws.server.db.writer().QueryContext(ctx, `
+		SELECT `+expressionColumns+`
+		FROM cron_expressions e
+		WHERE e.deleted_at IS NULL AND e.enabled
+		AND EXISTS (SELECT 1 FROM webhooks w WHERE w.expression_id = e.id AND w.enabled)
+	`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	expressions := []CronExpression{}
+	for rows.Next() {
+		var exp CronExpression
+		if err := scanExpression(rows, &exp); err != nil {
+			return err
+		}
+		expressions = append(expressions, exp)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+
+	live := map[int]bool{}
+	for _, exp := range expressions {
+		schedule, err := parseExpression(exp.Expression)
+		if err != nil {
+			log.Printf("Warning: not scheduling webhooks for expression %d: %v", exp.ID, err)
+			continue
+		}
+		live[exp.ID] = true
+		loc := ws.server.expressionLocation(exp)
+		key := fmt.Sprintf("%s|%s|%s", exp.Name, exp.Expression, loc)
+		if entry, ok := ws.entries[exp.ID]; ok {
+			if entry.key == key {
+				continue
+			}
+			ws.cron.Remove(entry.id)
+		}
+		exp := exp
+		id := ws.cron.Schedule(locatedSchedule{schedule, loc}, cron.FuncJob(func() {
+			ws.fire(exp, loc)
+		}))
+		ws.entries[exp.ID] = webhookEntry{id: id, key: key}
+	}
+	for expressionID, entry := range ws.entries {
+		if !live[expressionID] {
+			ws.cron.Remove(entry.id)
+			delete(ws.entries, expressionID)
+		}
+	}
+	return nil
+}
+
+// fire delivers a run of exp to each of its enabled webhooks concurrently
+func (ws *webhookScheduler) fire(exp CronExpression, loc *time.Location) {
+	payload, err := json.Marshal(WebhookPayload{
+		ExpressionID: exp.ID,
+		Name:         exp.Name,
+		Expression:   exp.Expression,
+		Timezone:     loc.String(),
+		FiredAt:      time.Now().In(loc).Truncate(time.Minute),
+	})
+	if err != nil {
+		log.Printf("Error encoding webhook payload for expression %d: %v", exp.ID, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), ws.server.config.DBQueryTimeout)
+	defer cancel()
+	rows, err := ws.server.db.writer().QueryContext(ctx, `
+		SELECT id, url FROM webhooks WHERE expression_id = $1 AND enabled
+	`, exp.ID)
+	if err != nil {
+		log.Printf("Error loading webhooks for expression %d: %v", exp.ID, err)
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var hookID int
+		var target string
+		if err := rows.Scan(&hookID, &target); err != nil {
+			log.Printf("Error loading webhooks for expression %d: %v", exp.ID, err)
+			return
+		}
+		go ws.deliver(hookID, target, payload)
+	}
+}
+
+// deliver POSTs payload to target, retrying with backoff, and records the
+// outcome against the webhook
+func (ws *webhookScheduler) deliver(hookID int, target string, payload []byte) {
+	delay := webhookRetryDelay
+	var err error
+	for attempt := 1; attempt <= webhookMaxAttempts; attempt++ {
+		start := time.Now()
+		err = ws.post(target, payload)
+		webhookDeliveryDuration.Observe(time.Since(start).Seconds())
+		if err == nil {
+			break
+		}
+		if attempt < webhookMaxAttempts {
+			time.Sleep(delay)
+			delay *= 2
+		}
+	}
+
+	if err == nil {
+		webhookDeliveries.WithLabelValues("success").Inc()
+	} else {
+		webhookDeliveries.WithLabelValues("failure").Inc()
+		log.Printf("Warning: webhook %d delivery to %s failed after %d attempts: %v", hookID, target, webhookMaxAttempts, err)
+	}
+	if recordErr := ws.recordDelivery(hookID, err); recordErr != nil {
+		log.Printf("Error recording delivery for webhook %d: %v", hookID, recordErr)
+	}
+}
+
+// post makes one delivery attempt; any non-2xx response is a failure
+func (ws *webhookScheduler) post(target string, payload []byte) error {
+	resp, err := ws.client.Post(target, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return webhookStatusError{status: resp.Status}
+	}
+	return nil
+}
+
+// recordDelivery resets the failure count after a success. After a failure
+// it increments the count and disables the webhook once it reaches
+// webhookMaxFailures.
+func (ws *webhookScheduler) recordDelivery(hookID int, deliveryErr error) error {
+	ctx, cancel := context.WithTimeout(context.Background(), ws.server.config.DBQueryTimeout)
+	defer cancel()
+	now := time.Now()
+
+	if deliveryErr == nil {
+		_, err := ws.server.db.writer().ExecContext(ctx, `
+			UPDATE webhooks
+			SET consecutive_failures = 0, last_delivery_at = $1, last_error = NULL
+			WHERE id = $2
+		`, now, hookID)
+		return err
+	}
+
+	var enabled bool
+	err := ws.server.db.writer().QueryRowContext(ctx, `
+		UPDATE webhooks
+		SET consecutive_failures = consecutive_failures + 1, last_delivery_at = $1, last_error = $2,
+			enabled = consecutive_failures + 1 < $3
+		WHERE id = $4
+		RETURNING enabled
+	`, now, deliveryError(deliveryErr), webhookMaxFailures, hookID).Scan(&enabled)
+	if err != nil {
+		return err
+	}
+	if !enabled {
+		webhooksDisabled.Inc()
+		log.Printf("Disabled webhook %d after %d consecutive failed deliveries", hookID, webhookMaxFailures)
+	}
+	return nil
+}
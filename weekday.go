@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// weekdaySchedule fires on the weekday nearest a fixed day of the month, the
+// Quartz "15W" syntax that robfig's parser doesn't support, or with "LW" on
+// the last weekday of the month
+type weekdaySchedule struct {
+	base cron.Schedule // the expression with its day-of-month set to "*"
+	day  int           // lastDayOfMonth for LW
+}
+
+// lastDayOfMonth stands in for the day of month in "LW"
+const lastDayOfMonth = 0
+
+// weekdayLookahead bounds how many months Next searches before giving up
+const weekdayLookahead = 5 * 12
+
+// parseWeekdayExpression parses a 5-field expression whose day-of-month is
+// "<day>W" or "LW". ok is false when the expression doesn't use W at all.
+func parseWeekdayExpression(expression string) (schedule cron.Schedule, ok bool, err error) {
+	parts := strings.Fields(expression)
+	if len(parts) != 5 || !strings.HasSuffix(strings.ToUpper(parts[2]), "W") {
+		return nil, false, nil
+	}
+
+	day := lastDayOfMonth
+	if !strings.EqualFold(parts[2], "LW") {
+		var err error
+		day, err = strconv.Atoi(parts[2][:len(parts[2])-1])
+		if err != nil || day < 1 || day > 31 {
+			return nil, true, fmt.Errorf("invalid nearest-weekday day of month %q", parts[2])
+		}
+	}
+	if parts[4] != "*" && parts[4] != "?" {
+		return nil, true, fmt.Errorf("day of week must be * or ? when day of month uses W")
+	}
+
+	parts[2] = "*"
+	base, err := standardParser.Parse(strings.Join(parts, " "))
+	if err != nil {
+		return nil, true, err
+	}
+	return weekdaySchedule{base: base, day: day}, true, nil
+}
+
+// nearestWeekday returns the weekday closest to day in the given month
+// without crossing into a neighbouring month: a Saturday moves back to Friday
+// unless it is the 1st, and a Sunday moves forward to Monday unless it is the
+// last day. ok is false when the month is shorter than day.
+func nearestWeekday(year int, month time.Month, day int, loc *time.Location) (time.Time, bool) {
+	lastDay := time.Date(year, month+1, 0, 0, 0, 0, 0, loc).Day()
+	if day > lastDay {
+		return time.Time{}, false
+	}
+
+	date := time.Date(year, month, day, 0, 0, 0, 0, loc)
+	switch date.Weekday() {
+	case time.Saturday:
+		if day == 1 {
+			return date.AddDate(0, 0, 2), true
+		}
+		return date.AddDate(0, 0, -1), true
+	case time.Sunday:
+		if day == lastDay {
+			return date.AddDate(0, 0, -2), true
+		}
+		return date.AddDate(0, 0, 1), true
+	}
+	return date, true
+}
+
+// Next returns the first activation after t that falls on the month's
+// nearest weekday, or the zero time if none is found within the lookahead
+func (s weekdaySchedule) Next(t time.Time) time.Time {
+	loc := t.Location()
+	for i := 0; i < weekdayLookahead; i++ {
+		month := time.Date(t.Year(), t.Month()+time.Month(i), 1, 0, 0, 0, 0, loc)
+		day := s.day
+		if day == lastDayOfMonth {
+			// The weekday nearest the last day never leaves the month, so
+			// it is always the last weekday
+			day = month.AddDate(0, 1, -1).Day()
+		}
+		target, ok := nearestWeekday(month.Year(), month.Month(), day, loc)
+		if !ok {
+			continue
+		}
+
+		// Start just before midnight of the target day, or at t if later
+		from := target.Add(-time.Nanosecond)
+		if from.Before(t) {
+			from = t
+		}
+		next := s.base.Next(from)
+		if !next.IsZero() && next.Year() == target.Year() && next.YearDay() == target.YearDay() {
+			return next
+		}
+	}
+	return time.Time{}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/robfig/cron/v3"
+)
+
+const (
+	// weeklyDays is how many calendar days the week view covers
+	weeklyDays = 7
+	// maxWeeklyRunsPerDay keeps every-minute schedules from producing
+	// thousands of entries a day
+	maxWeeklyRunsPerDay = 100
+)
+
+// WeeklyDay lists the times an expression fires on one calendar date
+type WeeklyDay struct {
+	Date      string   `json:"date"`
+	Runs      []string `json:"runs"`
+	Truncated bool     `json:"truncated"`
+}
+
+// WeeklyResponse is a stored expression's runs over the next 7 days. Paused
+// expressions have no runs.
+type WeeklyResponse struct {
+	ID         int         `json:"id"`
+	Expression string      `json:"expression"`
+	Enabled    bool        `json:"enabled"`
+	Timezone   string      `json:"timezone"`
+	Days       []WeeklyDay `json:"days"`
+}
+
+// weeklyRuns groups the runs of schedule from now to the end of the seventh
+// calendar day in now's location, one entry per day starting today. A nil
+// schedule, for a paused expression, gives seven empty days.
+func weeklyRuns(schedule cron.Schedule, now time.Time) []WeeklyDay {
+	days := make([]WeeklyDay, 0, weeklyDays)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	from := now
+	for i := 0; i < weeklyDays; i++ {
+		dayStart := today.AddDate(0, 0, i)
+		dayEnd := dayStart.AddDate(0, 0, 1)
+		day := WeeklyDay{Date: dayStart.Format("2006-01-02"), Runs: []string{}}
+		if schedule == nil {
+			days = append(days, day)
+			continue
+		}
+		for next := schedule.Next(from); !next.IsZero() && next.Before(dayEnd); next = schedule.Next(next) {
+			if len(day.Runs) == maxWeeklyRunsPerDay {
+				day.Truncated = true
+				break
+			}
+			day.Runs = append(day.Runs, next.Format("15:04"))
+		}
+		days = append(days, day)
+		// Start just before the next midnight so a run at 00:00 is kept
+		from = dayEnd.Add(-time.Second)
+	}
+	return days
+}
+
+// weeklyHandler returns a stored expression's runs for each of the next 7
+// calendar days in its timezone, for week-view calendars
+func (s *Server) weeklyHandler(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	ctx, cancel := s.dbContext(r)
+	defer cancel()
+
+	exp, err := s.db.expressionByID(ctx, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			writeError(w, http.StatusNotFound, ErrNotFound, "Expression not found")
+		} else {
+			writeDBError(w, ctx, err)
+		}
+		return
+	}
+
+	schedule, err := parseExpression(exp.Expression)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, ErrInternal, "Stored expression is invalid: "+err.Error())
+		return
+	}
+
+	loc := s.expressionLocation(exp)
+	now := time.Now().In(loc)
+	response := WeeklyResponse{ID: exp.ID, Expression: exp.Expression, Enabled: exp.Enabled, Timezone: loc.String()}
+	if !exp.Enabled {
+		schedule = nil
+	}
+	response.Days = weeklyRuns(schedule, now)
+
+	s.writeJSON(w, r, http.StatusOK, response)
+}
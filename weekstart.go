@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// parseWeekStart reads the weekStart option; empty means Sunday, matching
+// cron's own numbering
+func parseWeekStart(value string) (time.Weekday, error) {
+	switch strings.ToLower(value) {
+	case "", "sunday":
+		return time.Sunday, nil
+	case "monday":
+		return time.Monday, nil
+	}
+	return time.Sunday, fmt.Errorf("weekStart must be sunday or monday")
+}
+
+// weekOrderedDays sorts a day-of-week list into the order the days fall in a
+// week starting on weekStart, so "0,1,3" reads Monday, Wednesday, Sunday for
+// a Monday-first week. Values come back as numbers 0-6. ok is false if any
+// item isn't a single day, leaving the list as written.
+func weekOrderedDays(items []string, weekStart time.Weekday) ([]string, bool) {
+	days := make([]int, 0, len(items))
+	for _, item := range items {
+		day, found := dowNumbers[strings.ToUpper(item)]
+		if !found {
+			n, err := strconv.Atoi(item)
+			if err != nil || n < 0 || n > 7 {
+				return nil, false
+			}
+			day = n % 7
+		}
+		days = append(days, day)
+	}
+
+	position := func(day int) int { return (day - int(weekStart) + 7) % 7 }
+	sort.SliceStable(days, func(i, j int) bool { return position(days[i]) < position(days[j]) })
+
+	ordered := make([]string, len(days))
+	for i, day := range days {
+		ordered[i] = strconv.Itoa(day)
+	}
+	return ordered, true
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// maxWorldclockZones bounds how many zones one worldclock request may list
+const maxWorldclockZones = 50
+
+// ZoneTime is the next run as seen in one zone. Fields other than Zone are
+// empty when there is no next run.
+type ZoneTime struct {
+	Zone         string     `json:"zone"`
+	NextRun      *time.Time `json:"nextRun"`
+	Local        string     `json:"local,omitempty"`
+	Abbreviation string     `json:"abbreviation,omitempty"`
+	Offset       string     `json:"offset,omitempty"`
+}
+
+// WorldclockResponse is a stored expression's next run in each requested
+// zone, in request order. NextRun is in the expression's own zone and is
+// null when it is paused or never fires again.
+type WorldclockResponse struct {
+	ID         int        `json:"id"`
+	Expression string     `json:"expression"`
+	Enabled    bool       `json:"enabled"`
+	Timezone   string     `json:"timezone"`
+	NextRun    *time.Time `json:"nextRun"`
+	Zones      []ZoneTime `json:"zones"`
+}
+
+// parseZones splits a comma-separated zone list, returning the loaded zones
+// and the names that failed to load
+func parseZones(value string) (zones []*time.Location, invalid []string) {
+	for _, name := range strings.Split(value, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		loc, err := time.LoadLocation(name)
+		if err != nil {
+			invalid = append(invalid, name)
+			continue
+		}
+		zones = append(zones, loc)
+	}
+	return zones, invalid
+}
+
+// worldclockHandler renders a stored expression's next run in each zone of
+// ?zones=, so teams in different places can see when a job fires locally
+func (s *Server) worldclockHandler(w http.ResponseWriter, r *http.Request) {
+	zones, invalid := parseZones(r.URL.Query().Get("zones"))
+	if len(invalid) > 0 {
+		writeErrorBody(w, http.StatusBadRequest, ErrorBody{
+			Code:     ErrInvalidTimezone,
+			Message:  "Invalid timezones: " + strings.Join(invalid, ", "),
+			Field:    "zones",
+			Problems: invalid,
+		})
+		return
+	}
+	if len(zones) == 0 || len(zones) > maxWorldclockZones {
+		writeFieldError(w, http.StatusBadRequest, ErrInvalidRequest, "zones", fmt.Sprintf("zones must list between 1 and %d timezones", maxWorldclockZones))
+		return
+	}
+
+	id := mux.Vars(r)["id"]
+
+	ctx, cancel := s.dbContext(r)
+	defer cancel()
+
+	exp, err := s.db.expressionByID(ctx, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			writeError(w, http.StatusNotFound, ErrNotFound, "Expression not found")
+		} else {
+			writeDBError(w, ctx, err)
+		}
+		return
+	}
+
+	schedule, err := parseExpression(exp.Expression)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, ErrInternal, "Stored expression is invalid: "+err.Error())
+		return
+	}
+
+	loc := s.expressionLocation(exp)
+	response := WorldclockResponse{
+		ID:         exp.ID,
+		Expression: exp.Expression,
+		Enabled:    exp.Enabled,
+		Timezone:   loc.String(),
+		Zones:      make([]ZoneTime, 0, len(zones)),
+	}
+	if next := schedule.Next(time.Now().In(loc)); exp.Enabled && !next.IsZero() {
+		response.NextRun = &next
+	}
+	for _, zone := range zones {
+		entry := ZoneTime{Zone: zone.String()}
+		if response.NextRun != nil {
+			local := response.NextRun.In(zone)
+			entry.NextRun = &local
+			entry.Local = local.Format(executionFormat)
+			entry.Abbreviation, _ = local.Zone()
+			entry.Offset = local.Format("-07:00")
+		}
+		response.Zones = append(response.Zones, entry)
+	}
+
+	s.writeJSON(w, r, http.StatusOK, response)
+}
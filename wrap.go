@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// wrapFields are the fields where a range may wrap past the field's maximum,
+// e.g. hours 22-2 or days FRI-MON
+var wrapFields = map[string]bool{
+	"minute":      true,
+	"hour":        true,
+	"month":       true,
+	"day-of-week": true,
+}
+
+// wrapRange reports whether item is a plain range like "22-2" whose start is
+// after its end in field f, returning its bounds
+func wrapRange(item string, f cronField) (start, end int, ok bool) {
+	if !wrapFields[f.name] || strings.ContainsAny(item, "/,") {
+		return 0, 0, false
+	}
+	bounds := strings.Split(item, "-")
+	if len(bounds) != 2 {
+		return 0, 0, false
+	}
+	start, okStart := f.value(bounds[0])
+	end, okEnd := f.value(bounds[1])
+	if !okStart || !okEnd || start <= end || start > f.max || end < f.min {
+		return 0, 0, false
+	}
+	return start, end, true
+}
+
+// expandWrapRanges rewrites wrap-around ranges, which robfig's parser
+// rejects, as two ranges: hours 22-2 become 22-23,0-2
+func expandWrapRanges(expression string) string {
+	fields := strings.Fields(expression)
+	if len(fields) != len(cronFields) {
+		return expression
+	}
+
+	for i, field := range fields {
+		f := cronFields[i]
+		items := strings.Split(field, ",")
+		for j, item := range items {
+			if start, end, ok := wrapRange(item, f); ok {
+				items[j] = fmt.Sprintf("%d-%d,%d-%d", start, f.max, f.min, end)
+			}
+		}
+		fields[i] = strings.Join(items, ",")
+	}
+	return strings.Join(fields, " ")
+}
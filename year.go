@@ -0,0 +1,141 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// Bounds of the year field in the unix-year variant
+const (
+	minYear = 1970
+	maxYear = 2099
+)
+
+// yearSchedule restricts a 5-field schedule to the years listed in a sixth
+// year field
+type yearSchedule struct {
+	base  cron.Schedule
+	years []int // sorted
+}
+
+// withYears wraps schedule so it only fires in the years matched by field
+func withYears(schedule cron.Schedule, field string) (cron.Schedule, error) {
+	years, err := parseYearField(field)
+	if err != nil {
+		return nil, err
+	}
+	return yearSchedule{base: schedule, years: years}, nil
+}
+
+// Next returns the next activation after t in an allowed year, or the zero
+// time once the last allowed year has passed
+func (s yearSchedule) Next(t time.Time) time.Time {
+	for {
+		next := s.base.Next(t)
+		if next.IsZero() {
+			return next
+		}
+
+		i := sort.SearchInts(s.years, next.Year())
+		if i == len(s.years) {
+			return time.Time{}
+		}
+		if s.years[i] == next.Year() {
+			return next
+		}
+
+		// Skip ahead to just before the next allowed year
+		t = time.Date(s.years[i], time.January, 1, 0, 0, 0, 0, next.Location()).Add(-time.Second)
+	}
+}
+
+// parseYearField expands a year field (*, lists, ranges and steps) into the
+// sorted years it matches
+func parseYearField(field string) ([]int, error) {
+	matched := make(map[int]bool)
+	for _, item := range strings.Split(field, ",") {
+		rangePart, step := item, 1
+		if i := strings.Index(item, "/"); i >= 0 {
+			n, err := strconv.Atoi(item[i+1:])
+			if err != nil || n <= 0 {
+				return nil, fmt.Errorf("invalid year step in %q", item)
+			}
+			rangePart, step = item[:i], n
+		}
+
+		start, end := minYear, maxYear
+		if rangePart != "*" {
+			bounds := strings.SplitN(rangePart, "-", 2)
+			var err error
+			if start, err = parseYear(bounds[0]); err != nil {
+				return nil, err
+			}
+			end = start
+			if len(bounds) == 2 {
+				if end, err = parseYear(bounds[1]); err != nil {
+					return nil, err
+				}
+			} else if step > 1 {
+				// "2025/2" means every second year from 2025
+				end = maxYear
+			}
+			if start > end {
+				return nil, fmt.Errorf("year range %q is backwards", rangePart)
+			}
+		}
+
+		for year := start; year <= end; year += step {
+			matched[year] = true
+		}
+	}
+
+	years := make([]int, 0, len(matched))
+	for year := range matched {
+		years = append(years, year)
+	}
+	sort.Ints(years)
+	return years, nil
+}
+
+func parseYear(value string) (int, error) {
+	year, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid year %q", value)
+	}
+	if year < minYear || year > maxYear {
+		return 0, fmt.Errorf("year %d out of range %d-%d", year, minYear, maxYear)
+	}
+	return year, nil
+}
+
+// describeWithYear describes a unix-year expression by appending the year
+// clause to the description of its first five fields
+func describeWithYear(expression string, weekStart time.Weekday) string {
+	fields := strings.Fields(expression)
+	if len(fields) != 6 {
+		return "Invalid cron expression"
+	}
+	description := describeExpression(strings.Join(fields[:5], " "), weekStart)
+	return strings.TrimSuffix(description, ".") + " " + describeYear(fields[5]) + "."
+}
+
+// describeYear describes a year field for generateDescription
+func describeYear(field string) string {
+	switch {
+	case field == "*":
+		return "every year"
+	case strings.HasPrefix(field, "*/"):
+		return fmt.Sprintf("every %s years", field[2:])
+	case strings.Contains(field, ",") || strings.Contains(field, "/"):
+		return fmt.Sprintf("in years %s", field)
+	case strings.Contains(field, "-"):
+		bounds := strings.SplitN(field, "-", 2)
+		return fmt.Sprintf("in years %s to %s", bounds[0], bounds[1])
+	}
+	return fmt.Sprintf("in year %s", field)
+}